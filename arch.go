@@ -0,0 +1,94 @@
+package dewy
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+var elfArchs = map[elf.Machine]string{
+	elf.EM_X86_64:  "amd64",
+	elf.EM_386:     "386",
+	elf.EM_AARCH64: "arm64",
+	elf.EM_ARM:     "arm",
+	elf.EM_RISCV:   "riscv64",
+	elf.EM_S390:    "s390x",
+}
+
+var machoArchs = map[macho.Cpu]string{
+	macho.CpuAmd64: "amd64",
+	macho.Cpu386:   "386",
+	macho.CpuArm64: "arm64",
+	macho.CpuArm:   "arm",
+}
+
+var peArchs = map[uint16]string{
+	pe.IMAGE_FILE_MACHINE_AMD64: "amd64",
+	pe.IMAGE_FILE_MACHINE_I386:  "386",
+	pe.IMAGE_FILE_MACHINE_ARM64: "arm64",
+	pe.IMAGE_FILE_MACHINE_ARMNT: "arm",
+}
+
+// checkArch checks the executable is built for runtime.GOARCH.
+// Files other than ELF, Mach-O and PE such as scripts are not checked.
+func checkArch(p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return nil
+	}
+
+	var archs []string
+	switch {
+	case bytes.Equal(magic, []byte(elf.ELFMAG)):
+		ef, err := elf.NewFile(f)
+		if err != nil {
+			return err
+		}
+		arch := elfArchs[ef.Machine]
+		if ef.Machine == elf.EM_PPC64 {
+			arch = "ppc64"
+			if ef.ByteOrder == binary.LittleEndian {
+				arch = "ppc64le"
+			}
+		}
+		archs = append(archs, arch)
+	case bytes.Equal(magic[:2], []byte("MZ")):
+		pf, err := pe.NewFile(f)
+		if err != nil {
+			return err
+		}
+		archs = append(archs, peArchs[pf.Machine])
+	default:
+		if ff, err := macho.NewFatFile(f); err == nil {
+			for _, a := range ff.Arches {
+				archs = append(archs, machoArchs[a.Cpu])
+			}
+			break
+		}
+		mf, err := macho.NewFile(f)
+		if err != nil {
+			// not an executable binary
+			return nil
+		}
+		archs = append(archs, machoArchs[mf.Cpu])
+	}
+
+	for _, a := range archs {
+		if a == runtime.GOARCH {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is built for %v, not for %s", p, archs, runtime.GOARCH)
+}
@@ -0,0 +1,29 @@
+package dewy
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// envRef matches ${VAR} references in the artifact name.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandArtifact expands ${VAR} references in the artifact name with environment variables,
+// such as app_${DATACENTER}_linux_amd64.tar.gz. It returns an error if a referenced variable is unset.
+func expandArtifact(name string) (string, error) {
+	var unset []string
+	expanded := envRef.ReplaceAllStringFunc(name, func(ref string) string {
+		key := envRef.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(key)
+		if !ok {
+			unset = append(unset, key)
+		}
+		return v
+	})
+	if len(unset) > 0 {
+		return "", fmt.Errorf("environment variables for artifact %s are not set: %s", name, strings.Join(unset, ", "))
+	}
+	return expanded, nil
+}
@@ -0,0 +1,28 @@
+package dewy
+
+import "testing"
+
+func TestExpandArtifact(t *testing.T) {
+	t.Setenv("DEWY_TEST_DC", "tokyo")
+	t.Setenv("DEWY_TEST_TIER", "web")
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"app_linux_amd64.tar.gz", "app_linux_amd64.tar.gz", false},
+		{"app_${DEWY_TEST_DC}_${DEWY_TEST_TIER}.tar.gz", "app_tokyo_web.tar.gz", false},
+		{"app_$DEWY_TEST_DC.tar.gz", "app_$DEWY_TEST_DC.tar.gz", false},
+		{"app_${DEWY_TEST_UNSET}.tar.gz", "", true},
+	}
+	for _, tt := range tests {
+		got, err := expandArtifact(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
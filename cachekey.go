@@ -0,0 +1,93 @@
+package dewy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/linyows/dewy/registry"
+	ghrelease "github.com/linyows/dewy/registry/github_release"
+)
+
+var _ CacheKeyer = (*ghrelease.GithubRelease)(nil)
+
+// Cache key strategies.
+const (
+	// CacheKeyTag keys the artifact by the tag and the revision if any, which is the default.
+	CacheKeyTag = "tag"
+	// CacheKeyRevision keys the artifact by the revision, such as the asset ID or the digest,
+	// so that tags of the same content are deployed once. The tag is used if the registry has no revision.
+	CacheKeyRevision = "revision"
+	// CacheKeyURL keys the artifact by the artifact URL, for URLs containing the version.
+	CacheKeyURL = "url"
+)
+
+// CacheKeyer derives the cache key of the artifact. An artifact of a key different from the current one is deployed.
+// A registry implementing CacheKeyer chooses the key unless the strategy is configured.
+type CacheKeyer interface {
+	CacheKey(res *registry.CurrentResponse) string
+}
+
+// CacheKeyFunc is an adapter to use a function as CacheKeyer.
+type CacheKeyFunc func(res *registry.CurrentResponse) string
+
+// CacheKey calls f(res).
+func (f CacheKeyFunc) CacheKey(res *registry.CurrentResponse) string {
+	return f(res)
+}
+
+// tagCacheKey keys the artifact by the tag and the revision.
+func tagCacheKey(res *registry.CurrentResponse) string {
+	if res.Revision != "" {
+		return fmt.Sprintf("%s-%s-%s", res.Tag, res.Revision, artifactName(res.ArtifactURL))
+	}
+	return fmt.Sprintf("%s-%s", res.Tag, artifactName(res.ArtifactURL))
+}
+
+// revisionCacheKey keys the artifact by the revision.
+func revisionCacheKey(res *registry.CurrentResponse) string {
+	if res.Revision == "" {
+		return tagCacheKey(res)
+	}
+	return fmt.Sprintf("%s-%s", res.Revision, artifactName(res.ArtifactURL))
+}
+
+// urlCacheKey keys the artifact by the hash of the artifact URL.
+func urlCacheKey(res *registry.CurrentResponse) string {
+	sum := sha256.Sum256([]byte(res.ArtifactURL))
+	return fmt.Sprintf("%s-%s", hex.EncodeToString(sum[:])[:12], artifactName(res.ArtifactURL))
+}
+
+// artifactName returns the file name of the artifact URL without the query, which may be given to download,
+// so that the cache key keeps the extension detecting the archive format.
+func artifactName(urlstr string) string {
+	if u, err := url.Parse(urlstr); err == nil && u.Path != "" {
+		return path.Base(u.Path)
+	}
+	return path.Base(urlstr)
+}
+
+// cacheKeyer returns CacheKeyer of the configured strategy and the registry.
+func (d *Dewy) cacheKeyer() (CacheKeyer, error) {
+	return newCacheKeyer(d.config.CacheKey, d.registry)
+}
+
+// newCacheKeyer returns CacheKeyer of the strategy, or of the registry if the strategy is empty.
+func newCacheKeyer(strategy string, r registry.Registry) (CacheKeyer, error) {
+	switch strategy {
+	case "":
+		if k, ok := r.(CacheKeyer); ok {
+			return k, nil
+		}
+		return CacheKeyFunc(tagCacheKey), nil
+	case CacheKeyTag:
+		return CacheKeyFunc(tagCacheKey), nil
+	case CacheKeyRevision:
+		return CacheKeyFunc(revisionCacheKey), nil
+	case CacheKeyURL:
+		return CacheKeyFunc(urlCacheKey), nil
+	}
+	return nil, fmt.Errorf("invalid cache key strategy: %s", strategy)
+}
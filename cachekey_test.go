@@ -0,0 +1,57 @@
+package dewy
+
+import (
+	"testing"
+
+	"github.com/linyows/dewy/registry"
+	ghrelease "github.com/linyows/dewy/registry/github_release"
+)
+
+type keyedRegistry struct{ registry.Registry }
+
+func (keyedRegistry) CacheKey(res *registry.CurrentResponse) string { return "custom" }
+
+func TestCacheKeyer(t *testing.T) {
+	res := &registry.CurrentResponse{
+		Tag:         "v1.0.0",
+		Revision:    "123",
+		ArtifactURL: "github_release://linyows/dewy/tag/v1.0.0/dewy_linux_amd64.tar.gz",
+	}
+	noRevision := &registry.CurrentResponse{Tag: res.Tag, ArtifactURL: res.ArtifactURL}
+	query := &registry.CurrentResponse{Tag: res.Tag, ArtifactURL: "https://example.com/dewy_linux_amd64.tar.gz?token=secret"}
+	tests := []struct {
+		strategy string
+		registry registry.Registry
+		res      *registry.CurrentResponse
+		want     string
+		wantErr  bool
+	}{
+		{"", nil, res, "v1.0.0-123-dewy_linux_amd64.tar.gz", false},
+		{"", nil, noRevision, "v1.0.0-dewy_linux_amd64.tar.gz", false},
+		{"", nil, query, "v1.0.0-dewy_linux_amd64.tar.gz", false},
+		{"", keyedRegistry{}, res, "custom", false},
+		{"", &ghrelease.GithubRelease{}, res, "v1.0.0-123-dewy_linux_amd64.tar.gz", false},
+		{"tag", keyedRegistry{}, res, "v1.0.0-123-dewy_linux_amd64.tar.gz", false},
+		{"revision", nil, res, "123-dewy_linux_amd64.tar.gz", false},
+		{"revision", nil, noRevision, "v1.0.0-dewy_linux_amd64.tar.gz", false},
+		{"url", nil, res, "7d80a1b55db3-dewy_linux_amd64.tar.gz", false},
+		{"digest", nil, res, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			k, err := newCacheKeyer(tt.strategy, tt.registry)
+			if err != nil {
+				if !tt.wantErr {
+					t.Fatal(err)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Fatal("want error")
+			}
+			if got := k.CacheKey(tt.res); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package dewy
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// readCurrent returns the release directory the current symlink points to.
+func (d *Dewy) readCurrent() (string, error) {
+	linkTo := d.currentPath()
+	dst, err := os.Readlink(linkTo)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(dst) {
+		dst = filepath.Join(filepath.Dir(linkTo), dst)
+	}
+	return dst, nil
+}
+
+// linkCurrent switches the current symlink to the release directory.
+// In the chroot, the symlink is relative so that it is resolved both inside and outside the chroot.
+func (d *Dewy) linkCurrent(release string) error {
+	linkTo := d.currentPath()
+	oldname := release
+	if d.config.Chroot != "" {
+		rel, err := filepath.Rel(filepath.Dir(linkTo), release)
+		if err != nil {
+			return err
+		}
+		oldname = rel
+	}
+	return swapSymlink(oldname, linkTo)
+}
+
+// chrootStarter runs the server command in the chroot by chroot(8), which requires root privileges.
+// Otherwise the command is run in the chroot directory without isolation.
+func chrootStarter(sc *StarterConfig, dir string) {
+	if os.Geteuid() == 0 {
+		p, err := exec.LookPath("chroot")
+		if err == nil {
+			sc.args = append([]string{dir, sc.command}, sc.args...)
+			sc.command = p
+			return
+		}
+		log.Printf("[WARN] Start server without chroot: %s", err)
+	} else {
+		log.Print("[WARN] Start server without chroot: root privileges are required")
+	}
+	if filepath.IsAbs(sc.command) {
+		sc.command = filepath.Join(dir, sc.command)
+	}
+	sc.dir = dir
+}
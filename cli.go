@@ -2,15 +2,20 @@ package dewy
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/logutils"
 	flags "github.com/jessevdk/go-flags"
 	ghrelease "github.com/linyows/dewy/registry/github_release"
+	"github.com/linyows/dewy/verify"
 )
 
 const (
@@ -22,18 +27,120 @@ const (
 )
 
 type cli struct {
-	env        Env
-	command    string
-	args       []string
-	LogLevel   string `long:"log-level" short:"l" arg:"(debug|info|warn|error)" description:"Level displayed as log"`
-	Interval   int    `long:"interval" arg:"seconds" short:"i" description:"The polling interval to the repository (default: 10)"`
-	Port       string `long:"port" short:"p" description:"TCP port to listen"`
-	Repository string `long:"repository" short:"r" description:"Repository for application"`
-	Registry   string `long:"registry" description:"Registry for application"`
-	Artifact   string `long:"artifact" short:"a" description:"Artifact name for application"`
-	PreRelease bool   `long:"pre" short:"P" description:"Pre-release handling (default: false)"`
-	Help       bool   `long:"help" short:"h" description:"show this help message and exit"`
-	Version    bool   `long:"version" short:"v" description:"prints the version number"`
+	env                      Env
+	command                  string
+	args                     []string
+	LogLevel                 string            `long:"log-level" short:"l" arg:"(debug|info|warn|error)" description:"Level displayed as log"`
+	Interval                 int               `long:"interval" arg:"seconds" short:"i" description:"The polling interval to the repository (default: 10)"`
+	Jitter                   time.Duration     `long:"jitter" arg:"duration" description:"Maximum random delay of each polling to spread hosts, bounded by the interval"`
+	Schedule                 string            `long:"schedule" arg:"cron" description:"Cron expression to poll the repository on instead of the interval, such as '*/5 9-17 * * 1-5'"`
+	Port                     string            `long:"port" short:"p" description:"TCP port to listen"`
+	Repository               string            `long:"repository" short:"r" description:"Repository for application"`
+	Registry                 string            `long:"registry" description:"Registry for application"`
+	Artifact                 string            `long:"artifact" short:"a" description:"Artifact name for application"`
+	PreRelease               bool              `long:"pre" short:"P" description:"Pre-release handling (default: false)"`
+	Environment              string            `long:"deployment-environment" description:"GitHub environment to track deployments"`
+	MinReleaseAge            time.Duration     `long:"min-release-age" arg:"duration" description:"Skip releases younger than the duration (e.g. 1h)"`
+	BeforeDeploy             []string          `long:"before-deploy-hook" description:"Command executed before the symlink swap, aborting the deploy if it fails (can be specified multiple times)"`
+	AfterDeploy              []string          `long:"after-deploy-hook" description:"Command executed after deploy (can be specified multiple times)"`
+	AfterDeployContinue      bool              `long:"after-deploy-continue-on-error" description:"Continue after deploy hooks even if one fails"`
+	Role                     string            `long:"role" description:"Role of the host such as web or worker"`
+	Tags                     []string          `long:"host-tag" description:"Tag of the host (can be specified multiple times)"`
+	SourceArchive            bool              `long:"source-archive" description:"Deploy the source tarball of the release instead of an asset"`
+	CacheDir                 string            `long:"cache-dir" arg:"path" description:"Directory to persist the cache (default: temporary directory)"`
+	CacheURL                 string            `long:"cache-url" arg:"url" description:"Remote cache shared by hosts, such as redis://localhost:6379/0?prefix=dewy:&ttl=168h"`
+	Offline                  bool              `long:"offline" description:"Deploy the cached current version without accessing the registry"`
+	NoticeTemplates          map[string]string `long:"notice-template" arg:"event:template" description:"Message template of notice for the event (can be specified multiple times)"`
+	RemoteHosts              []string          `long:"remote-host" arg:"[user@]host[:port]" description:"Remote host to deploy over SSH (can be specified multiple times)"`
+	SSHKey                   string            `long:"ssh-key" arg:"path" description:"SSH private key for remote hosts (default: ~/.ssh/id_rsa)"`
+	RemoteHealthCheck        string            `long:"remote-health-check" arg:"command" description:"Command to check the health on remote hosts"`
+	CacheCompression         string            `long:"cache-compression" arg:"(none|gzip|zstd)[:level]" description:"Compression of cached artifacts (default: none)"`
+	SkipRunningVersion       bool              `long:"skip-running-version" description:"Skip redeploy and restart when the server is already on the target version"`
+	RecursiveExtract         bool              `long:"recursive-extract" description:"Extract archives contained in the artifact archive"`
+	MaxExtractBytes          int64             `long:"max-extract-bytes" arg:"bytes" description:"Limit of total uncompressed bytes of the artifact"`
+	MaxExtractFiles          int               `long:"max-extract-files" arg:"count" description:"Limit of the number of entries in the artifact"`
+	ReadyFile                string            `long:"ready-file" arg:"path" description:"File in the release directory created by the server when it is ready"`
+	ReadyTimeout             time.Duration     `long:"ready-timeout" arg:"duration" description:"Duration to wait for the ready file" default:"30s"`
+	MinRetention             time.Duration     `long:"min-retention" arg:"duration" description:"Keep releases younger than this duration regardless of the number of releases"`
+	KeepReleases             int               `long:"keep-releases" arg:"count" description:"Number of the newest releases to keep (default: 7)"`
+	DirMode                  string            `long:"dir-mode" arg:"mode" description:"Permission of created directories in octal (default: 0755)"`
+	FileMode                 string            `long:"file-mode" arg:"mode" description:"Permission of written files in octal (default: 0644)"`
+	RequireChecksGreen       bool              `long:"require-checks-green" description:"Deploy a release only after all GitHub checks of its commit pass"`
+	MaxArtifactSize          int64             `long:"max-artifact-size" arg:"bytes" description:"Maximum size of the downloaded artifact"`
+	StartRetries             int               `long:"start-retries" arg:"count" description:"Number of retries when the server fails to start"`
+	DownloadRetries          int               `long:"download-retries" arg:"count" description:"Number of retries of fetching the release and downloading on transient errors"`
+	DownloadRetryInterval    time.Duration     `long:"download-retry-interval" arg:"duration" description:"First interval of download retries, doubled for each retry (default: 1s)"`
+	ReleasesRoot             string            `long:"releases-root" arg:"path" description:"Writable directory to extract releases (default: working directory)"`
+	ContentAddressedReleases bool              `long:"content-addressed-releases" description:"Name release directories by the content hash of the artifact"`
+	JSON                     bool              `long:"json" description:"Output results of doctor and status commands in JSON"`
+	NotifyOncePerRelease     bool              `long:"notify-once-per-release" description:"Suppress notices of a release already shipped to other hosts"`
+	Tag                      string            `long:"tag" arg:"tag" description:"Tag of the release to deploy instead of the latest, such as a moving stable tag"`
+	MaxConcurrency           int               `long:"max-concurrency" arg:"count" description:"Maximum number of downloads and extractions each running at the same time"`
+	MaxConcurrentDownloads   int               `long:"max-concurrent-downloads" arg:"count" description:"Maximum number of downloads running at the same time (default: --max-concurrency)"`
+	MaxConcurrentExtractions int               `long:"max-concurrent-extractions" arg:"count" description:"Maximum number of extractions running at the same time (default: --max-concurrency)"`
+	ExtractNice              int               `long:"extract-nice" arg:"0-19" description:"Nice value of extraction on Linux"`
+	ExtractIdleIO            bool              `long:"extract-idle-io" description:"Extract in the idle I/O scheduling class on Linux"`
+	StatsdAddr               string            `long:"statsd-addr" arg:"host:port" description:"StatsD or DogStatsD address to send deploy metrics and events"`
+	VerifyArch               bool              `long:"verify-arch" description:"Check the server executable is built for this architecture before starting"`
+	RestartSignal            string            `long:"restart-signal" arg:"signal" description:"Signal sent to the old server process on restart (default: SIGTERM)"`
+	SystemdNotify            bool              `long:"systemd-notify" description:"Notify systemd of readiness, status and watchdog (Type=notify)"`
+	HeartbeatURL             string            `long:"heartbeat-url" arg:"url" description:"URL pinged after each successful cycle for a dead man's switch"`
+	HeartbeatInterval        time.Duration     `long:"heartbeat-interval" arg:"duration" description:"Minimum interval between heartbeats"`
+	VersionRegex             string            `long:"version-regex" arg:"regex" description:"Deploy the highest version parsed from tags by capture groups, or semver"`
+	Branch                   string            `long:"branch" arg:"name" description:"Branch whose latest successful build is deployed (github_actions registry)"`
+	Workflow                 string            `long:"workflow" arg:"file" description:"Workflow file building the artifact, such as build.yml (github_actions registry)"`
+	VersionURL               string            `long:"version-url" arg:"url" description:"Endpoint returning the latest version as text or JSON manifest (http registry)"`
+	DrainFile                string            `long:"drain-file" arg:"path" description:"Defer deploys while the file exists, keeping the current server"`
+	Root                     string            `long:"root" arg:"path" description:"Directory where the current symlink is created (default: working directory)"`
+	SymlinkName              string            `long:"symlink" arg:"name" description:"Name of the symlink to the current release (default: current)"`
+	NotifyDiff               bool              `long:"notify-diff" description:"Notify deploys with the summary of changed files from the previous release"`
+	WorkDir                  string            `long:"work-dir" arg:"path" description:"Working directory of hooks and the server, supporting {{.ReleaseDir}} (default: release directory)"`
+	Notifiers                []string          `long:"notifier" arg:"url" description:"Notifier such as slack, slack://channel, discord, webhook URL or none, can be comma-separated or specified multiple times"`
+	DeployLog                bool              `long:"deploy-log" description:"Save the log of each deploy into the release directory as deploy.log.gz"`
+	MaxConsecutiveFailures   int               `long:"max-consecutive-failures" arg:"count" description:"Quarantine the release after the number of consecutive failed deploys"`
+	Chroot                   string            `long:"chroot" arg:"path" description:"Directory to run the server chrooted, where releases are extracted (requires root)"`
+	CheckInodes              bool              `long:"check-inodes" description:"Abort the extraction if entries of the artifact exceed free inodes"`
+	VersionSourceURL         string            `long:"version-source-url" arg:"url" description:"Endpoint returning the tag to deploy instead of the latest release"`
+	VersionSourceHeaders     []string          `long:"version-source-header" arg:"header" description:"Header sent to the version source like 'Authorization: Bearer ${TOKEN}', can be specified multiple times"`
+	SharedPaths              []string          `long:"shared-path" arg:"path" description:"Path in the release linked to the shared directory to keep across deploys, can be specified multiple times"`
+	MatchLabel               bool              `long:"match-label" description:"Match the artifact name also against labels of release assets"`
+	Foreground               bool              `long:"foreground" description:"Run the server as a direct child for containers, exiting with its exit code"`
+	GlobalDeployLock         string            `long:"global-deploy-lock" arg:"path" description:"Lock file to deploy one at a time across Dewy instances on the host"`
+	OTLPEndpoint             string            `long:"otlp-endpoint" arg:"url" description:"OTLP/HTTP endpoint of the OpenTelemetry collector to export traces of deploys"`
+	RequireSignedTag         bool              `long:"require-signed-tag" description:"Refuse to deploy releases whose tag is not signed and verified"`
+	AllowedSigners           []string          `long:"allowed-signer" arg:"email" description:"Email of the tagger allowed to sign tags, can be specified multiple times"`
+	Sidecars                 []string          `long:"sidecar" arg:"name:command" description:"Sidecar command restarted with the server in order, can be specified multiple times"`
+	SidecarHealthChecks      map[string]string `long:"sidecar-health-check" arg:"name:command" description:"Health check command of the sidecar, can be specified multiple times"`
+	DeployerID               string            `long:"deployer-id" arg:"name" description:"Team or pipeline name recorded with the host in shipping markers"`
+	DeployCooldown           time.Duration     `long:"deploy-cooldown" arg:"duration" description:"Minimum interval between deploys, deferring releases within it"`
+	CacheKey                 string            `long:"cache-key" arg:"(tag|revision|url)" description:"Strategy of cache keys detecting changes of the artifact (default: tag)"`
+	HealthCheck              string            `long:"health-check" arg:"command" description:"Command to check the health of the server after deploy"`
+	HealthCheckURL           string            `long:"health-check-url" arg:"url" description:"URL responding 2xx when the server is healthy after start or restart, rolling back otherwise"`
+	HealthCheckTimeout       time.Duration     `long:"health-check-timeout" arg:"duration" description:"Timeout of each request of the health check URL (default: 5s)"`
+	HealthCheckRetries       int               `long:"health-check-retries" arg:"count" description:"Number of retries of the health check URL (default: 5)"`
+	PostDeployWatch          time.Duration     `long:"post-deploy-watch" arg:"duration" description:"Duration to keep checking the health after deploy"`
+	PostDeployRollback       bool              `long:"post-deploy-rollback" description:"Roll back on sustained health check failures after deploy"`
+	Config                   string            `long:"config" short:"c" arg:"path" description:"Config file of options by long names, such as 'artifact = app.tar.gz'"`
+	Profile                  string            `long:"profile" arg:"name" env:"DEWY_PROFILE" description:"Section of the config file merged over the top-level options"`
+	PrintConfig              bool              `long:"print-config" description:"Print the resolved configuration with secrets redacted and exit"`
+	InstallCommand           string            `long:"install-command" arg:"command" description:"Command to install the artifact from stdin instead of extracting it"`
+	SelfUpdateRegistry       string            `long:"self-update-registry" arg:"url" description:"Registry of Dewy releases to update Dewy itself, e.g. github_release://linyows/dewy"`
+	SelfUpdateArtifact       string            `long:"self-update-artifact" arg:"name" description:"Artifact name of Dewy for self update"`
+	SelfUpdateInterval       time.Duration     `long:"self-update-interval" arg:"duration" description:"Interval to check updates of Dewy (default: 1h)"`
+	SelfUpdateChecksum       string            `long:"self-update-checksum-artifact" arg:"name" description:"Verify Dewy against the checksums file published with it, such as checksums.txt, required for self update"`
+	SemverConstraint         string            `long:"semver-constraint" arg:"constraint" description:"Deploy the highest version satisfying the constraint, e.g. '>=1.2.0 <2.0.0'"`
+	ArtifactAliases          map[string]string `long:"artifact-alias" arg:"name:alias" description:"Alias of OS or architecture in the artifact template, can be specified multiple times"`
+	GCSCredentials           string            `long:"gcs-credentials" arg:"path" description:"Service account JSON for the gcs registry (default: Application Default Credentials)"`
+	Manifest                 bool              `long:"manifest" description:"Registry URL is a JSON manifest of the version and the artifact URL (http registry)"`
+	ChecksumArtifact         string            `long:"checksum-artifact" arg:"name" description:"Verify the artifact against the checksums file published with it, such as checksums.txt"`
+	SignatureKey             string            `long:"signature-key" arg:"key|path" description:"Verify the detached signature of the artifact (.sig or .asc) with the armored public key"`
+	CosignKey                string            `long:"cosign-key" arg:"key|path" description:"Verify the cosign signature of the artifact (.sig) with the PEM public key"`
+	CosignIdentity           string            `long:"cosign-identity" arg:"identity" description:"Signer email or URI of keyless cosign signing, verified with the certificate (.pem or .crt)"`
+	CosignIssuer             string            `long:"cosign-issuer" arg:"url" description:"OIDC issuer of keyless cosign signing"`
+	CosignRoots              string            `long:"cosign-roots" arg:"path" description:"PEM certificates of Fulcio issuing certificates of keyless cosign signing"`
+	NotifierHeaders          []string          `long:"notifier-header" arg:"header" description:"Header of requests of webhook notifiers such as 'Authorization: Bearer ${TOKEN}', can be specified multiple times"`
+	Help                     bool              `long:"help" short:"h" description:"show this help message and exit"`
+	Version                  bool              `long:"version" short:"v" description:"prints the version number"`
 }
 
 // Env struct.
@@ -106,12 +213,113 @@ func (c *cli) buildHelp(names []string) []string {
 func (c *cli) showHelp() {
 	opts := strings.Join(c.buildHelp([]string{
 		"Config",
+		"Profile",
 		"Interval",
+		"Schedule",
+		"Jitter",
 		"Registry",
 		"Repository",
 		"Artifact",
 		"Port",
 		"PreRelease",
+		"Environment",
+		"MinReleaseAge",
+		"BeforeDeploy",
+		"AfterDeploy",
+		"AfterDeployContinue",
+		"Role",
+		"Tags",
+		"SourceArchive",
+		"CacheDir",
+		"CacheURL",
+		"Offline",
+		"NoticeTemplates",
+		"RemoteHosts",
+		"SSHKey",
+		"RemoteHealthCheck",
+		"CacheCompression",
+		"SkipRunningVersion",
+		"RecursiveExtract",
+		"MaxExtractBytes",
+		"MaxExtractFiles",
+		"ReadyFile",
+		"ReadyTimeout",
+		"MinRetention",
+		"KeepReleases",
+		"DirMode",
+		"FileMode",
+		"RequireChecksGreen",
+		"MaxArtifactSize",
+		"StartRetries",
+		"DownloadRetries",
+		"DownloadRetryInterval",
+		"ReleasesRoot",
+		"ContentAddressedReleases",
+		"JSON",
+		"NotifyOncePerRelease",
+		"Tag",
+		"MaxConcurrency",
+		"MaxConcurrentDownloads",
+		"MaxConcurrentExtractions",
+		"ExtractNice",
+		"ExtractIdleIO",
+		"StatsdAddr",
+		"VerifyArch",
+		"RestartSignal",
+		"SystemdNotify",
+		"HeartbeatURL",
+		"HeartbeatInterval",
+		"VersionRegex",
+		"Branch",
+		"Workflow",
+		"VersionURL",
+		"DrainFile",
+		"Root",
+		"SymlinkName",
+		"NotifyDiff",
+		"WorkDir",
+		"Notifiers",
+		"DeployLog",
+		"MaxConsecutiveFailures",
+		"Chroot",
+		"CheckInodes",
+		"VersionSourceURL",
+		"VersionSourceHeaders",
+		"SharedPaths",
+		"MatchLabel",
+		"Foreground",
+		"GlobalDeployLock",
+		"OTLPEndpoint",
+		"RequireSignedTag",
+		"AllowedSigners",
+		"Sidecars",
+		"SidecarHealthChecks",
+		"DeployerID",
+		"DeployCooldown",
+		"CacheKey",
+		"HealthCheck",
+		"HealthCheckURL",
+		"HealthCheckTimeout",
+		"HealthCheckRetries",
+		"PostDeployWatch",
+		"PostDeployRollback",
+		"PrintConfig",
+		"InstallCommand",
+		"SelfUpdateRegistry",
+		"SelfUpdateArtifact",
+		"SelfUpdateInterval",
+		"SelfUpdateChecksum",
+		"SemverConstraint",
+		"ArtifactAliases",
+		"GCSCredentials",
+		"Manifest",
+		"ChecksumArtifact",
+		"SignatureKey",
+		"CosignKey",
+		"CosignIdentity",
+		"CosignIssuer",
+		"CosignRoots",
+		"NotifierHeaders",
 		"LogLevel",
 	}), "\n")
 
@@ -119,8 +327,14 @@ func (c *cli) showHelp() {
 Usage: dewy [--version] [--help] command <options>
 
 Commands:
-  server   Keep the app server up to date
-  assets   Keep assets up to date
+  server      Keep the app server up to date
+  assets      Keep assets up to date
+  doctor      Check the configuration and environment
+  status      Show the last fetched release and the deployed version from the cache
+  deploy      Deploy the release of --tag once, such as rolling back in incidents
+  redeploy    Deploy the cached current version again and run hooks
+  rollback    Link the current symlink to the previous release
+  notify-test Send a test notice to each notifier
 
 Options:
 %s
@@ -135,13 +349,22 @@ func (c *cli) run() int {
 		c.showHelp()
 		return ExitErr
 	}
+	if c.Config != "" {
+		if err := loadConfigFile(p, c.Config, c.Profile); err != nil {
+			fmt.Fprintf(c.env.Err, "Error: %s\n", err)
+			return ExitErr
+		}
+	} else if c.Profile != "" {
+		fmt.Fprintf(c.env.Err, "Error: --config is required for the profile %s\n", c.Profile)
+		return ExitErr
+	}
 
 	if c.Version {
 		fmt.Fprintf(c.env.Err, "dewy version %s [%v, %v]\n", c.env.Version, c.env.Commit, c.env.Date)
 		return ExitOK
 	}
 
-	if len(args) == 0 || (args[0] != "server" && args[0] != "assets") {
+	if len(args) == 0 || (args[0] != "server" && args[0] != "assets" && args[0] != "doctor" && args[0] != "status" && args[0] != "redeploy" && args[0] != "rollback" && args[0] != "notify-test" && args[0] != "deploy") {
 		fmt.Fprintf(c.env.Err, "Error: command is not available\n")
 		c.showHelp()
 		return ExitErr
@@ -172,26 +395,167 @@ func (c *cli) run() int {
 
 	conf := DefaultConfig()
 
-	if c.Registry == "" && c.Repository == "" {
+	if c.Registry == "" && c.Repository == "" && !c.Offline && args[0] != "status" && args[0] != "redeploy" && args[0] != "rollback" && args[0] != "notify-test" {
 		fmt.Fprintf(c.env.Err, "Error: --registry is not set\n")
 		c.showHelp()
 		return ExitErr
 	}
-	if c.Registry == "" && c.Repository != "" {
+	// the server caches in a temporary directory unless configured, which other processes cannot find
+	if c.CacheDir == "" && (args[0] == "status" || args[0] == "redeploy" || args[0] == "rollback" || args[0] == "deploy") {
+		fmt.Fprintf(c.env.Err, "Error: --cache-dir of the server is required for %s\n", args[0])
+		return ExitErr
+	}
+	if c.Registry != "" {
+		conf.Registry = c.Registry
+	} else if c.Repository != "" {
 		// --repository is sintax sugar for --registry github_release://
 		conf.Registry = fmt.Sprintf("%s://%s", ghrelease.Scheme, c.Repository)
 	}
 
 	conf.ArtifactName = c.Artifact
 	conf.PreRelease = c.PreRelease
-
+	conf.DeploymentEnvironment = c.Environment
+	conf.MinReleaseAge = c.MinReleaseAge
+	conf.BeforeDeploy = c.BeforeDeploy
+	conf.AfterDeploy = c.AfterDeploy
+	conf.AfterDeployContinueOnError = c.AfterDeployContinue
+	conf.Role = c.Role
+	conf.Tags = c.Tags
+
+	conf.UseSourceArchive = c.SourceArchive
+	conf.Cache.Dir = c.CacheDir
+	conf.Cache.URL = c.CacheURL
+	conf.Offline = c.Offline
+	conf.NoticeTemplates = c.NoticeTemplates
+	for _, h := range c.RemoteHosts {
+		rh := ParseRemoteHost(h)
+		rh.KeyFile = c.SSHKey
+		rh.HealthCheck = c.RemoteHealthCheck
+		conf.RemoteHosts = append(conf.RemoteHosts, rh)
+	}
+	if c.CacheCompression != "" {
+		typ, level, _ := strings.Cut(c.CacheCompression, ":")
+		conf.Cache.Compression = typ
+		if level != "" {
+			l, err := strconv.Atoi(level)
+			if err != nil {
+				fmt.Fprintf(c.env.Err, "Error: invalid compression level: %s\n", level)
+				return ExitErr
+			}
+			conf.Cache.CompressionLevel = l
+		}
+	}
+	conf.SkipRunningVersion = c.SkipRunningVersion
+	conf.RecursiveExtract = c.RecursiveExtract
+	conf.MaxExtractBytes = c.MaxExtractBytes
+	conf.MaxExtractFiles = c.MaxExtractFiles
+	conf.ReadyFile = c.ReadyFile
+	conf.ReadyTimeout = c.ReadyTimeout
+	conf.MinRetention = c.MinRetention
+	conf.KeepReleases = c.KeepReleases
+	for _, m := range []struct {
+		name string
+		s    string
+		mode *os.FileMode
+	}{
+		{"dir-mode", c.DirMode, &conf.DirMode},
+		{"file-mode", c.FileMode, &conf.FileMode},
+	} {
+		if m.s == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(m.s, 8, 32)
+		if err != nil {
+			fmt.Fprintf(c.env.Err, "Error: invalid --%s: %s\n", m.name, m.s)
+			return ExitErr
+		}
+		*m.mode = os.FileMode(v)
+	}
+	conf.RequireChecksGreen = c.RequireChecksGreen
+	conf.MaxArtifactSize = c.MaxArtifactSize
+	conf.StartRetries = c.StartRetries
+	conf.DownloadRetries = c.DownloadRetries
+	conf.DownloadRetryInterval = c.DownloadRetryInterval
+	conf.ReleasesRoot = c.ReleasesRoot
+	conf.ContentAddressedReleases = c.ContentAddressedReleases
+	conf.NotifyOncePerRelease = c.NotifyOncePerRelease
+	conf.Tag = c.Tag
+	conf.MaxConcurrency = c.MaxConcurrency
+	conf.MaxConcurrentDownloads = c.MaxConcurrentDownloads
+	conf.MaxConcurrentExtractions = c.MaxConcurrentExtractions
+	conf.ExtractNice = c.ExtractNice
+	conf.ExtractIdleIO = c.ExtractIdleIO
+	conf.StatsdAddr = c.StatsdAddr
+	conf.VerifyArch = c.VerifyArch
+	conf.RestartSignal = c.RestartSignal
+	conf.SystemdNotify = c.SystemdNotify
+	conf.HeartbeatURL = c.HeartbeatURL
+	conf.HeartbeatInterval = c.HeartbeatInterval
+	conf.Schedule = c.Schedule
+	conf.Interval = time.Duration(c.Interval) * time.Second
+	conf.Jitter = c.Jitter
+	conf.VersionRegex = c.VersionRegex
+	conf.Branch = c.Branch
+	conf.Workflow = c.Workflow
+	conf.VersionURL = c.VersionURL
+	conf.DrainFile = c.DrainFile
+	conf.Root = c.Root
+	conf.SymlinkName = c.SymlinkName
+	conf.NotifyDiff = c.NotifyDiff
+	conf.WorkDir = c.WorkDir
+	conf.Notifiers = c.Notifiers
+	conf.DeployLog = c.DeployLog
+	conf.MaxConsecutiveFailures = c.MaxConsecutiveFailures
+	conf.Chroot = c.Chroot
+	conf.CheckInodes = c.CheckInodes
+	conf.VersionSourceURL = c.VersionSourceURL
+	conf.VersionSourceHeaders = c.VersionSourceHeaders
+	conf.SharedPaths = c.SharedPaths
+	conf.MatchLabel = c.MatchLabel
+	conf.Foreground = c.Foreground
+	conf.GlobalDeployLock = c.GlobalDeployLock
+	conf.OTLPEndpoint = c.OTLPEndpoint
+	conf.RequireSignedTag = c.RequireSignedTag
+	conf.AllowedSigners = c.AllowedSigners
+	conf.Commands, err = ParseSidecars(c.Sidecars, c.SidecarHealthChecks)
+	if err != nil {
+		fmt.Fprintf(c.env.Err, "Error: %s\n", err)
+		return ExitErr
+	}
+	conf.DeployerID = c.DeployerID
+	conf.DeployCooldown = c.DeployCooldown
+	conf.CacheKey = c.CacheKey
+	conf.HealthCheck = c.HealthCheck
+	conf.HealthCheckURL = c.HealthCheckURL
+	conf.HealthCheckTimeout = c.HealthCheckTimeout
+	conf.HealthCheckRetries = c.HealthCheckRetries
+	conf.PostDeployWatch = c.PostDeployWatch
+	conf.PostDeployRollback = c.PostDeployRollback
+	conf.InstallCommand = c.InstallCommand
+	conf.SelfUpdate = SelfUpdateConfig{
+		Registry: c.SelfUpdateRegistry,
+		Artifact: c.SelfUpdateArtifact,
+		Interval: c.SelfUpdateInterval,
+		Version:  c.env.Version,
+
+		ChecksumArtifact: c.SelfUpdateChecksum,
+	}
+	conf.SemverConstraint = c.SemverConstraint
+	conf.ArtifactAliases = c.ArtifactAliases
+	conf.GCSCredentials = c.GCSCredentials
+	conf.Manifest = c.Manifest
+	conf.ChecksumArtifact = c.ChecksumArtifact
+	conf.SignatureKey = c.SignatureKey
+	conf.Cosign = verify.CosignConfig{
+		Key:      c.CosignKey,
+		Identity: c.CosignIdentity,
+		Issuer:   c.CosignIssuer,
+		Roots:    c.CosignRoots,
+	}
+	conf.NotifierHeaders = c.NotifierHeaders
 	if c.command == "server" {
 		conf.Command = SERVER
-		conf.Starter = &StarterConfig{
-			ports:   []string{c.Port},
-			command: c.args[0],
-			args:    c.args[1:],
-		}
+		conf.Starter = newStarterConfig(conf, c.Port, c.args)
 	} else {
 		conf.Command = ASSETS
 	}
@@ -202,8 +566,112 @@ func (c *cli) run() int {
 		fmt.Fprintf(c.env.Err, "Error: %s\n", err)
 		return ExitErr
 	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			log.Printf("[ERROR] Cache close failure: %#v", err)
+		}
+	}()
 
-	d.Start(c.Interval)
+	if c.PrintConfig {
+		return c.printJSON(conf.Redacted())
+	}
+	if c.command == "doctor" {
+		return c.doctor(d)
+	}
+	if c.command == "status" {
+		return c.status(d)
+	}
+	if c.command == "notify-test" {
+		return c.printChecks(d.NotifyTest())
+	}
+	if c.command == "deploy" {
+		if err := d.Deploy(); err != nil {
+			fmt.Fprintf(c.env.Err, "Error: %s\n", err)
+			return ExitErr
+		}
+		return ExitOK
+	}
+	if c.command == "redeploy" {
+		if err := d.Redeploy(); err != nil {
+			fmt.Fprintf(c.env.Err, "Error: %s\n", err)
+			return ExitErr
+		}
+		return ExitOK
+	}
+	if c.command == "rollback" {
+		if err := d.Rollback(); err != nil {
+			fmt.Fprintf(c.env.Err, "Error: %s\n", err)
+			return ExitErr
+		}
+		return ExitOK
+	}
+
+	d.Start()
+
+	return ExitOK
+}
+
+// printJSON writes v to the output as indented JSON.
+func (c *cli) printJSON(v any) int {
+	enc := json.NewEncoder(c.env.Out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(c.env.Err, "Error: %s\n", err)
+		return ExitErr
+	}
+	return ExitOK
+}
+
+func (c *cli) doctor(d *Dewy) int {
+	return c.printChecks(d.Doctor())
+}
 
+// printChecks prints the checks and returns the exit code failing if any critical check fails.
+func (c *cli) printChecks(checks []Check) int {
+	code := ExitOK
+	for _, check := range checks {
+		if !check.OK && check.Critical {
+			code = ExitErr
+		}
+	}
+	if c.JSON {
+		if c.printJSON(checks) != ExitOK {
+			return ExitErr
+		}
+		return code
+	}
+	for _, check := range checks {
+		result := "PASS"
+		if !check.OK {
+			result = "FAIL"
+			if !check.Critical {
+				result = "WARN"
+			}
+		}
+		fmt.Fprintf(c.env.Out, "[%s] %s: %s\n", result, check.Name, check.Message)
+	}
+	return code
+}
+
+func (c *cli) status(d *Dewy) int {
+	s := d.Status()
+	if c.JSON {
+		return c.printJSON(s)
+	}
+	if s.Release != nil {
+		fmt.Fprintf(c.env.Out, "tag: %s\n", s.Release.Tag)
+		fmt.Fprintf(c.env.Out, "artifact: %s\n", s.Release.ArtifactURL)
+		if !s.Release.PublishedAt.IsZero() {
+			fmt.Fprintf(c.env.Out, "published: %s\n", s.Release.PublishedAt.Format(time.RFC3339))
+		}
+		fmt.Fprintf(c.env.Out, "fetched: %s\n", s.Release.FetchedAt.Format(time.RFC3339))
+	} else {
+		fmt.Fprintln(c.env.Out, "tag: unknown")
+	}
+	fmt.Fprintf(c.env.Out, "deployed: %s\n", s.Deployed)
+	fmt.Fprintf(c.env.Out, "current: %s\n", s.Current)
+	if s.Quarantined != "" {
+		fmt.Fprintf(c.env.Out, "quarantined: %s\n", s.Quarantined)
+	}
 	return ExitOK
 }
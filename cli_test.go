@@ -0,0 +1,145 @@
+package dewy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCLIJSON(t *testing.T) {
+	t.Setenv("SLACK_TOKEN", "")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+	}))
+	defer ts.Close()
+	dir := t.TempDir()
+
+	t.Run("status", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		code := RunCLI(Env{Out: &out, Err: &errOut, Args: []string{"status", "--json", "--cache-dir", dir}})
+		if code != ExitOK {
+			t.Fatalf("exit %d: %s", code, errOut.String())
+		}
+		var s Status
+		if err := json.Unmarshal(out.Bytes(), &s); err != nil {
+			t.Fatalf("%s: %s", err, out.String())
+		}
+		if s.Release != nil || s.Deployed != "" {
+			t.Errorf("nothing should be deployed: %+v", s)
+		}
+	})
+
+	t.Run("status without the cache dir", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		if code := RunCLI(Env{Out: &out, Err: &errOut, Args: []string{"status", "--json"}}); code != ExitErr {
+			t.Errorf("status of a temporary cache should be error, got %d: %s", code, out.String())
+		}
+	})
+
+	t.Run("doctor", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		// the exit code depends on the free space of the host
+		RunCLI(Env{Out: &out, Err: &errOut, Args: []string{"doctor", "--json", "--cache-dir", dir, "--registry", ts.URL + "/app.tar.gz", "--notifier", "none"}})
+		var checks []Check
+		if err := json.Unmarshal(out.Bytes(), &checks); err != nil {
+			t.Fatalf("%s: %s", err, out.String())
+		}
+		if len(checks) == 0 {
+			t.Error("no checks are printed")
+		}
+		for _, c := range checks {
+			if c.Name == "" || c.Message == "" {
+				t.Errorf("check should have the name and the message: %+v", c)
+			}
+		}
+	})
+}
+
+func TestRunCLIInterval(t *testing.T) {
+	tests := []struct {
+		args []string
+		want time.Duration
+	}{
+		{nil, 10 * time.Second},
+		{[]string{"--interval", "30"}, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		var out, errOut bytes.Buffer
+		args := append([]string{"assets", "--print-config", "--registry", "https://example.com/app.tar.gz", "--cache-dir", t.TempDir()}, tt.args...)
+		if code := RunCLI(Env{Out: &out, Err: &errOut, Args: args}); code != ExitOK {
+			t.Fatalf("exit %d: %s", code, errOut.String())
+		}
+		var c struct{ Interval time.Duration }
+		if err := json.Unmarshal(out.Bytes(), &c); err != nil {
+			t.Fatalf("%s: %s", err, out.String())
+		}
+		if c.Interval != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.args, c.Interval, tt.want)
+		}
+	}
+}
+
+func TestRunCLIProfile(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "dewy.ini")
+	if err := os.WriteFile(config, []byte(`registry = https://example.com/app.tar.gz
+interval = 30
+pre = false
+
+[staging]
+pre = true
+semver-constraint = >=1.0.0-0
+
+[prod]
+semver-constraint = >=1.0.0 <2.0.0
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	type resolved struct {
+		Interval         time.Duration
+		PreRelease       bool
+		SemverConstraint string
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		want    resolved
+		wantErr string
+	}{
+		{"base", nil, "", resolved{30 * time.Second, false, ""}, ""},
+		{"profile", []string{"--profile", "staging"}, "", resolved{30 * time.Second, true, ">=1.0.0-0"}, ""},
+		{"profile by env", nil, "prod", resolved{30 * time.Second, false, ">=1.0.0 <2.0.0"}, ""},
+		{"flags take precedence", []string{"--profile", "prod", "--interval", "60", "--semver-constraint", ">=2.0.0"}, "", resolved{60 * time.Second, false, ">=2.0.0"}, ""},
+		{"unknown profile", []string{"--profile", "dev"}, "", resolved{}, `profile "dev" is not found`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEWY_PROFILE", tt.env)
+			var out, errOut bytes.Buffer
+			args := append([]string{"assets", "--print-config", "--config", config, "--cache-dir", t.TempDir()}, tt.args...)
+			code := RunCLI(Env{Out: &out, Err: &errOut, Args: args})
+			if tt.wantErr != "" {
+				if code != ExitErr || !strings.Contains(errOut.String(), tt.wantErr) {
+					t.Errorf("got exit %d: %s, want %s", code, errOut.String(), tt.wantErr)
+				}
+				return
+			}
+			if code != ExitOK {
+				t.Fatalf("exit %d: %s", code, errOut.String())
+			}
+			var got resolved
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("%s: %s", err, out.String())
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
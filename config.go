@@ -1,9 +1,13 @@
 package dewy
 
 import (
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	starter "github.com/lestrrat-go/server-starter"
+	"github.com/linyows/dewy/verify"
 )
 
 // Command for CLI.
@@ -28,6 +32,11 @@ func (c Command) String() string {
 	}
 }
 
+// MarshalText encodes Command as its name.
+func (c Command) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
 // CacheType for cache type.
 type CacheType int
 
@@ -36,6 +45,12 @@ const (
 	NONE CacheType = iota
 	// FILE cache type.
 	FILE
+	// REDIS cache type.
+	REDIS
+	// S3 cache type.
+	S3
+	// MEMORY cache type.
+	MEMORY
 )
 
 // String to string for CacheType.
@@ -45,15 +60,36 @@ func (c CacheType) String() string {
 		return "none"
 	case FILE:
 		return "file"
+	case REDIS:
+		return "redis"
+	case S3:
+		return "s3"
+	case MEMORY:
+		return "memory"
 	default:
 		return "unknown"
 	}
 }
 
+// MarshalText encodes CacheType as its name.
+func (c CacheType) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
 // CacheConfig struct.
 type CacheConfig struct {
 	Type       CacheType
 	Expiration int
+	// Dir is the directory to persist the cache. A temporary directory is used if empty.
+	// It stages data of the remote cache.
+	Dir string
+	// URL is the URL of the remote cache such as redis://localhost:6379/0?prefix=dewy:&ttl=168h
+	// or s3://bucket/prefix?region=ap-northeast-1, which survives replacing hosts. The type is decided by the scheme.
+	URL string
+	// Compression is the compression type of cached data as none, gzip or zstd.
+	Compression string
+	// CompressionLevel is the compression level. The default level is used if zero.
+	CompressionLevel int
 }
 
 // Config struct.
@@ -62,8 +98,225 @@ type Config struct {
 	Registry     string
 	ArtifactName string
 	PreRelease   bool
-	Cache        CacheConfig
-	Starter      starter.Config
+	// DeploymentEnvironment is the GitHub environment to create deployments for. If the environment requires reviewers,
+	// releases wait until a reviewer marks the deployment in progress, and rejected or failed deployments are not deployed.
+	DeploymentEnvironment string
+	Cache                 CacheConfig
+	Starter               starter.Config
+	// BeforeDeploy is a list of commands executed in order in the extracted release before the symlink swap.
+	// The deploy is aborted if one fails.
+	BeforeDeploy []string
+	// AfterDeploy is a list of commands executed in order after deploy.
+	AfterDeploy []string
+	// AfterDeployContinueOnError continues to run after deploy commands even if one fails.
+	AfterDeployContinueOnError bool
+	// Role is the role of the host such as web or worker.
+	Role string
+	// Tags are arbitrary labels of the host.
+	Tags []string
+	// MinReleaseAge skips releases published more recently than this duration.
+	MinReleaseAge time.Duration
+	// UseSourceArchive deploys the source tarball of the release instead of an asset.
+	UseSourceArchive bool
+	// Offline deploys the cached current version without accessing the registry.
+	Offline bool
+	// NoticeTemplates are message templates of notice by event.
+	NoticeTemplates map[string]string
+	// RemoteHosts are hosts to deploy the release over SSH after the local deploy.
+	RemoteHosts []RemoteHost
+	// SkipRunningVersion starts the server without deploying again when the current symlink
+	// already points to the target version, and skips restarting the server running it.
+	SkipRunningVersion bool
+	// RecursiveExtract extracts archives contained in the artifact archive, each into the directory named after it
+	// without extensions, such as assets/ of assets.tar.gz.
+	RecursiveExtract bool
+	// MaxExtractBytes is the limit of total uncompressed bytes of the artifact. No limit if zero.
+	MaxExtractBytes int64
+	// MaxExtractFiles is the limit of the number of entries in the artifact. No limit if zero.
+	MaxExtractFiles int
+	// ReadyFile is the file in the release directory that the server creates when it is ready.
+	ReadyFile string
+	// ReadyTimeout is the duration to wait for the ready file.
+	ReadyTimeout time.Duration
+	// MinRetention keeps releases younger than this duration even if they exceed the number to keep.
+	MinRetention time.Duration
+	// KeepReleases is the number of the newest releases to keep. 7 is used if zero.
+	KeepReleases int
+	// DirMode is the permission of created release directories. 0755 is used if zero.
+	DirMode os.FileMode
+	// FileMode is the permission of files written by Dewy. 0644 is used if zero.
+	FileMode os.FileMode
+	// RequireChecksGreen deploys a release only after all statuses and check runs of its commit pass.
+	RequireChecksGreen bool
+	// MaxArtifactSize is the maximum size of the downloaded artifact. No limit if zero.
+	MaxArtifactSize int64
+	// Verifiers verify the downloaded artifact in order before caching.
+	Verifiers []verify.Verifier
+	// StartRetries is the number of retries when the server fails to start.
+	StartRetries int
+	// DownloadRetries is the number of retries of fetching the release and downloading the artifact
+	// on server errors, connection resets and timeouts. Client errors are not retried.
+	DownloadRetries int
+	// DownloadRetryInterval is the first interval of DownloadRetries, doubled for each retry with jitter. A second if zero.
+	DownloadRetryInterval time.Duration
+	// Interval is the polling interval, 10 seconds if zero. It is truncated to seconds.
+	// Each instance has its own scheduler job, so apps can be polled at their own cadences.
+	Interval time.Duration
+	// Schedule is the cron expression of 5 fields in the local time, such as "*/5 9-17 * * 1-5",
+	// to run cycles on instead of the interval.
+	Schedule string
+	// Jitter is the maximum random delay of each cycle to spread polling of hosts, bounded by the interval.
+	Jitter time.Duration
+	// Root is the directory where the current symlink is created. The working directory is used if empty.
+	Root string
+	// ReleasesRoot is the writable directory where releases are extracted. Root is used if empty.
+	ReleasesRoot string
+	// ContentAddressedReleases names release directories by the content hash of the artifact
+	// and reuses the directory when the same artifact is deployed again.
+	ContentAddressedReleases bool
+	// NotifyOncePerRelease suppresses notices of a release already shipped to other hosts.
+	NotifyOncePerRelease bool
+	// Tag is the tag of the release to deploy instead of the latest release.
+	// The tag may be moved, and the release is deployed again when its assets change.
+	Tag string
+	// MaxConcurrency is the maximum number of downloads, verifications and extractions running
+	// at the same time per stage, which overlap when a deploy takes longer than the interval. No limit if zero.
+	MaxConcurrency int
+	// MaxConcurrentDownloads is the maximum number of downloads and verifications of the app and Dewy itself
+	// running at the same time, instead of MaxConcurrency.
+	MaxConcurrentDownloads int
+	// MaxConcurrentExtractions is the maximum number of extractions running at the same time, instead of MaxConcurrency.
+	MaxConcurrentExtractions int
+	// ExtractNice is the nice value from 0 to 19 of extraction, not to starve the running server on small hosts.
+	// Supported only on Linux.
+	ExtractNice int
+	// ExtractIdleIO extracts in the idle I/O scheduling class, getting disk time only when others do not use it.
+	// Supported only on Linux.
+	ExtractIdleIO bool
+	// StatsdAddr is the address of StatsD or DogStatsD to send deploy metrics and events.
+	StatsdAddr string
+	// VerifyArch checks the server executable is built for this architecture before starting it.
+	VerifyArch bool
+	// RestartSignal is the signal sent to the old server process when the server is restarted
+	// for a new release, such as SIGUSR2 for graceful restart. SIGTERM is sent if empty.
+	RestartSignal string
+	// SystemdNotify sends READY=1 to systemd after the first successful cycle, and STATUS= and WATCHDOG=1
+	// after each cycle, for services with Type=notify.
+	SystemdNotify bool
+	// HeartbeatURL is pinged after each successful cycle for a dead man's switch service,
+	// which alerts when the pings stop.
+	HeartbeatURL string
+	// HeartbeatInterval is the minimum interval between heartbeats. Every successful cycle pings if zero.
+	HeartbeatInterval time.Duration
+	// VersionRegex extracts comparable parts from tags by capture groups to deploy the highest version
+	// instead of the latest published release, such as ^build-(\d+)-(\d+)$. Parts are compared numerically.
+	// "semver" parses semantic versions.
+	VersionRegex string
+	// Branch is the branch whose latest successful build is deployed by the github_actions registry.
+	Branch string
+	// Workflow is the workflow file building the artifact for the github_actions registry, such as build.yml.
+	Workflow string
+	// VersionURL is the endpoint returning the latest version for the http registry,
+	// whose registry URL may be a template such as https://dl.example.com/{{.Version}}/app_{{.OS}}_{{.Arch}}.tar.gz.
+	VersionURL string
+	// DrainFile defers deploys while the file exists, keeping the current server running.
+	// New artifacts are still fetched and notified, and the newest one is deployed after the file is removed.
+	DrainFile string
+	// SymlinkName is the name of the symlink to the current release in Root. "current" is used if empty.
+	SymlinkName string
+	// NotifyDiff notifies the deployed event with the summary of changed files from the previous release.
+	NotifyDiff bool
+	// WorkDir is the working directory of after deploy hooks and the server, relative to Root.
+	// {{.ReleaseDir}} is replaced with the release directory, which is the current symlink for the server
+	// so that restarted servers run in the new release. The release directory is used if empty.
+	WorkDir string
+	// Notifiers are URLs of notifiers to fan out messages, such as "slack", "slack://deploy"
+	// and "https://example.com/hook". Slack is used if empty.
+	Notifiers []string
+	// DeployLog saves the log of each deploy into the release directory as deploy.log.gz.
+	DeployLog bool
+	// MaxConsecutiveFailures quarantines the artifact after the number of consecutive failed deploys,
+	// and skips it until a new artifact is released or the quarantine is cleared. No limit if zero.
+	MaxConsecutiveFailures int
+	// Chroot is the directory where the server runs chrooted, which requires root privileges.
+	// Root and ReleasesRoot are the paths inside it, and Root is "/" if empty.
+	Chroot string
+	// CheckInodes aborts the extraction if the number of entries in the artifact exceeds free inodes.
+	CheckInodes bool
+	// VersionSourceURL is the endpoint returning the tag to deploy from GitHub releases,
+	// as plain text or JSON like {"tag":"v1.2.3"}. The latest release is deployed if empty.
+	VersionSourceURL string
+	// VersionSourceHeaders are headers sent to VersionSourceURL, with environment variables expanded.
+	VersionSourceHeaders []string
+	// SharedPaths are paths relative to the release directory, such as uploads, linked to the shared
+	// directory next to releases so that the data is kept across deploys.
+	SharedPaths []string
+	// MatchLabel matches the artifact name also against labels of GitHub release assets.
+	MatchLabel bool
+	// Foreground runs the server as a direct child of Dewy without server-starter, forwarding signals to it
+	// and exiting with its exit code, such as Dewy is the entrypoint of a container.
+	Foreground bool
+	// GlobalDeployLock is the path of the lock file to serialize deploys across Dewy instances on the host.
+	GlobalDeployLock string
+	// OTLPEndpoint is the OTLP/HTTP endpoint of the OpenTelemetry collector like "http://localhost:4318",
+	// to export traces of deploys.
+	OTLPEndpoint string
+	// RequireSignedTag refuses to deploy GitHub releases whose tag is not an annotated tag with a verified signature.
+	RequireSignedTag bool
+	// AllowedSigners are emails of taggers allowed to sign tags with RequireSignedTag. Any signer is allowed if empty.
+	AllowedSigners []string
+	// Commands are sidecars started and restarted in order together with the server on deploy.
+	Commands []Sidecar
+	// DeployerID is the team or pipeline name recorded with the host in shipping markers of GitHub releases.
+	DeployerID string
+	// DeployCooldown is the minimum interval between deploys. A release detected within it is deployed after it elapses.
+	DeployCooldown time.Duration
+	// CacheKey is the strategy to derive cache keys detecting changes of the artifact: tag, revision or url.
+	// The registry chooses it if empty, which is tag by default.
+	CacheKey string
+	// HealthCheck is the shell command succeeding when the server is healthy, run in the working directory like AfterDeploy.
+	HealthCheck string
+	// HealthCheckURL is the URL responding 2xx when the server is healthy, requested after starting or restarting the server.
+	// The server is rolled back to the previous release if it keeps failing.
+	HealthCheckURL string
+	// HealthCheckTimeout is the timeout of each request of HealthCheckURL. 5 seconds if zero.
+	HealthCheckTimeout time.Duration
+	// HealthCheckRetries is the number of retries of HealthCheckURL at intervals of a second. 5 if zero.
+	HealthCheckRetries int
+	// PostDeployWatch is the duration to keep checking the health after deploy. Sustained failures within it are notified.
+	PostDeployWatch time.Duration
+	// PostDeployRollback rolls back the server on sustained failures within PostDeployWatch, instead of recommending it.
+	PostDeployRollback bool
+	// InstallCommand is the shell command into whose stdin the artifact is streamed instead of extracting it,
+	// such as a package manager. The exit code is the result of the deploy, and no releases or symlink are created.
+	InstallCommand string
+	// SelfUpdate updates the Dewy binary itself from its own releases, which is disabled by default.
+	SelfUpdate SelfUpdateConfig
+	// SemverConstraint deploys the highest semantic version of GitHub releases satisfying the constraint,
+	// such as ">=1.2.0 <2.0.0" to track a major line. Tags not parsed as semver are skipped.
+	SemverConstraint string
+	// ArtifactAliases are other names of OS and architectures in artifact names of GitHub releases,
+	// such as amd64:x86_64, tried when the artifact templated with {{.OS}} and {{.Arch}} is not found.
+	// They take precedence over the default aliases, and an empty alias disables the default one.
+	ArtifactAliases map[string]string
+	// GCSCredentials is the path of the service account JSON for the gcs registry,
+	// which uses Application Default Credentials if empty.
+	GCSCredentials string
+	// Manifest makes the http registry URL the version endpoint whose JSON manifest has the artifact URL,
+	// such as {"version":"1.2.3","url":"https://dl.example.com/app-1.2.3.tar.gz"}.
+	Manifest bool
+	// ChecksumArtifact is the name of the checksums file published with the artifact, such as checksums.txt,
+	// against which the artifact is verified before caching.
+	ChecksumArtifact string
+	// SignatureKey is the armored OpenPGP public key, or the path to it, verifying the detached signature
+	// published with the artifact as <artifact>.sig or <artifact>.asc before caching.
+	SignatureKey string
+	// Cosign verifies the signature of cosign sign-blob published with the artifact before caching,
+	// by the public key or by the certificate of keyless signing. Disabled if empty.
+	Cosign verify.CosignConfig
+	// NotifierHeaders are headers of requests of webhook notifiers such as "Authorization: Bearer ${TOKEN}",
+	// whose values expand environment variables.
+	NotifierHeaders []string
 }
 
 // OverrideWithEnv overrides by environments.
@@ -78,6 +331,49 @@ func (c *Config) OverrideWithEnv() {
 	}
 }
 
+// redacted replaces secrets in the configuration.
+const redacted = "REDACTED"
+
+// Redacted returns a copy of Config with secrets redacted, such as paths of webhook URLs
+// and values of headers, to print the configuration.
+func (c Config) Redacted() Config {
+	c.Notifiers = append([]string(nil), c.Notifiers...)
+	for i, n := range c.Notifiers {
+		c.Notifiers[i] = redactURL(n)
+	}
+	c.HeartbeatURL = redactURL(c.HeartbeatURL)
+	if u, err := url.Parse(c.Cache.URL); err == nil && u.User != nil {
+		c.Cache.URL = u.Redacted()
+	}
+	c.VersionSourceURL = redactURL(c.VersionSourceURL)
+	c.VersionSourceHeaders = redactHeaders(c.VersionSourceHeaders)
+	c.NotifierHeaders = redactHeaders(c.NotifierHeaders)
+	return c
+}
+
+// redactHeaders redacts values of headers.
+func redactHeaders(hh []string) []string {
+	hh = append([]string(nil), hh...)
+	for i, h := range hh {
+		if name, _, ok := strings.Cut(h, ":"); ok {
+			hh[i] = name + ": " + redacted
+		}
+	}
+	return hh
+}
+
+// redactURL keeps only the scheme and the host of http and discord URLs, whose path or query may contain tokens.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "discord") {
+		return s
+	}
+	if u.User == nil && (u.Path == "" || u.Path == "/") && u.RawQuery == "" {
+		return s
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
 // DefaultConfig returns default Config.
 func DefaultConfig() Config {
 	return Config{
@@ -0,0 +1,44 @@
+package dewy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConfigRedacted(t *testing.T) {
+	c := Config{
+		Command:              SERVER,
+		Notifiers:            []string{"slack://deploy", "https://hooks.example.com/services/T000/B000/secret"},
+		HeartbeatURL:         "https://hc-ping.com/uuid",
+		VersionSourceURL:     "https://deploy.example.com/",
+		VersionSourceHeaders: []string{"Authorization: Bearer secret"},
+		NotifierHeaders:      []string{"Authorization: Bearer secret"},
+		Starter:              &StarterConfig{command: "app", ports: []string{"8000"}},
+	}
+	r := c.Redacted()
+
+	if c.Notifiers[1] == r.Notifiers[1] || c.VersionSourceHeaders[0] == r.VersionSourceHeaders[0] {
+		t.Error("original config should not be modified")
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	if strings.Contains(got, "secret") || strings.Contains(got, "uuid") {
+		t.Errorf("secrets are not redacted: %s", got)
+	}
+	for _, want := range []string{
+		`"Command":"server"`,
+		`"slack://deploy"`,
+		`"https://hooks.example.com/REDACTED"`,
+		`"https://deploy.example.com/"`,
+		`"Authorization: REDACTED"`,
+		`"Starter":{"Command":"app","Args":null,"Ports":["8000"]}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%s is not in %s", want, got)
+		}
+	}
+}
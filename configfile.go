@@ -0,0 +1,66 @@
+package dewy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	flags "github.com/jessevdk/go-flags"
+)
+
+// loadConfigFile sets options not given by flags from the config file. Options are written as
+// "long-name = value" lines, where top-level options are shared by profiles in sections like "[staging]",
+// and options of the profile take precedence over them.
+func loadConfigFile(p *flags.Parser, path, profile string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sections := splitSections(string(b))
+	// options set first take precedence, as the later values are parsed as defaults
+	var names []string
+	if profile != "" {
+		if _, ok := sections[profile]; !ok {
+			return fmt.Errorf("profile %q is not found in %s", profile, path)
+		}
+		names = append(names, profile)
+	}
+	names = append(names, "")
+	for _, name := range names {
+		ip := flags.NewIniParser(p)
+		ip.ParseAsDefaults = true
+		if err := ip.Parse(strings.NewReader(sections[name])); err != nil {
+			var ie *flags.IniError
+			if errors.As(err, &ie) {
+				ie.File = path
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// splitSections returns the lines of each section by the name, keeping line numbers for errors
+// by blank lines in place of other sections. The top-level section is named empty.
+func splitSections(s string) map[string]string {
+	lines := strings.Split(s, "\n")
+	sections := map[string][]string{"": make([]string, len(lines))}
+	name := ""
+	for i, l := range lines {
+		t := strings.TrimSpace(l)
+		if strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]") {
+			name = strings.TrimSpace(t[1 : len(t)-1])
+			if _, ok := sections[name]; !ok {
+				sections[name] = make([]string, len(lines))
+			}
+			continue
+		}
+		sections[name][i] = l
+	}
+	m := map[string]string{}
+	for n, ls := range sections {
+		m[n] = strings.Join(ls, "\n")
+	}
+	return m
+}
@@ -0,0 +1,49 @@
+package dewy
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// pidKey is the cache key of the process ID of Dewy running the server,
+// to restart the server from commands such as redeploy and rollback.
+const pidKey = "dewy.pid"
+
+// writePid records the process ID of Dewy running the server, and returns the function removing it.
+func (d *Dewy) writePid() func() {
+	if err := d.state.Write(pidKey, []byte(strconv.Itoa(os.Getpid()))); err != nil {
+		log.Printf("[WARN] PID write failure: %s", err)
+		return func() {}
+	}
+	return func() {
+		if err := d.state.Delete(pidKey); err != nil {
+			log.Printf("[WARN] PID delete failure: %s", err)
+		}
+	}
+}
+
+// restartRunningServer sends SIGHUP to Dewy running the server on the host to restart the server
+// with the current release. Nothing is done if no Dewy runs the server.
+func (d *Dewy) restartRunningServer() error {
+	b, err := d.state.Read(pidKey)
+	if err != nil {
+		log.Print("[INFO] No running server to restart")
+		return nil
+	}
+	pid, err := strconv.Atoi(string(b))
+	if err != nil {
+		return fmt.Errorf("invalid PID of Dewy running the server: %w", err)
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Send SIGHUP to Dewy PID %d for server restart", pid)
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		return fmt.Errorf("server restart failure: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package dewy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// deployLogName is the name of the compressed deploy log in the release directory.
+const deployLogName = "deploy.log.gz"
+
+// logCapture tees the log output into buffers of running cycles.
+type logCapture struct {
+	mu   sync.Mutex
+	out  io.Writer
+	bufs map[*bytes.Buffer]struct{}
+}
+
+func newLogCapture(out io.Writer) *logCapture {
+	return &logCapture{out: out, bufs: map[*bytes.Buffer]struct{}{}}
+}
+
+func (c *logCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	for b := range c.bufs {
+		b.Write(p)
+	}
+	c.mu.Unlock()
+	return c.out.Write(p)
+}
+
+// capture starts capturing the log until stop is called.
+func (c *logCapture) capture() (*bytes.Buffer, func()) {
+	b := new(bytes.Buffer)
+	c.mu.Lock()
+	c.bufs[b] = struct{}{}
+	c.mu.Unlock()
+	return b, func() {
+		c.mu.Lock()
+		delete(c.bufs, b)
+		c.mu.Unlock()
+	}
+}
+
+// saveDeployLog writes the captured log of the cycle into the release directory with gzip,
+// so that it is pruned together with the release.
+func saveDeployLog(release string, data []byte) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	p := filepath.Join(release, deployLogName)
+	if err := os.WriteFile(p, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Deploy log is saved to %s", p)
+	return nil
+}
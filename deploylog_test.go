@@ -0,0 +1,56 @@
+package dewy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogCapture(t *testing.T) {
+	var out bytes.Buffer
+	c := newLogCapture(&out)
+	if _, err := c.Write([]byte("before\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf, stop := c.capture()
+	if _, err := c.Write([]byte("during\n")); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	if _, err := c.Write([]byte("after\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := buf.String(), "during\n"; got != want {
+		t.Errorf("captured %q, want %q", got, want)
+	}
+	if got, want := out.String(), "before\nduring\nafter\n"; got != want {
+		t.Errorf("output %q, want %q", got, want)
+	}
+}
+
+func TestSaveDeployLog(t *testing.T) {
+	release := t.TempDir()
+	if err := saveDeployLog(release, []byte("[INFO] deployed\n")); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filepath.Join(release, deployLogName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "[INFO] deployed\n" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -3,15 +3,20 @@ package dewy
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"runtime"
-	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -19,9 +24,18 @@ import (
 	starter "github.com/lestrrat-go/server-starter"
 	"github.com/linyows/dewy/kvs"
 	"github.com/linyows/dewy/notice"
+	"github.com/linyows/dewy/otlp"
 	"github.com/linyows/dewy/registry"
+	gcsreg "github.com/linyows/dewy/registry/gcs"
+	ghactions "github.com/linyows/dewy/registry/github_actions"
 	ghrelease "github.com/linyows/dewy/registry/github_release"
+	glrelease "github.com/linyows/dewy/registry/gitlab_release"
+	httpreg "github.com/linyows/dewy/registry/http"
+	s3reg "github.com/linyows/dewy/registry/s3"
+	"github.com/linyows/dewy/statsd"
 	"github.com/linyows/dewy/storage"
+	"github.com/linyows/dewy/verify"
+	"github.com/robfig/cron/v3"
 )
 
 const (
@@ -30,18 +44,84 @@ const (
 	releasesDir  = "releases"
 	symlinkDir   = "current"
 	keepReleases = 7
+	currentKey   = "current.txt"
+	// recursiveExtractDepth is the depth limit of nested archives to prevent archive bombs.
+	recursiveExtractDepth = 1
+	// serverStartWait is the duration to wait for the server to fail to start.
+	serverStartWait    = time.Second
+	startRetryInterval = 3 * time.Second
+	// noticeAttempts is the number of attempts to deliver a notice.
+	noticeAttempts      = 3
+	noticeRetryInterval = 5 * time.Second
+	noticeCloseTimeout  = 10 * time.Second
+	// contentHashLength is the length of the hex content hash used as release directory names.
+	contentHashLength   = 12
+	defaultReadyTimeout = 30 * time.Second
+	readyPollInterval   = 500 * time.Millisecond
+	// defaultInterval is the polling interval if not configured.
+	defaultInterval = 10 * time.Second
 )
 
+// quietContextKey is the context key to suppress notices.
+type quietContextKey struct{}
+
+// shippedHoster is implemented by registries recording shipping to hosts.
+type shippedHoster interface {
+	ShippedHosts(tag string) ([]string, error)
+}
+
+// ErrServerStart is returned when the server fails to start or restart.
+var ErrServerStart = errors.New("server failed to start")
+
+// ErrNoSpace is returned when the disk fills up while extracting the artifact.
+var ErrNoSpace = errors.New("no space left to extract")
+
 // Dewy struct.
 type Dewy struct {
 	config          Config
 	registry        registry.Registry
 	cache           kvs.KVS
+	state           kvs.KVS
+	storageOpts     storage.Options
+	verifier        verify.Chain
 	isServerRunning bool
 	disableReport   bool
+	deployedKey     string
+	previousRelease string
+	downloads       throttle
+	extractions     throttle
+	statsd          *statsd.Client
+	tracer          *otlp.Tracer
+	systemdReady    bool
+	lastHeartbeat   time.Time
+	pendingKey      string
+	lastDeployedAt  time.Time
+	currentChecked  bool
+	logs            *logCapture
+	failedKey       string
+	failures        int
+	force           bool
+	runningKey      string
+	fg              *foreground
+	starter         *starter.Starter
+	starterDone     chan error
+	sidecars        map[string]*foreground
+	reaper          *reaper
+	exitCode        int
+	selfRegistry    registry.Registry
+	selfJob         *scheduler.Job
+	selfUpdated     chan string
+	selfUpdatedTag  string
+	reexecTag       string
 	root            string
 	job             *scheduler.Job
 	notice          notice.Notice
+	remoteRelease   string
+	failedRemotes   []RemoteHost
+	remoteDeploy    func(h RemoteHost, release string) error
+	extractCheck    func(dst string, files int, bytes int64) error
+	stopWatch       context.CancelFunc
+	deploying       sync.Mutex
 	sync.RWMutex
 }
 
@@ -49,35 +129,244 @@ type Dewy struct {
 func New(c Config) (*Dewy, error) {
 	kv := &kvs.File{}
 	kv.Default()
+	if err := kvs.ValidateCompression(c.Cache.Compression); err != nil {
+		return nil, err
+	}
+	kv.Compression = c.Cache.Compression
+	kv.CompressionLevel = c.Cache.CompressionLevel
+	kv.DirMode = c.DirMode
+	kv.FileMode = c.FileMode
+	if c.Cache.Dir != "" {
+		if err := kv.SetDir(c.Cache.Dir); err != nil {
+			return nil, err
+		}
+	}
 
-	wd, err := os.Getwd()
-	if err != nil {
+	// the state of the host such as the current key stays in the local directory even if the cache is shared by hosts
+	var cache, state kvs.KVS = kv, kv
+	if c.Cache.URL != "" {
+		scheme, _, _ := strings.Cut(c.Cache.URL, "://")
+		switch scheme {
+		case "redis":
+			rc, err := kvs.NewRedis(c.Cache.URL, kv)
+			if err != nil {
+				return nil, err
+			}
+			c.Cache.Type = REDIS
+			cache = rc
+		case "s3":
+			sc, err := kvs.NewS3(c.Cache.URL, kv)
+			if err != nil {
+				return nil, err
+			}
+			c.Cache.Type = S3
+			cache = sc
+		case "memory":
+			m, err := kvs.NewMemory()
+			if err != nil {
+				return nil, err
+			}
+			c.Cache.Type = MEMORY
+			cache, state = m, m
+		default:
+			return nil, fmt.Errorf("unsupported cache: %s", scheme)
+		}
+	}
+
+	if c.Schedule != "" {
+		if _, err := parseCron(c.Schedule); err != nil {
+			return nil, err
+		}
+	}
+	if c.KeepReleases < 0 {
+		return nil, fmt.Errorf("keep releases must not be negative: %d", c.KeepReleases)
+	}
+	if c.Interval < 0 || (c.Interval > 0 && c.Interval < time.Second) {
+		return nil, fmt.Errorf("interval must be at least 1s: %s", c.Interval)
+	}
+
+	if c.ExtractNice < 0 || c.ExtractNice > 19 {
+		return nil, fmt.Errorf("extract nice must be from 0 to 19: %d", c.ExtractNice)
+	}
+
+	if c.RestartSignal != "" && starter.SigFromName(c.RestartSignal) == nil {
+		return nil, fmt.Errorf("unknown restart signal: %s", c.RestartSignal)
+	}
+
+	if err := notice.ValidateTemplates(c.NoticeTemplates); err != nil {
 		return nil, err
 	}
 
-	r, err := newRegistry(c.Registry, c.PreRelease, c.ArtifactName)
+	if c.SignatureKey != "" {
+		k, err := readKey(c.SignatureKey)
+		if err != nil {
+			return nil, err
+		}
+		c.SignatureKey = k
+	}
+
+	if c.Cosign != (verify.CosignConfig{}) {
+		for _, k := range []*string{&c.Cosign.Key, &c.Cosign.Roots} {
+			if *k == "" {
+				continue
+			}
+			v, err := readKey(*k)
+			if err != nil {
+				return nil, err
+			}
+			*k = v
+		}
+	}
+
+	if c.WorkDir != "" {
+		if err := validateWorkDir(c.WorkDir); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range c.SharedPaths {
+		if err := validateSharedPath(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if n := c.SymlinkName; n != "" && (n != filepath.Base(n) || n == "." || n == ".." || n == releasesDir) {
+		return nil, fmt.Errorf("invalid symlink name: %s", n)
+	}
+
+	var err error
+	wd := c.Root
+	if c.Chroot != "" {
+		c.Chroot, err = filepath.Abs(c.Chroot)
+		if err != nil {
+			return nil, err
+		}
+		if fi, err := os.Stat(c.Chroot); err != nil || !fi.IsDir() {
+			return nil, fmt.Errorf("chroot is not a directory: %s", c.Chroot)
+		}
+		// the root is the path inside the chroot
+		if wd == "" {
+			wd = "/"
+		}
+		wd = filepath.Join(c.Chroot, wd)
+		if sc, ok := c.Starter.(*StarterConfig); ok {
+			chrootStarter(sc, c.Chroot)
+		}
+	} else if wd == "" {
+		wd, err = os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	so := storageOptions(c)
+	verifier, err := newVerifier(c, so.Fetch)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Dewy{
+	var r registry.Registry
+	if c.Registry != "" {
+		r, err = newRegistry(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := newCacheKeyer(c.CacheKey, r); err != nil {
+		return nil, err
+	}
+	var sr registry.Registry
+	if c.SelfUpdate.Registry != "" {
+		if _, err := c.SelfUpdate.verifier(); err != nil {
+			return nil, err
+		}
+		sr, err = newRegistry(Config{Registry: c.SelfUpdate.Registry})
+		if err != nil {
+			return nil, fmt.Errorf("self update: %w", err)
+		}
+	}
+
+	var sc *statsd.Client
+	if c.StatsdAddr != "" {
+		hostname, _ := os.Hostname()
+		tags := []string{"host:" + hostname}
+		if c.Role != "" {
+			tags = append(tags, "role:"+c.Role)
+		}
+		sc, err = statsd.New(c.StatsdAddr, append(tags, c.Tags...))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var tr *otlp.Tracer
+	if c.OTLPEndpoint != "" {
+		hostname, _ := os.Hostname()
+		attrs := map[string]string{"host.name": hostname}
+		if c.Role != "" {
+			attrs["dewy.role"] = c.Role
+		}
+		tr, err = otlp.New(c.OTLPEndpoint, attrs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var fg *foreground
+	var rp *reaper
+	if c.Foreground && c.Starter != nil {
+		rp = newReaper()
+		fg = newForeground(c.Starter, rp)
+	}
+
+	var logs *logCapture
+	if c.DeployLog {
+		// tee in front of the level filter to record the full log
+		logs = newLogCapture(log.Writer())
+		log.SetOutput(logs)
+	}
+
+	d := &Dewy{
 		config:          c,
-		cache:           kv,
+		cache:           cache,
+		state:           state,
 		registry:        r,
+		storageOpts:     so,
+		verifier:        verifier,
 		isServerRunning: false,
 		root:            wd,
-	}, nil
+		downloads:       newThrottle(stageLimit(c.MaxConcurrentDownloads, c.MaxConcurrency)),
+		extractions:     newThrottle(stageLimit(c.MaxConcurrentExtractions, c.MaxConcurrency)),
+		statsd:          sc,
+		tracer:          tr,
+		logs:            logs,
+		fg:              fg,
+		reaper:          rp,
+		selfRegistry:    sr,
+	}
+	if d.notice, err = d.newNotice(); err != nil {
+		return nil, err
+	}
+	return d, nil
 }
 
-// Start dewy.
-func (d *Dewy) Start(i int) {
-	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), notice.MetaContextKey, true))
-	defer cancel()
-	var err error
+// Close releases the cache, such as removing the staging directory of the memory cache.
+func (d *Dewy) Close() error {
+	if c, ok := d.cache.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
 
+// newNotice returns the configured notifier, which is Slack by default.
+func (d *Dewy) newNotice() (notice.Notice, error) {
 	nc := &notice.Config{
 		Source:  d.config.ArtifactName,
 		Command: d.config.Command.String(),
+		Role:    d.config.Role,
+		Tags:    d.config.Tags,
+
+		WebhookHeaders: d.config.NotifierHeaders,
 	}
 	repo, ok := d.registry.(*ghrelease.GithubRelease)
 	if ok {
@@ -88,57 +377,253 @@ func (d *Dewy) Start(i int) {
 		nc.RepoLink = repo.URL()
 	}
 
-	d.notice, err = notice.New(&notice.Slack{Meta: nc})
-	if err != nil {
-		log.Printf("[ERROR] Notice failure: %#v", err)
-		return
+	if len(d.config.Notifiers) > 0 {
+		return notice.NewFromURLs(d.config.Notifiers, nc)
+	}
+	return notice.New(&notice.Slack{Meta: nc})
+}
+
+// Start dewy.
+func (d *Dewy) Start() {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), notice.MetaContextKey, true))
+	defer cancel()
+	var err error
+	// executed last, after the stop notice is delivered
+	defer func() {
+		if d.reexecTag != "" {
+			d.reexec()
+		}
+	}()
+
+	if d.config.Command == SERVER {
+		defer d.writePid()()
+	}
+
+	q := notice.NewQueue(d.notice, noticeAttempts, noticeRetryInterval)
+	defer q.Close(noticeCloseTimeout)
+	d.notice = q
+	if repo, ok := d.registry.(*ghrelease.GithubRelease); ok {
+		if err := repo.Probe(ctx); errors.Is(err, ghrelease.ErrIncompatible) {
+			log.Printf("[ERROR] Registry failure: %s", err)
+			return
+		} else if err != nil {
+			log.Printf("[WARN] Registry probe failure: %s", err)
+		}
 	}
-	d.notice.Notify(ctx, "Automatic shipping started by Dewy")
+	d.notify(ctx, notice.EventStart, notice.Message{})
 	ctx, cancel = context.WithCancel(context.Background())
 	defer cancel()
 
-	d.job, err = scheduler.Every(i).Seconds().Run(func() {
+	i := int(defaultInterval / time.Second)
+	if d.config.Interval > 0 {
+		i = int(d.config.Interval / time.Second)
+	}
+	run := func() {
 		e := d.Run()
 		if e != nil {
 			log.Printf("[ERROR] Dewy run failure: %#v", e)
 		}
-	})
+		d.notifySystemd(e)
+		if e == nil {
+			d.heartbeat()
+		}
+	}
+	// nil without the schedule, which is validated in New
+	s, _ := parseCron(d.config.Schedule)
+	if d.config.Jitter > 0 {
+		run = d.withJitter(run, s, time.Duration(i)*time.Second)
+	}
+	if s != nil {
+		log.Printf("[INFO] Run on the schedule %s, next at %s", d.config.Schedule, s.Next(time.Now()).Format(time.RFC3339))
+		d.job = runCron(s, run)
+	} else {
+		d.job, err = scheduler.Every(i).Seconds().Run(run)
+	}
 	if err != nil {
 		log.Printf("[ERROR] Scheduler failure: %#v", err)
 	}
+	if d.selfRegistry != nil {
+		if err := d.startSelfUpdate(); err != nil {
+			log.Printf("[ERROR] Scheduler failure: %#v", err)
+		}
+	}
 
-	d.notice.Notify(ctx, fmt.Sprintf("Stop receiving \"%s\" signal", d.waitSigs()))
+	if d.fg == nil {
+		sig := d.waitSigs()
+		d.stopSidecars()
+		if sig != nil {
+			d.notify(ctx, notice.EventStop, notice.Message{Signal: sig.String()})
+		} else {
+			// the updated Dewy starts the server by server-starter again
+			d.stopStarter()
+			d.notify(ctx, notice.EventStop, notice.Message{Error: fmt.Sprintf("Dewy is updated to %s", d.reexecTag)})
+		}
+		return
+	}
+	sig := d.waitForeground()
+	d.stopSidecars()
+	if sig != nil {
+		d.notify(ctx, notice.EventStop, notice.Message{Signal: sig.String()})
+	} else if d.reexecTag != "" {
+		d.notify(ctx, notice.EventStop, notice.Message{Error: fmt.Sprintf("Dewy is updated to %s", d.reexecTag)})
+	} else {
+		d.notify(ctx, notice.EventStop, notice.Message{Error: fmt.Sprintf("server exited with %d", d.exitCode)})
+	}
+}
+
+// withJitter delays f randomly up to the jitter, bounded by the interval or the time to the next time of the schedule,
+// to spread polling of hosts. The first run is not delayed to start the server promptly.
+func (d *Dewy) withJitter(f func(), s cron.Schedule, interval time.Duration) func() {
+	var started atomic.Bool
+	return func() {
+		if started.Swap(true) {
+			bound := interval
+			if s != nil {
+				bound = time.Until(s.Next(time.Now()))
+			}
+			delay := jitterDelay(d.config.Jitter, bound)
+			log.Printf("[DEBUG] Run after jitter of %s", delay)
+			time.Sleep(delay)
+		}
+		f()
+	}
 }
 
+// notifySystemd reports the result of the cycle to systemd.
+func (d *Dewy) notifySystemd(runErr error) {
+	if !d.config.SystemdNotify {
+		return
+	}
+	d.Lock()
+	defer d.Unlock()
+
+	// the watchdog tracks that dewy keeps polling, so it is kicked even if the cycle failed
+	state := fmt.Sprintf("STATUS=Deployed %s\nWATCHDOG=1", d.deployedKey)
+	if runErr != nil {
+		state = fmt.Sprintf("STATUS=Failed: %s\nWATCHDOG=1", runErr)
+	} else if !d.systemdReady {
+		state = "READY=1\n" + state
+	}
+	if err := sdNotify(state); err != nil {
+		log.Printf("[ERROR] Systemd notify failure: %#v", err)
+		return
+	}
+	if runErr == nil {
+		d.systemdReady = true
+	}
+}
+
+// waitSigs waits for the signal to stop, or for Dewy to be updated. SIGHUP is handled by server-starter
+// to restart the server, and is ignored not to stop Dewy before the server starts.
 func (d *Dewy) waitSigs() os.Signal {
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-	sigReceived := <-sigCh
-	log.Printf("[DEBUG] PID %d received signal as %s", os.Getpid(), sigReceived)
-	d.job.Quit <- true
-	return sigReceived
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+	defer d.quitSelfUpdate()
+	for {
+		select {
+		case sigReceived := <-sigCh:
+			log.Printf("[DEBUG] PID %d received signal as %s", os.Getpid(), sigReceived)
+			if sigReceived == syscall.SIGHUP {
+				continue
+			}
+			d.job.Quit <- true
+			return sigReceived
+		case d.reexecTag = <-d.selfUpdated:
+			d.job.Quit <- true
+			return nil
+		}
+	}
 }
 
 // Run dewy.
 func (d *Dewy) Run() error {
-	ctx, cancel := context.WithCancel(context.Background())
+	span := d.tracer.Start("run")
+	err := d.run(otlp.ContextWithSpan(context.Background(), span))
+	// idle cycles only fetching the current artifact are not traced
+	if err != nil || span.Len() > 1 {
+		span.End(err)
+	}
+	return err
+}
+
+func (d *Dewy) run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	started := time.Now()
+	var release string
+	if d.logs != nil {
+		buf, stop := d.logs.capture()
+		defer func() {
+			stop()
+			if release == "" {
+				return
+			}
+			if err := saveDeployLog(release, buf.Bytes()); err != nil {
+				log.Printf("[ERROR] Deploy log failure: %#v", err)
+			}
+		}()
+	}
+	d.statsd.Count("cycle", 1)
+	d.checkCurrentOnce()
+	if d.config.Offline {
+		return d.runOffline(ctx)
+	}
+
+	if d.registry == nil {
+		return errors.New("registry is not set")
+	}
+
+	artifact, err := expandArtifact(d.config.ArtifactName)
+	if err != nil {
+		return err
+	}
+
 	// Get current
-	res, err := d.registry.Current(&registry.CurrentRequest{
-		Arch:         runtime.GOARCH,
-		OS:           runtime.GOOS,
-		ArtifactName: d.config.ArtifactName,
+	span := otlp.SpanFromContext(ctx).Start("fetch")
+	var res *registry.CurrentResponse
+	err = d.retry(ctx, "Current", func() error {
+		var err error
+		res, err = d.registry.Current(&registry.CurrentRequest{
+			Arch:         runtime.GOARCH,
+			OS:           runtime.GOOS,
+			ArtifactName: artifact,
+		})
+		return err
 	})
+	if res != nil {
+		span.SetAttr("dewy.tag", res.Tag)
+		span.SetAttr("dewy.artifact_url", res.ArtifactURL)
+		otlp.SpanFromContext(ctx).SetAttr("dewy.tag", res.Tag)
+	}
+	span.End(err)
+	if errors.Is(err, registry.ErrNotReady) {
+		log.Printf("[INFO] Deploy skipped: %s", err)
+		return nil
+	}
 	if err != nil {
 		log.Printf("[ERROR] Current failure: %#v", err)
 		return err
 	}
+	if err := d.saveRelease(res); err != nil {
+		log.Printf("[ERROR] Save release failure: %#v", err)
+	}
 
 	// Check cache
-	cacheKey := fmt.Sprintf("%s-%s", res.Tag, filepath.Base(res.ArtifactURL))
-	currentKey := "current.txt"
-	currentSourceKey, _ := d.cache.Read(currentKey)
+	keyer, err := d.cacheKeyer()
+	if err != nil {
+		return err
+	}
+	cacheKey := keyer.CacheKey(res)
+	if !d.force {
+		d.holdPin(cacheKey)
+	}
+	if !d.force && d.isQuarantined(cacheKey) {
+		log.Printf("[WARN] %s is quarantined, deploy skipped", cacheKey)
+		return nil
+	}
+	currentSourceKey, _ := d.state.Read(currentKey)
 	found := false
 	list, err := d.cache.List()
 	if err != nil {
@@ -147,7 +632,25 @@ func (d *Dewy) Run() error {
 	for _, key := range list {
 		// same current version and already cached
 		if string(currentSourceKey) == cacheKey && key == cacheKey {
-			log.Print("[DEBUG] Deploy skipped")
+			if err := d.retryRemotes(); err != nil {
+				return err
+			}
+			if d.config.Command != SERVER || d.isServerRunning {
+				log.Print("[DEBUG] Deploy skipped")
+				return nil
+			}
+			// the server is not running yet
+			if d.config.SkipRunningVersion && d.isDeployed(cacheKey) {
+				log.Printf("[INFO] %s is already deployed, deploy skipped", cacheKey)
+				d.deployedKey = cacheKey
+				d.notify(ctx, notice.EventServerStart, notice.Message{Tag: res.Tag, URL: res.ArtifactURL, Duration: time.Since(started)})
+				if err := d.startServer(); err != nil {
+					log.Printf("[ERROR] Server failure: %#v", err)
+					return err
+				}
+				return nil
+			}
+			found = true
 			break
 		}
 
@@ -160,104 +663,826 @@ func (d *Dewy) Run() error {
 
 	// Download artifact and cache
 	if !found {
-		buf := new(bytes.Buffer)
-		if err := storage.Fetch(res.ArtifactURL, buf); err != nil {
-			return err
-		}
-		if err := d.cache.Write(cacheKey, buf.Bytes()); err != nil {
+		err := d.downloads.do(func() error {
+			buf := new(bytes.Buffer)
+			span := otlp.SpanFromContext(ctx).Start("download")
+			err := d.retry(ctx, "Download", func() error {
+				buf.Reset()
+				return d.fetch(res.ArtifactURL, buf)
+			})
+			span.SetAttr("dewy.size", buf.Len())
+			span.End(err)
+			if err != nil {
+				return err
+			}
+			a := &verify.Artifact{Tag: res.Tag, URL: res.ArtifactURL}
+			span = otlp.SpanFromContext(ctx).Start("verify")
+			err = d.verifier.Verify(ctx, a, bytes.NewReader(buf.Bytes()))
+			span.End(err)
+			if err != nil {
+				log.Printf("[ERROR] Verify failure: %#v", err)
+				d.notify(ctx, notice.EventVerifyFailure, notice.Message{Tag: res.Tag, URL: res.ArtifactURL, Duration: time.Since(started), Error: err.Error()})
+				return err
+			}
+			return d.cache.Write(cacheKey, buf.Bytes())
+		})
+		if err != nil {
 			return err
 		}
 		log.Printf("[INFO] Cached as %s", cacheKey)
 	}
 
-	if d.notice != nil {
-		d.notice.Notify(ctx, fmt.Sprintf("New shipping <%s|%s> was detected",
-			res.ArtifactURL, res.Tag))
+	if d.config.NotifyOncePerRelease && d.shippedToOthers(res.Tag) {
+		ctx = context.WithValue(ctx, quietContextKey{}, true)
+	}
+
+	if !d.force && d.draining() {
+		return d.drain(ctx, cacheKey, notice.Message{Tag: res.Tag, URL: res.ArtifactURL, Duration: time.Since(started)})
+	}
+	d.pendingKey = ""
+
+	if !d.force && d.coolingDown() {
+		log.Printf("[INFO] Deploy of %s is deferred for cooldown until %s", cacheKey, d.lastDeployedAt.Add(d.config.DeployCooldown).Format(time.RFC3339))
+		return nil
 	}
 
-	if err := d.deploy(cacheKey); err != nil {
+	d.notify(ctx, notice.EventDetect, notice.Message{Tag: res.Tag, URL: res.ArtifactURL, Duration: time.Since(started)})
+
+	unlock, err := d.lockDeploy()
+	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	if d.config.Command == SERVER {
-		if d.isServerRunning {
-			d.notice.Notify(ctx, "Server restarting")
-			err = d.restartServer()
+	if err := d.deployContext(ctx, cacheKey, res.Tag); err != nil {
+		d.statsd.Count("deploy.failure", 1, "tag:"+res.Tag)
+		if errors.Is(err, ErrNoSpace) {
+			// never suppressed, and not a failure of the release to be quarantined
+			d.notify(context.WithValue(ctx, quietContextKey{}, nil), notice.EventDiskFull,
+				notice.Message{Tag: res.Tag, URL: res.ArtifactURL, Duration: time.Since(started), Disk: d.diskUsage(), Error: err.Error()})
 		} else {
-			d.notice.Notify(ctx, "Server starting")
-			err = d.startServer()
+			d.recordFailure(ctx, cacheKey, notice.Message{Tag: res.Tag, URL: res.ArtifactURL, Duration: time.Since(started)}, err)
 		}
-		if err != nil {
-			log.Printf("[ERROR] Server failure: %#v", err)
+		if !d.disableReport {
+			if rerr := d.registry.Report(&registry.ReportRequest{ID: res.ID, Tag: res.Tag, Err: err}); rerr != nil && !errors.Is(rerr, err) {
+				log.Printf("[ERROR] Report failure: %#v", rerr)
+			}
 		}
+		return err
+	}
+	d.lastDeployedAt = time.Now()
+
+	release, _ = d.readCurrent()
+
+	var diff string
+	if d.config.NotifyDiff {
+		diff = d.releaseDiff()
+	}
+
+	hookErr := d.afterDeploy(ctx, notice.Message{Tag: res.Tag, URL: res.ArtifactURL, Duration: time.Since(started)})
+	if errors.Is(hookErr, ErrServerStart) {
+		d.statsd.Count("deploy.failure", 1, "tag:"+res.Tag)
+		d.recordFailure(ctx, cacheKey, notice.Message{Tag: res.Tag, URL: res.ArtifactURL, Duration: time.Since(started)}, hookErr)
+		if !d.disableReport {
+			if rerr := d.registry.Report(&registry.ReportRequest{ID: res.ID, Tag: res.Tag, Err: hookErr}); rerr != nil && !errors.Is(rerr, hookErr) {
+				log.Printf("[ERROR] Report failure: %#v", rerr)
+			}
+		}
+		return hookErr
+	}
+
+	d.resetFailures()
+	d.statsd.Count("deploy.success", 1, "tag:"+res.Tag)
+	d.statsd.Timing("deploy.duration", time.Since(started), "tag:"+res.Tag)
+	d.statsd.Event("Dewy deployed "+res.Tag, fmt.Sprintf("%s was deployed from %s", res.Tag, res.ArtifactURL), "tag:"+res.Tag)
+	if d.config.NotifyDiff {
+		d.notify(ctx, notice.EventDeployed, notice.Message{Tag: res.Tag, URL: res.ArtifactURL, Duration: time.Since(started), Diff: diff})
 	}
 
 	if !d.disableReport {
 		log.Print("[DEBUG] Report shipping")
 		err := d.registry.Report(&registry.ReportRequest{
-			ID:  res.ID,
-			Tag: res.Tag,
+			ID:         res.ID,
+			Tag:        res.Tag,
+			Role:       d.config.Role,
+			Tags:       d.config.Tags,
+			DeployerID: d.config.DeployerID,
 		})
 		if err != nil {
 			log.Printf("[ERROR] Report shipping failure: %#v", err)
 		}
 	}
 
-	log.Printf("[INFO] Keep releases as %d", keepReleases)
-	err = d.keepReleases()
+	d.cleanupReleases()
+
+	return hookErr
+}
+
+// symlinkName returns the name of the symlink to the current release.
+func (d *Dewy) symlinkName() string {
+	if d.config.SymlinkName != "" {
+		return d.config.SymlinkName
+	}
+	return symlinkDir
+}
+
+// currentPath returns the path of the symlink to the current release.
+func (d *Dewy) currentPath() string {
+	return filepath.Join(d.root, d.symlinkName())
+}
+
+// draining reports whether the drain file exists.
+func (d *Dewy) draining() bool {
+	return d.config.DrainFile != "" && kvs.IsFileExist(d.config.DrainFile)
+}
+
+// coolingDown reports whether the last deploy is within the cooldown.
+// The newest release fetched in later cycles is deployed after it elapses.
+func (d *Dewy) coolingDown() bool {
+	return d.config.DeployCooldown > 0 && !d.lastDeployedAt.IsZero() && time.Since(d.lastDeployedAt) < d.config.DeployCooldown
+}
+
+// drain defers the deploy of the cached artifact until the drain file is removed.
+// The current server keeps running, and it is started if not running yet.
+func (d *Dewy) drain(ctx context.Context, cacheKey string, msg notice.Message) error {
+	if d.pendingKey != cacheKey {
+		log.Printf("[INFO] Deploy of %s is deferred while %s exists", cacheKey, d.config.DrainFile)
+		d.notify(ctx, notice.EventDrain, msg)
+		d.pendingKey = cacheKey
+	}
+	if d.config.Command == SERVER && !d.isServerRunning && d.hasCurrentRelease() {
+		if err := d.startServer(); err != nil {
+			log.Printf("[ERROR] Server failure: %#v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// runOffline deploys the cached current version without accessing the registry.
+func (d *Dewy) runOffline(ctx context.Context) error {
+	key, err := d.state.Read(currentKey)
 	if err != nil {
-		log.Printf("[ERROR] Keep releases failure: %#v", err)
+		return fmt.Errorf("offline mode requires the cached current version: %w", err)
+	}
+	cacheKey := string(key)
+	if err := d.stage(cacheKey); err != nil || !kvs.IsFileExist(filepath.Join(d.cache.GetDir(), cacheKey)) {
+		return fmt.Errorf("offline mode requires the cached artifact: %s", cacheKey)
+	}
+	if d.deployedKey == cacheKey {
+		log.Print("[DEBUG] Deploy skipped")
+		return nil
+	}
+
+	log.Printf("[INFO] Deploy %s from cache in offline mode", cacheKey)
+	tag := d.tagOf(cacheKey)
+	if err := d.deployContext(ctx, cacheKey, tag); err != nil {
+		return err
 	}
 
+	hookErr := d.afterDeploy(ctx, notice.Message{Tag: tag})
+	d.cleanupReleases()
+
+	return hookErr
+}
+
+// Deploy fetches and deploys the release of the configured tag once, bypassing the quarantine and the drain file,
+// and pins it not to be reverted by the loop. Run by the deploy command, the server of Dewy running on the host
+// is restarted by SIGHUP.
+func (d *Dewy) Deploy() error {
+	if d.config.Tag == "" {
+		return errors.New("tag is required to deploy")
+	}
+	d.force = true
+	if err := d.Run(); err != nil {
+		return err
+	}
+	key, err := d.state.Read(currentKey)
+	if err != nil {
+		return fmt.Errorf("no current version deployed: %w", err)
+	}
+	if err := d.pin(string(key)); err != nil {
+		return err
+	}
+	if d.config.Command != SERVER {
+		return d.restartRunningServer()
+	}
 	return nil
 }
 
+// Redeploy deploys the cached current version again into a fresh release directory,
+// then runs after deploy hooks and restarts the server. Run by the redeploy command,
+// the server of Dewy running on the host is restarted by SIGHUP.
+func (d *Dewy) Redeploy() error {
+	ctx := context.Background()
+	key, err := d.state.Read(currentKey)
+	if err != nil {
+		return fmt.Errorf("no current version to redeploy: %w", err)
+	}
+	cacheKey := string(key)
+	tag := d.tagOf(cacheKey)
+
+	log.Printf("[INFO] Redeploy %s", cacheKey)
+	unlock, err := d.lockDeploy()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if d.config.InstallCommand != "" {
+		if err := d.install(ctx, cacheKey, tag); err != nil {
+			return err
+		}
+		return d.afterDeploy(ctx, notice.Message{Tag: tag})
+	}
+	linkFrom, err := d.preserve(filepath.Join(d.cache.GetDir(), cacheKey))
+	if err != nil {
+		log.Printf("[ERROR] Preserve failure: %#v", err)
+		if isNoSpace(err) {
+			// the partial release is removed and the current symlink is kept
+			return fmt.Errorf("%w: %s", ErrNoSpace, err)
+		}
+		return err
+	}
+	log.Printf("[INFO] Extract archive to %s", linkFrom)
+
+	if err := d.link(cacheKey, tag, linkFrom); err != nil {
+		return err
+	}
+	// restart the server even if it is running the same version
+	d.runningKey = ""
+
+	hookErr := d.afterDeploy(ctx, notice.Message{Tag: tag})
+	d.cleanupReleases()
+	if d.config.Command != SERVER {
+		if err := d.restartRunningServer(); err != nil {
+			return errors.Join(hookErr, err)
+		}
+	}
+
+	return hookErr
+}
+
+// afterDeploy starts or restarts the server and runs after deploy hooks.
+func (d *Dewy) afterDeploy(ctx context.Context, m notice.Message) error {
+	if d.config.Command == SERVER {
+		if d.config.VerifyArch {
+			if err := d.verifyArch(); err != nil {
+				log.Printf("[ERROR] Architecture failure: %s", err)
+				if d.previousRelease != "" {
+					if rerr := d.rollback(); rerr != nil {
+						log.Printf("[ERROR] Rollback failure: %#v", rerr)
+					}
+				}
+				m.Error = err.Error()
+				d.notify(ctx, notice.EventServerStartFailure, m)
+				return fmt.Errorf("%w: %s", ErrServerStart, err)
+			}
+		}
+		var err error
+		if d.config.SkipRunningVersion && d.isServerRunning && d.runningKey == d.deployedKey {
+			log.Printf("[INFO] Server is already running %s, restart skipped", d.runningKey)
+		} else if d.isServerRunning {
+			d.notify(ctx, notice.EventServerRestart, m)
+			span := otlp.SpanFromContext(ctx).Start("restart")
+			err = d.restartServer()
+			span.End(err)
+			if err != nil {
+				log.Printf("[ERROR] Server restart failure: %#v", err)
+				m.Error = err.Error()
+				// restart the server with the previous release, as the server is down in the foreground mode
+				if rerr := d.rollbackServer(); rerr != nil {
+					log.Printf("[ERROR] Rollback failure: %#v", rerr)
+					m.Error = fmt.Sprintf("%s, and rollback failed: %s", err, rerr)
+				}
+				d.notify(ctx, notice.EventServerRestartFailure, m)
+				return fmt.Errorf("%w: %s", ErrServerStart, err)
+			}
+		} else {
+			d.notify(ctx, notice.EventServerStart, m)
+			span := otlp.SpanFromContext(ctx).Start("start")
+			err = d.startServer()
+			for i := 0; err != nil && i < d.config.StartRetries; i++ {
+				log.Printf("[WARN] Server start failure, retry %d/%d: %s", i+1, d.config.StartRetries, err)
+				time.Sleep(startRetryInterval)
+				err = d.startServer()
+			}
+			span.End(err)
+			if err != nil {
+				// There is no running server to roll back to, so the symlink is left in place.
+				log.Printf("[ERROR] Server start failure: %#v", err)
+				m.Error = err.Error()
+				d.notify(ctx, notice.EventServerStartFailure, m)
+				return fmt.Errorf("%w: %s", ErrServerStart, err)
+			}
+		}
+		if err := d.restartSidecars(); err != nil {
+			log.Printf("[ERROR] Sidecar failure: %#v", err)
+			event := notice.EventServerStartFailure
+			if d.previousRelease != "" {
+				// restart the whole group with the previous release
+				event = notice.EventServerRestartFailure
+				if rerr := d.rollback(); rerr != nil {
+					log.Printf("[ERROR] Rollback failure: %#v", rerr)
+				} else if rerr := d.restartServer(); rerr != nil {
+					log.Printf("[ERROR] Server restart failure: %#v", rerr)
+				} else if rerr := d.restartSidecars(); rerr != nil {
+					log.Printf("[ERROR] Sidecar failure: %#v", rerr)
+				}
+			}
+			m.Error = err.Error()
+			d.notify(ctx, event, m)
+			return fmt.Errorf("%w: %s", ErrServerStart, err)
+		}
+		if d.config.ReadyFile != "" {
+			span := otlp.SpanFromContext(ctx).Start("health-check")
+			err := d.waitReady()
+			span.End(err)
+			if err != nil {
+				log.Printf("[ERROR] Server readiness failure: %#v", err)
+				return err
+			}
+		}
+		if d.config.HealthCheckURL != "" {
+			span := otlp.SpanFromContext(ctx).Start("health-check")
+			err := d.checkHealthURL(ctx, m)
+			span.End(err)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := d.runAfterDeployHooks(m.Tag); err != nil {
+		return err
+	}
+	d.startPostDeployWatch(ctx, m)
+	return nil
+}
+
+// verifyArch checks the executable of the server is built for this architecture.
+func (d *Dewy) verifyArch() error {
+	if d.config.Starter == nil {
+		return nil
+	}
+	p, err := exec.LookPath(d.config.Starter.Command())
+	if err != nil {
+		return err
+	}
+	return checkArch(p)
+}
+
+// waitReady waits for the ready file to appear in the current release.
+func (d *Dewy) waitReady() error {
+	p := filepath.Join(d.currentPath(), d.config.ReadyFile)
+	timeout := d.config.ReadyTimeout
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		if kvs.IsFileExist(p) {
+			log.Printf("[INFO] Server is ready with %s", p)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("ready file is not found in %s: %s", timeout, p)
+		}
+		time.Sleep(readyPollInterval)
+	}
+}
+
+func (d *Dewy) cleanupReleases() {
+	if d.config.InstallCommand != "" {
+		// no releases are extracted
+		return
+	}
+	log.Printf("[INFO] Keep releases as %d", d.keepReleasesCount())
+	if err := d.keepReleases(); err != nil {
+		log.Printf("[ERROR] Keep releases failure: %#v", err)
+	}
+}
+
+func (d *Dewy) notify(ctx context.Context, event string, m notice.Message) {
+	if d.notice == nil {
+		return
+	}
+	message, err := notice.Render(d.config.NoticeTemplates, event, m)
+	if err != nil {
+		log.Printf("[ERROR] Notice template failure: %#v", err)
+		return
+	}
+	if ctx.Value(quietContextKey{}) != nil {
+		log.Printf("[INFO] Notice suppressed: %s", message)
+		return
+	}
+	if err := d.notice.Notify(ctx, message); err != nil {
+		log.Printf("[ERROR] Notice failure: %#v", err)
+	}
+}
+
+// shippedToOthers reports whether other hosts have already recorded shipping of the tag.
+func (d *Dewy) shippedToOthers(tag string) bool {
+	r, ok := d.registry.(shippedHoster)
+	if !ok {
+		return false
+	}
+	hosts, err := r.ShippedHosts(tag)
+	if err != nil {
+		log.Printf("[ERROR] Shipped hosts failure: %#v", err)
+		return false
+	}
+	hostname, _ := os.Hostname()
+	hostname = strings.ReplaceAll(strings.ToLower(hostname), " ", "_")
+	for _, h := range hosts {
+		if h != hostname {
+			return true
+		}
+	}
+	return false
+}
+
+// deploy deploys the cached artifact again with the recorded tag, such as to repair the current symlink.
 func (d *Dewy) deploy(key string) error {
+	return d.deployContext(context.Background(), key, d.tagOf(key))
+}
 
+// deployContext extracts the cached artifact of the tag and links it, or installs it by the install command,
+// traced by the span in the context.
+func (d *Dewy) deployContext(ctx context.Context, key, tag string) error {
+	if d.config.InstallCommand != "" {
+		return d.install(ctx, key, tag)
+	}
+	if err := d.stage(key); err != nil {
+		return err
+	}
 	p := filepath.Join(d.cache.GetDir(), key)
-	linkFrom, err := d.preserve(p)
+	var linkFrom string
+	var err error
+	span := otlp.SpanFromContext(ctx).Start("extract")
+	if d.config.ContentAddressedReleases {
+		linkFrom, err = d.preserveByContent(key, p)
+	} else {
+		linkFrom, err = d.preserve(p)
+	}
+	span.SetAttr("dewy.release", linkFrom)
+	span.End(err)
 	if err != nil {
 		log.Printf("[ERROR] Preserve failure: %#v", err)
+		if isNoSpace(err) {
+			// the partial release is removed and the current symlink is kept
+			return fmt.Errorf("%w: %s", ErrNoSpace, err)
+		}
 		return err
 	}
 	log.Printf("[INFO] Extract archive to %s", linkFrom)
 
-	linkTo := filepath.Join(d.root, symlinkDir)
+	if err := d.runBeforeDeployHooks(linkFrom, tag); err != nil {
+		if !d.config.ContentAddressedReleases {
+			// the release is never linked
+			os.RemoveAll(linkFrom)
+		}
+		return err
+	}
+
+	span = otlp.SpanFromContext(ctx).Start("swap")
+	err = d.link(key, tag, linkFrom)
+	span.End(err)
+	return err
+}
+
+// isNoSpace reports whether the error is caused by no space left on device.
+// Errors of extraction are not wrapped, so the message is also checked.
+func isNoSpace(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || strings.Contains(err.Error(), syscall.ENOSPC.Error())
+}
+
+// diskUsage returns the releases directory and its free space for notices.
+func (d *Dewy) diskUsage() string {
+	dir := d.releasesPath()
+	free, _, err := diskFree(dir)
+	if err != nil {
+		return dir
+	}
+	return fmt.Sprintf("%s (%d MB available)", dir, free/1024/1024)
+}
+
+// link switches the current symlink to the release with shared paths linked, and records it as the current version of the tag.
+func (d *Dewy) link(key, tag, linkFrom string) error {
+	if err := d.linkShared(linkFrom); err != nil {
+		log.Printf("[ERROR] Shared path failure: %#v", err)
+		return err
+	}
+
+	linkTo := d.currentPath()
 	if _, err := os.Lstat(linkTo); err == nil {
-		os.Remove(linkTo)
+		d.previousRelease, _ = d.readCurrent()
 	}
 
 	log.Printf("[INFO] Create symlink to %s from %s", linkTo, linkFrom)
-	if err := os.Symlink(linkFrom, linkTo); err != nil {
+	if err := d.linkCurrent(linkFrom); err != nil {
+		return err
+	}
+
+	if err := d.recordRelease(linkFrom, key); err != nil {
+		return err
+	}
+	if err := d.recordTag(key, tag); err != nil {
+		return err
+	}
+	if err := d.state.Write(currentKey, []byte(key)); err != nil {
+		return err
+	}
+	d.deployedKey = key
+
+	if len(d.config.RemoteHosts) > 0 {
+		// failures of remote hosts do not fail the local deploy, and are retried in later cycles
+		if err := d.deployRemotes(linkFrom, d.config.RemoteHosts); err != nil {
+			log.Printf("[ERROR] %s", err)
+		}
+	}
+
+	return nil
+}
+
+// releaseDiff returns the summary of changed files from the previous release, or empty if unknown.
+func (d *Dewy) releaseDiff() string {
+	if d.previousRelease == "" {
+		return ""
+	}
+	current, err := d.readCurrent()
+	if err != nil {
+		return ""
+	}
+	r, err := diffReleases(d.previousRelease, current)
+	if err != nil {
+		log.Printf("[ERROR] Diff failure: %#v", err)
+		return ""
+	}
+	return r.String()
+}
+
+// checkCurrentOnce repairs the current symlink on the first run.
+func (d *Dewy) checkCurrentOnce() {
+	d.Lock()
+	checked := d.currentChecked
+	d.currentChecked = true
+	d.Unlock()
+	if checked {
+		return
+	}
+	if err := d.repairCurrent(); err != nil {
+		log.Printf("[ERROR] Repair current failure: %#v", err)
+	}
+}
+
+// repairCurrent repairs the current symlink pointing to a removed or empty release,
+// such as the host was restarted while deploying. It deploys the cached current version again,
+// or links the most recent valid release.
+func (d *Dewy) repairCurrent() error {
+	linkTo := d.currentPath()
+	fi, err := os.Lstat(linkTo)
+	if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+	dst, err := d.readCurrent()
+	if err != nil {
+		return err
+	}
+	if isValidRelease(dst) {
+		return nil
+	}
+	log.Printf("[WARN] %s points to the invalid release %s", linkTo, dst)
+
+	if key, err := d.state.Read(currentKey); err == nil && d.stage(string(key)) == nil && kvs.IsFileExist(filepath.Join(d.cache.GetDir(), string(key))) {
+		if err := d.deploy(string(key)); err != nil {
+			return err
+		}
+		// never roll back to the invalid release
+		d.previousRelease = ""
+		log.Printf("[INFO] Repaired %s by deploying %s again", linkTo, key)
+		return nil
+	}
+
+	releases, err := d.listReleases()
+	if err != nil {
 		return err
 	}
+	var latest string
+	for _, r := range releases {
+		if r.path != filepath.Clean(dst) && isValidRelease(r.path) {
+			latest = r.path
+			break
+		}
+	}
+	if latest == "" {
+		return fmt.Errorf("no valid release to repair %s", linkTo)
+	}
+	if err := d.linkCurrent(latest); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Repaired %s by linking %s", linkTo, latest)
+	return nil
+}
+
+// isValidRelease reports whether the release directory exists and is not empty.
+func isValidRelease(p string) bool {
+	files, err := os.ReadDir(p)
+	return err == nil && len(files) > 0
+}
+
+// isDeployed reports whether the current symlink points to an existing release of the artifact of the cache key.
+func (d *Dewy) isDeployed(cacheKey string) bool {
+	if !d.hasCurrentRelease() {
+		return false
+	}
+	dst, _ := d.readCurrent()
+	return d.releaseKeyOf(dst) == cacheKey
+}
+
+// hasCurrentRelease reports whether the current symlink points to an existing release.
+func (d *Dewy) hasCurrentRelease() bool {
+	dst, err := d.readCurrent()
+	if err != nil {
+		return false
+	}
+	fi, err := os.Stat(dst)
+	if err != nil || !fi.IsDir() {
+		return false
+	}
+	return filepath.Dir(dst) == d.releasesPath()
+}
+
+// newVerifier returns the chain of built-in verifiers and configured verifiers, built once in New
+// so that a verifier failing to build fails New instead of being skipped.
+func newVerifier(c Config, fetch func(urlstr string, w io.Writer) error) (verify.Chain, error) {
+	var chain verify.Chain
+	if c.MaxArtifactSize > 0 {
+		chain = append(chain, &verify.Size{Max: c.MaxArtifactSize})
+	}
+	if c.ChecksumArtifact != "" {
+		chain = append(chain, &verify.Checksums{Name: c.ChecksumArtifact, Fetch: fetch})
+	}
+	if c.SignatureKey != "" {
+		s, err := verify.NewSignature(c.SignatureKey, fetch)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, s)
+	}
+	if c.Cosign != (verify.CosignConfig{}) {
+		s, err := verify.NewCosign(c.Cosign, fetch)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, s)
+	}
+	return append(chain, c.Verifiers...), nil
+}
 
+// stage stages the data of the key in the cache directory if the cache is remote.
+func (d *Dewy) stage(key string) error {
+	if s, ok := d.cache.(kvs.Stager); ok {
+		return s.Stage(key)
+	}
+	return nil
+}
+
+// readKey returns the armored or PEM-encoded key given inline, or read from the file of the path.
+func readKey(k string) (string, error) {
+	if strings.HasPrefix(strings.TrimSpace(k), "-----BEGIN") {
+		return k, nil
+	}
+	b, err := os.ReadFile(k)
+	if err != nil {
+		return "", fmt.Errorf("key: %w", err)
+	}
+	return string(b), nil
+}
+
+func (d *Dewy) extractor() *kvs.Extractor {
+	e := &kvs.Extractor{
+		MaxBytes: d.config.MaxExtractBytes,
+		MaxFiles: d.config.MaxExtractFiles,
+	}
+	if d.config.RecursiveExtract {
+		e.Depth = recursiveExtractDepth
+	}
+	if d.config.CheckInodes {
+		e.Check = checkInodes
+	}
+	if d.extractCheck != nil {
+		e.Check = d.extractCheck
+	}
+	return e
+}
+
+// checkInodes aborts the extraction if the entries of the archive exceed free inodes,
+// which can run out before free bytes on filesystems with fixed inode counts.
+func checkInodes(dst string, files int, _ int64) error {
+	_, inodes, err := diskFree(dst)
+	if err != nil {
+		log.Printf("[WARN] Skip inode check: %s", err)
+		return nil
+	}
+	if uint64(files) > inodes {
+		return fmt.Errorf("%d entries of the archive exceed %d free inodes on %s", files, inodes, dst)
+	}
 	return nil
 }
 
 func (d *Dewy) preserve(p string) (string, error) {
-	dst := filepath.Join(d.root, releasesDir, time.Now().UTC().Format(releaseDir))
-	if err := os.MkdirAll(dst, 0755); err != nil {
+	dst := filepath.Join(d.releasesPath(), time.Now().UTC().Format(releaseDir))
+	if err := d.extract(p, dst); err != nil {
+		os.RemoveAll(dst)
+		return "", err
+	}
+
+	return dst, nil
+}
+
+// preserveByContent extracts the artifact into the directory named by its content hash,
+// and reuses the directory if it already exists.
+func (d *Dewy) preserveByContent(key, p string) (string, error) {
+	data, err := d.cache.Read(key)
+	if err != nil {
 		return "", err
 	}
+	sum := sha256.Sum256(data)
+	dst := filepath.Join(d.releasesPath(), hex.EncodeToString(sum[:])[:contentHashLength])
+
+	if fi, err := os.Stat(dst); err == nil && fi.IsDir() {
+		log.Printf("[INFO] Reuse %s for the same content", dst)
+		// keep the reused release from pruning
+		now := time.Now()
+		if err := os.Chtimes(dst, now, now); err != nil {
+			return "", err
+		}
+		return dst, nil
+	}
 
-	if err := kvs.ExtractArchive(p, dst); err != nil {
+	// extract to a temporary directory not to reuse a partially extracted one
+	tmp := dst + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return "", err
+	}
+	if err := d.extract(p, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
 		return "", err
 	}
 
 	return dst, nil
 }
 
+func (d *Dewy) extract(p, dst string) error {
+	mode := d.config.DirMode
+	if mode == 0 {
+		mode = kvs.DefaultDirMode
+	}
+	if err := os.MkdirAll(dst, mode); err != nil {
+		return err
+	}
+	// MkdirAll is affected by umask
+	if err := os.Chmod(dst, mode); err != nil {
+		return err
+	}
+
+	return d.extractions.do(func() error {
+		if d.config.ExtractNice == 0 && !d.config.ExtractIdleIO {
+			return d.extractor().Extract(p, dst)
+		}
+		return runLowPriority(d.config.ExtractNice, d.config.ExtractIdleIO, func() error {
+			return d.extractor().Extract(p, dst)
+		})
+	})
+}
+
 func (d *Dewy) restartServer() error {
 	d.Lock()
 	defer d.Unlock()
 
+	if d.fg != nil {
+		log.Print("[INFO] Restart server in foreground")
+		if err := d.fg.restart(serverStartWait); err != nil {
+			return err
+		}
+		d.runningKey = d.deployedKey
+		return nil
+	}
+
 	p, _ := os.FindProcess(os.Getpid())
 	err := p.Signal(syscall.SIGHUP)
 	if err != nil {
 		return err
 	}
-	log.Print("[INFO] Send SIGHUP for server restart")
+	if d.config.RestartSignal != "" {
+		log.Printf("[INFO] Send SIGHUP for server restart, the old server receives %s", strings.ToUpper(d.config.RestartSignal))
+	} else {
+		log.Print("[INFO] Send SIGHUP for server restart")
+	}
+	d.runningKey = d.deployedKey
 
 	return nil
 }
@@ -266,66 +1491,228 @@ func (d *Dewy) startServer() error {
 	d.Lock()
 	defer d.Unlock()
 
-	d.isServerRunning = true
-
 	log.Print("[INFO] Start server")
-	ch := make(chan error)
-
-	go func() {
-		s, err := starter.NewStarter(d.config.Starter)
+	// without releases by the install command, and in the chroot by default, the server runs in the working directory of Dewy
+	if sc, ok := d.config.Starter.(*StarterConfig); ok && (d.config.WorkDir != "" || d.config.InstallCommand == "" && d.config.Chroot == "") {
+		// the symlink is resolved whenever the server is started, so restarted servers run in the new release
+		dir, err := d.workDir(d.currentPath())
 		if err != nil {
-			log.Printf("[ERROR] Starter failure: %#v", err)
-			return
+			return err
 		}
+		sc.dir = dir
+	}
+	if d.fg != nil {
+		if err := d.fg.start(serverStartWait); err != nil {
+			return err
+		}
+		d.isServerRunning = true
+		d.runningKey = d.deployedKey
+		return nil
+	}
+	s, err := starter.NewStarter(d.config.Starter)
+	if err != nil {
+		return err
+	}
+	ch := make(chan error, 1)
 
+	go func() {
 		ch <- s.Run()
 	}()
 
+	select {
+	case err := <-ch:
+		if err == nil {
+			err = errors.New("server exited")
+		}
+		return err
+	case <-time.After(serverStartWait):
+	}
+
+	d.starter = s
+	d.starterDone = ch
+	d.isServerRunning = true
+	d.runningKey = d.deployedKey
+
+	return nil
+}
+
+// stopStarter stops server-starter and waits for the server to exit, such as to execute the updated Dewy,
+// which starts the server again. server-starter stops by SIGTERM to this process, received after waitSigs.
+func (d *Dewy) stopStarter() {
+	if d.starter == nil {
+		return
+	}
+	log.Print("[INFO] Stop server")
+	d.starter.Stop()
+	select {
+	case <-d.starterDone:
+	case <-time.After(foregroundStopTimeout):
+		log.Printf("[WARN] Server did not exit in %s", foregroundStopTimeout)
+	}
+	if err := d.starter.Teardown(); err != nil {
+		log.Printf("[ERROR] Server teardown failure: %#v", err)
+	}
+	d.starter = nil
+	d.isServerRunning = false
+}
+
+// rollback links the current symlink to the previous release.
+func (d *Dewy) rollback() error {
+	if d.previousRelease == "" {
+		return errors.New("no previous release to roll back")
+	}
+	if fi, err := os.Stat(d.previousRelease); err != nil || !fi.IsDir() {
+		return fmt.Errorf("previous release %s is removed", d.previousRelease)
+	}
+	linkTo := d.currentPath()
+	log.Printf("[INFO] Roll back symlink to %s from %s", linkTo, d.previousRelease)
+	return d.linkCurrent(d.previousRelease)
+}
+
+// swapSymlink replaces the symlink atomically by renaming a new symlink created next to it,
+// so only the parent directory of the symlink needs to be writable.
+func swapSymlink(oldname, newname string) error {
+	tmp := newname + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Symlink(oldname, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, newname); err != nil {
+		os.Remove(tmp)
+		return err
+	}
 	return nil
 }
 
+// releasesPath returns the directory containing releases.
+func (d *Dewy) releasesPath() string {
+	root := d.config.ReleasesRoot
+	if root == "" {
+		root = d.root
+	} else if d.config.Chroot != "" {
+		root = filepath.Join(d.config.Chroot, root)
+	}
+	return filepath.Join(root, releasesDir)
+}
+
+// keepReleasesCount returns the number of releases to keep.
+func (d *Dewy) keepReleasesCount() int {
+	if d.config.KeepReleases > 0 {
+		return d.config.KeepReleases
+	}
+	return keepReleases
+}
+
+// keepReleases removes releases except the newest ones, the current release, which may be older after rolling back,
+// and the previous release to roll back to. The cache keys of the removed releases are forgotten.
 func (d *Dewy) keepReleases() error {
-	dir := filepath.Join(d.root, releasesDir)
-	files, err := os.ReadDir(dir)
+	releases, err := d.listReleases()
 	if err != nil {
 		return err
 	}
+	current, _ := d.readCurrent()
 
-	sort.Slice(files, func(i, j int) bool {
-		fi, err := files[i].Info()
-		if err != nil {
-			return false
+	var removed []string
+	for i, r := range releases {
+		if i < d.keepReleasesCount() {
+			continue
 		}
-		fj, err := files[j].Info()
-		if err != nil {
-			return true
+		if (current != "" && r.path == filepath.Clean(current)) || (d.previousRelease != "" && r.path == filepath.Clean(d.previousRelease)) {
+			continue
 		}
-		return fi.ModTime().Unix() > fj.ModTime().Unix()
-	})
-
-	for i, f := range files {
-		if i < keepReleases {
+		if d.config.MinRetention > 0 && time.Since(r.modTime) < d.config.MinRetention {
 			continue
 		}
-		if err := os.RemoveAll(filepath.Join(dir, f.Name())); err != nil {
+		if err := os.RemoveAll(r.path); err != nil {
 			return err
 		}
+		removed = append(removed, r.path)
+	}
+	if len(removed) == 0 {
+		return nil
 	}
+	return d.forgetReleases(removed)
+}
 
-	return nil
+// storageOptions returns the options of storages given with the registry, such as the region of the s3 registry.
+func storageOptions(c Config) storage.Options {
+	o := storage.Options{GCSCredentialsFile: c.GCSCredentials}
+	if sc, err := s3reg.ParseURL(c.Registry); err == nil {
+		o.S3Region = sc.Region
+	}
+	return o
+}
+
+// fetch fetches the artifact of the URL with the storage options.
+func (d *Dewy) fetch(urlstr string, w io.Writer) error {
+	return d.storageOpts.Fetch(urlstr, w)
 }
 
-func newRegistry(urlstr string, preRelease bool, artifactName string) (registry.Registry, error) {
-	su := strings.SplitN(urlstr, "://", 2)
+func newRegistry(c Config) (registry.Registry, error) {
+	su := strings.SplitN(c.Registry, "://", 2)
+	if len(su) != 2 {
+		return nil, fmt.Errorf("invalid registry: %s", c.Registry)
+	}
 	switch su[0] {
 	case ghrelease.Scheme:
 		ownerrepo := strings.SplitN(su[1], "/", 2)
-		c := ghrelease.Config{
-			Owner:      ownerrepo[0],
-			Repo:       ownerrepo[1],
-			PreRelease: preRelease,
+		if len(ownerrepo) != 2 {
+			return nil, fmt.Errorf("invalid registry: %s", c.Registry)
+		}
+		return ghrelease.New(ghrelease.Config{
+			Owner:                ownerrepo[0],
+			Repo:                 ownerrepo[1],
+			PreRelease:           c.PreRelease,
+			Environment:          c.DeploymentEnvironment,
+			MinReleaseAge:        c.MinReleaseAge,
+			SourceArchive:        c.UseSourceArchive,
+			RequireChecksGreen:   c.RequireChecksGreen,
+			Tag:                  c.Tag,
+			VersionRegex:         c.VersionRegex,
+			VersionSourceURL:     c.VersionSourceURL,
+			VersionSourceHeaders: c.VersionSourceHeaders,
+			MatchLabel:           c.MatchLabel,
+			RequireSignedTag:     c.RequireSignedTag,
+			AllowedSigners:       c.AllowedSigners,
+			SemverConstraint:     c.SemverConstraint,
+			ArtifactAliases:      c.ArtifactAliases,
+		})
+	case ghactions.Scheme:
+		ownerrepo := strings.SplitN(su[1], "/", 2)
+		if len(ownerrepo) != 2 {
+			return nil, fmt.Errorf("invalid registry: %s", c.Registry)
+		}
+		return ghactions.New(ghactions.Config{
+			Owner:    ownerrepo[0],
+			Repo:     ownerrepo[1],
+			Branch:   c.Branch,
+			Workflow: c.Workflow,
+		})
+	case glrelease.Scheme:
+		return glrelease.New(glrelease.Config{Project: strings.Trim(su[1], "/")})
+	case httpreg.Scheme, httpreg.SchemeSecure:
+		if c.Manifest {
+			if c.VersionURL != "" {
+				return nil, fmt.Errorf("manifest and version url cannot be used together")
+			}
+			return httpreg.New(httpreg.Config{VersionURL: c.Registry})
+		}
+		return httpreg.New(httpreg.Config{URL: c.Registry, VersionURL: c.VersionURL})
+	case s3reg.Scheme:
+		sc, err := s3reg.ParseURL(c.Registry)
+		if err != nil {
+			return nil, err
+		}
+		return s3reg.New(sc)
+	case gcsreg.Scheme, gcsreg.SchemeShort:
+		gc, err := gcsreg.ParseURL(c.Registry)
+		if err != nil {
+			return nil, err
 		}
-		return ghrelease.New(c)
+		gc.CredentialsFile = c.GCSCredentials
+		return gcsreg.New(gc)
 	}
-	return nil, fmt.Errorf("unsupported registry: %s", urlstr)
+	return nil, fmt.Errorf("unsupported registry: %s", c.Registry)
 }
@@ -1,18 +1,34 @@
 package dewy
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/linyows/dewy/kvs"
-	ghrelease "github.com/linyows/dewy/registry/github_release"
+	"github.com/linyows/dewy/notice"
+	httpreg "github.com/linyows/dewy/registry/http"
+	"github.com/linyows/dewy/storage"
+	"github.com/linyows/dewy/verify"
+	"github.com/mholt/archiver/v3"
 )
 
 func TestNew(t *testing.T) {
-	regiurl := "github_release://linyows/dewy"
+	regiurl := "https://example.com/app.tar.gz"
 	c := DefaultConfig()
 	c.Registry = regiurl
 	dewy, err := New(c)
@@ -20,7 +36,7 @@ func TestNew(t *testing.T) {
 		t.Fatal(err)
 	}
 	wd, _ := os.Getwd()
-	r, err := newRegistry(regiurl, false, "")
+	r, err := newRegistry(c)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -35,15 +51,16 @@ func TestNew(t *testing.T) {
 		},
 		registry:        r,
 		cache:           dewy.cache,
+		state:           dewy.state,
 		isServerRunning: false,
 		root:            wd,
 	}
 
 	opts := []cmp.Option{
-		cmp.AllowUnexported(Dewy{}, ghrelease.GithubRelease{}, kvs.File{}),
+		cmp.AllowUnexported(Dewy{}, httpreg.HTTP{}, kvs.File{}),
 		cmpopts.IgnoreFields(Dewy{}, "notice"),
-		cmpopts.IgnoreFields(Dewy{}, "RWMutex"),
-		cmpopts.IgnoreFields(ghrelease.GithubRelease{}, "cl"),
+		cmpopts.IgnoreFields(Dewy{}, "RWMutex", "deploying"),
+		cmpopts.IgnoreFields(httpreg.HTTP{}, "cl"),
 		cmpopts.IgnoreFields(kvs.File{}, "mutex"),
 	}
 	if diff := cmp.Diff(dewy, expect, opts...); diff != "" {
@@ -85,3 +102,1183 @@ func TestRun(t *testing.T) {
 		t.Errorf("releases directory is not found: %v", err)
 	}
 }
+
+func TestKeepReleases(t *testing.T) {
+	tests := []struct {
+		name         string
+		ageMinutes   []int
+		minRetention time.Duration
+		keep         int
+		current      int
+		previous     int
+		want         int
+	}{
+		{"by count", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 0, 0, -1, -1, keepReleases},
+		{"rapid deploys", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, time.Hour, 0, -1, -1, 10},
+		{"old releases", []int{1, 2, 3, 4, 5, 6, 7, 480, 540, 600}, time.Hour, 0, -1, -1, keepReleases},
+		{"old and young releases", []int{1, 2, 3, 4, 5, 6, 7, 8, 540, 600}, time.Hour, 0, -1, -1, 8},
+		{"configured count", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 0, 3, -1, -1, 3},
+		{"current rolled back", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 0, 3, 9, -1, 4},
+		{"previous release", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 0, 1, 0, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			kv := &kvs.File{}
+			kv.Default()
+			if err := kv.SetDir(t.TempDir()); err != nil {
+				t.Fatal(err)
+			}
+			d := &Dewy{root: root, state: kv, config: Config{MinRetention: tt.minRetention, KeepReleases: tt.keep}}
+			now := time.Now()
+			for i, m := range tt.ageMinutes {
+				p := filepath.Join(root, releasesDir, fmt.Sprintf("release%d", i))
+				if err := os.MkdirAll(p, 0755); err != nil {
+					t.Fatal(err)
+				}
+				mt := now.Add(-time.Duration(m) * time.Minute)
+				if err := os.Chtimes(p, mt, mt); err != nil {
+					t.Fatal(err)
+				}
+				if err := d.recordRelease(p, fmt.Sprintf("v%d-app.tar.gz", i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.previous >= 0 {
+				d.previousRelease = filepath.Join(root, releasesDir, fmt.Sprintf("release%d", tt.previous))
+			}
+			current := ""
+			if tt.current >= 0 {
+				current = filepath.Join(root, releasesDir, fmt.Sprintf("release%d", tt.current))
+				if err := d.linkCurrent(current); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := d.keepReleases(); err != nil {
+				t.Fatal(err)
+			}
+			if current != "" && !kvs.IsFileExist(current) {
+				t.Error("current release should be kept")
+			}
+			if d.previousRelease != "" && !kvs.IsFileExist(d.previousRelease) {
+				t.Error("previous release should be kept")
+			}
+			files, err := os.ReadDir(filepath.Join(root, releasesDir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != tt.want {
+				t.Errorf("got %d releases, want %d", len(files), tt.want)
+			}
+			if keys := d.releaseKeys(); len(keys) != tt.want {
+				t.Errorf("got %d keys of releases, want %d", len(keys), tt.want)
+			}
+		})
+	}
+}
+
+func TestSwapSymlink(t *testing.T) {
+	root := t.TempDir()
+	link := filepath.Join(root, symlinkDir)
+	for _, name := range []string{"release1", "release2"} {
+		dst := filepath.Join(root, releasesDir, name)
+		if err := os.MkdirAll(dst, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := swapSymlink(dst, link); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.Readlink(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != dst {
+			t.Errorf("got %s, want %s", got, dst)
+		}
+	}
+	if kvs.IsFileExist(link + ".tmp") {
+		t.Error("temporary symlink should be renamed")
+	}
+}
+
+// artifact returns an archive containing the files.
+func artifact(t *testing.T, name string, files map[string]string) []byte {
+	t.Helper()
+	dir := t.TempDir()
+	var srcs []string
+	for n, content := range files {
+		p := filepath.Join(dir, n)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, p)
+	}
+	a := filepath.Join(t.TempDir(), name)
+	if err := archiver.Archive(srcs, a); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDeployContentAddressed(t *testing.T) {
+	root := t.TempDir()
+	kv := &kvs.File{}
+	kv.Default()
+	if err := kv.SetDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	v1 := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	v2 := artifact(t, "app.tar.gz", map[string]string{"app": "v2"})
+	cached := []struct {
+		key  string
+		data []byte
+	}{
+		{"v1.0.0-app.tar.gz", v1},
+		{"v1.0.1-app.tar.gz", v1},
+		{"v1.1.0-app.tar.gz", v2},
+	}
+
+	d := &Dewy{root: root, cache: kv, state: kv, config: Config{ContentAddressedReleases: true}}
+	var dirs []string
+	for _, c := range cached {
+		if err := kv.Write(c.key, c.data); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.deploy(c.key); err != nil {
+			t.Fatal(err)
+		}
+		dst, err := os.Readlink(filepath.Join(root, symlinkDir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(filepath.Base(dst)) != contentHashLength {
+			t.Errorf("unexpected release directory: %s", dst)
+		}
+		dirs = append(dirs, dst)
+	}
+	files, err := os.ReadDir(filepath.Join(root, releasesDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("got %d releases, want 2", len(files))
+	}
+	if dirs[0] != dirs[1] {
+		t.Error("the same content should be deployed to the same directory")
+	}
+	if dirs[0] == dirs[2] {
+		t.Error("different contents should be deployed to different directories")
+	}
+}
+
+type unreachableNotice struct {
+	calls int
+}
+
+func (n *unreachableNotice) String() string {
+	return "unreachable"
+}
+
+func (n *unreachableNotice) Notify(ctx context.Context, message string) error {
+	n.calls++
+	return errors.New("notifier is unreachable")
+}
+
+func TestRunWithUnreachableNotice(t *testing.T) {
+	data := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Registry = ts.URL + "/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = t.TempDir()
+	n := &unreachableNotice{}
+	d.notice = n
+
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if n.calls == 0 {
+		t.Error("notice is not called")
+	}
+	if !kvs.IsFileExist(filepath.Join(d.root, symlinkDir, "app")) {
+		t.Error("artifact is not deployed")
+	}
+}
+
+func TestRedeploy(t *testing.T) {
+	root := t.TempDir()
+	kv := &kvs.File{}
+	kv.Default()
+	if err := kv.SetDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	d := &Dewy{root: root, cache: kv, state: kv, config: Config{Command: ASSETS}}
+	if err := d.Redeploy(); err == nil {
+		t.Error("expected error without the current version")
+	}
+
+	if err := kv.Write("v1.0.0-app.tar.gz", artifact(t, "app.tar.gz", map[string]string{"app": "v1"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.deploy("v1.0.0-app.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.Readlink(filepath.Join(root, symlinkDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// break the release
+	if err := os.Remove(filepath.Join(before, "app")); err != nil {
+		t.Fatal(err)
+	}
+	// release directories are named by seconds
+	time.Sleep(time.Second)
+
+	if err := d.Redeploy(); err != nil {
+		t.Fatal(err)
+	}
+	after, err := os.Readlink(filepath.Join(root, symlinkDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before == after {
+		t.Error("redeploy should extract into a fresh release directory")
+	}
+	if !kvs.IsFileExist(filepath.Join(after, "app")) {
+		t.Error("release is not recovered")
+	}
+
+	// this process plays Dewy running the server
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	defer d.writePid()()
+	time.Sleep(time.Second)
+	if err := d.Redeploy(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-hup:
+	case <-time.After(5 * time.Second):
+		t.Error("the server of running Dewy is not restarted")
+	}
+}
+
+func TestRunDrain(t *testing.T) {
+	data := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	drain := filepath.Join(t.TempDir(), "dewy.drain")
+	if err := os.WriteFile(drain, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Registry = ts.URL + "/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	c.DrainFile = drain
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = t.TempDir()
+	n := &unreachableNotice{}
+	d.notice = n
+
+	for i := 0; i < 2; i++ {
+		if err := d.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if kvs.IsFileExist(filepath.Join(d.root, symlinkDir)) {
+		t.Error("artifact should not be deployed while draining")
+	}
+	if n.calls != 1 {
+		t.Errorf("pending release should be notified once, got %d", n.calls)
+	}
+
+	if err := os.Remove(drain); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !kvs.IsFileExist(filepath.Join(d.root, symlinkDir, "app")) {
+		t.Error("artifact is not deployed after draining")
+	}
+}
+
+func TestDeployForced(t *testing.T) {
+	data := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	drain := filepath.Join(t.TempDir(), "dewy.drain")
+	if err := os.WriteFile(drain, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Registry = ts.URL + "/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	c.DrainFile = drain
+	c.MaxConsecutiveFailures = 1
+	c.Tag = "v1"
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = t.TempDir()
+	d.notice = &unreachableNotice{}
+
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if d.pendingKey == "" {
+		t.Fatal("release should be pending while draining")
+	}
+	if err := d.state.Write(quarantineKey, []byte(d.pendingKey)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if kvs.IsFileExist(filepath.Join(d.root, symlinkDir)) {
+		t.Fatal("quarantined release should not be deployed while draining")
+	}
+
+	if err := d.Deploy(); err != nil {
+		t.Fatal(err)
+	}
+	if !kvs.IsFileExist(filepath.Join(d.root, symlinkDir, "app")) {
+		t.Error("forced deploy should bypass the quarantine and the drain file")
+	}
+}
+
+func TestDeployPin(t *testing.T) {
+	etag := "v1"
+	data := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", fmt.Sprintf("%q", etag))
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Registry = ts.URL + "/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	root := t.TempDir()
+	c.Tag = "v1"
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = root
+	d.notice = &unreachableNotice{}
+
+	// this process plays Dewy running the server
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	defer d.writePid()()
+	if err := d.Deploy(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-hup:
+	case <-time.After(5 * time.Second):
+		t.Error("the server of running Dewy is not restarted")
+	}
+	deployed, err := d.state.Read(currentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned, err := d.state.Read(pinKey); err != nil || string(pinned) != string(deployed) {
+		t.Errorf("got pin %q, want %q", pinned, deployed)
+	}
+
+	// the loop of the running Dewy finds the latest version
+	c.Tag = ""
+	loop, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loop.root = root
+	loop.notice = &unreachableNotice{}
+	etag = "v2"
+	if err := loop.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := loop.state.Read(currentKey); string(got) != string(deployed) {
+		t.Errorf("the pinned release is reverted to %s", got)
+	}
+	if s := loop.Status(); s.Quarantined == "" || s.Quarantined == string(deployed) {
+		t.Errorf("got quarantined %q, want the latest version", s.Quarantined)
+	}
+	if _, err := loop.state.Read(pinKey); err == nil {
+		t.Error("the pin should be handed to the quarantine")
+	}
+
+	// release directories are named by seconds
+	time.Sleep(time.Second)
+	etag = "v3"
+	if err := loop.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := loop.state.Read(currentKey); string(got) == string(deployed) {
+		t.Error("a different version should be deployed after the pin")
+	}
+}
+
+func TestRunCooldown(t *testing.T) {
+	etag := "v1"
+	data := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", fmt.Sprintf("%q", etag))
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Registry = ts.URL + "/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	c.DeployCooldown = time.Hour
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = t.TempDir()
+	d.notice = &unreachableNotice{}
+	app := filepath.Join(d.root, symlinkDir, "app")
+
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	etag = "v2"
+	data = artifact(t, "app.tar.gz", map[string]string{"app": "v2"})
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(app); string(b) != "v1" {
+		t.Errorf("deploy within the cooldown should be deferred, got %q", b)
+	}
+
+	// release directories are named by seconds
+	time.Sleep(time.Second)
+	d.lastDeployedAt = time.Now().Add(-c.DeployCooldown)
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(app); string(b) != "v2" {
+		t.Errorf("deferred release is not deployed after the cooldown, got %q", b)
+	}
+}
+
+func TestDeploySymlinkName(t *testing.T) {
+	root := t.TempDir()
+	kv := &kvs.File{}
+	kv.Default()
+	if err := kv.SetDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Write("v1.0.0-app.tar.gz", artifact(t, "app.tar.gz", map[string]string{"app": "v1"})); err != nil {
+		t.Fatal(err)
+	}
+	d := &Dewy{root: root, cache: kv, state: kv, config: Config{Command: ASSETS, SymlinkName: "live"}}
+	if err := d.deploy("v1.0.0-app.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if !kvs.IsFileExist(filepath.Join(root, "live", "app")) {
+		t.Error("release is not linked by the symlink name")
+	}
+	if kvs.IsFileExist(filepath.Join(root, symlinkDir)) {
+		t.Error("default symlink should not be created")
+	}
+
+	for _, n := range []string{"a/b", "..", "releases"} {
+		if _, err := New(Config{Command: ASSETS, SymlinkName: n}); err == nil {
+			t.Errorf("symlink name %q should be invalid", n)
+		}
+	}
+}
+
+func TestDeployChroot(t *testing.T) {
+	chroot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(chroot, "srv"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sc := &StarterConfig{command: "/srv/current/app"}
+	d, err := New(Config{Command: SERVER, Chroot: chroot, Root: "/srv", Starter: sc, Cache: CacheConfig{Dir: t.TempDir()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.Geteuid() == 0 {
+		if sc.args[0] != chroot {
+			t.Errorf("server should be started by chroot: %s %v", sc.command, sc.args)
+		}
+	} else if sc.command != filepath.Join(chroot, "srv", "current", "app") {
+		t.Errorf("got %s, want the command in the chroot", sc.command)
+	}
+
+	if err := d.cache.Write("v1.0.0-app.tar.gz", artifact(t, "app.tar.gz", map[string]string{"app": "v1"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.deploy("v1.0.0-app.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	link, err := os.Readlink(filepath.Join(chroot, "srv", symlinkDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.IsAbs(link) {
+		t.Errorf("symlink should be relative in the chroot: %s", link)
+	}
+	if !kvs.IsFileExist(filepath.Join(chroot, "srv", symlinkDir, "app")) {
+		t.Error("release is not extracted in the chroot")
+	}
+	if !d.isDeployed("v1.0.0-app.tar.gz") {
+		t.Error("release should be deployed")
+	}
+
+	if _, err := New(Config{Command: ASSETS, Chroot: filepath.Join(chroot, "none")}); err == nil {
+		t.Error("missing chroot should be invalid")
+	}
+}
+
+func TestRepairCurrent(t *testing.T) {
+	newDewy := func(t *testing.T) *Dewy {
+		kv := &kvs.File{}
+		kv.Default()
+		if err := kv.SetDir(t.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+		return &Dewy{root: t.TempDir(), cache: kv, state: kv, config: Config{Command: ASSETS}}
+	}
+
+	t.Run("deploy cached current again", func(t *testing.T) {
+		d := newDewy(t)
+		if err := d.cache.Write("v1.0.0-app.tar.gz", artifact(t, "app.tar.gz", map[string]string{"app": "v1"})); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.deploy("v1.0.0-app.tar.gz"); err != nil {
+			t.Fatal(err)
+		}
+		broken, _ := os.Readlink(d.currentPath())
+		if err := os.RemoveAll(broken); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := d.repairCurrent(); err != nil {
+			t.Fatal(err)
+		}
+		if !kvs.IsFileExist(filepath.Join(d.currentPath(), "app")) {
+			t.Error("current is not repaired")
+		}
+	})
+
+	t.Run("link most recent valid release", func(t *testing.T) {
+		d := newDewy(t)
+		releases := filepath.Join(d.root, releasesDir)
+		old := filepath.Join(releases, "20240101T000000Z")
+		recent := filepath.Join(releases, "20240102T000000Z")
+		empty := filepath.Join(releases, "20240103T000000Z")
+		for _, p := range []string{old, recent} {
+			if err := os.MkdirAll(p, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(p, "app"), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.MkdirAll(empty, 0755); err != nil {
+			t.Fatal(err)
+		}
+		past := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(old, past, past); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join(releases, "20240104T000000Z"), d.currentPath()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := d.repairCurrent(); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.Readlink(d.currentPath())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != recent {
+			t.Errorf("got %s, want %s", got, recent)
+		}
+	})
+}
+
+func TestRunQuarantine(t *testing.T) {
+	etag := "v1"
+	data := []byte("broken archive")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", fmt.Sprintf("%q", etag))
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Registry = ts.URL + "/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	c.MaxConsecutiveFailures = 2
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = t.TempDir()
+	n := &unreachableNotice{}
+	d.notice = n
+
+	for i := 0; i < 2; i++ {
+		if err := d.Run(); err == nil {
+			t.Fatalf("run %d: broken artifact should fail", i)
+		}
+	}
+	if got := d.Status().Quarantined; got != "v1-app.tar.gz" {
+		t.Errorf("quarantined: got %q", got)
+	}
+	if err := d.Run(); err != nil {
+		t.Errorf("quarantined artifact should be skipped: %s", err)
+	}
+
+	etag = "v2"
+	data = artifact(t, "app.tar.gz", map[string]string{"app": "v2"})
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !kvs.IsFileExist(filepath.Join(d.root, symlinkDir, "app")) {
+		t.Error("new artifact is not deployed")
+	}
+}
+
+func TestRunVerify(t *testing.T) {
+	data := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	checksums := "0000000000000000000000000000000000000000000000000000000000000000  app.tar.gz\n"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.URL.Path == "/checksums.txt" {
+			fmt.Fprint(w, checksums)
+			return
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Registry = ts.URL + "/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	c.SignatureKey = "-----BEGIN PGP PUBLIC KEY BLOCK-----\ninvalid\n-----END PGP PUBLIC KEY BLOCK-----"
+	if _, err := New(c); err == nil {
+		t.Fatal("invalid signature key should fail New, not be skipped")
+	}
+
+	c.SignatureKey = ""
+	c.ChecksumArtifact = "checksums.txt"
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = t.TempDir()
+	d.notice = &unreachableNotice{}
+	if err := d.Run(); !errors.Is(err, verify.ErrVerification) {
+		t.Fatalf("got %v, want %v", err, verify.ErrVerification)
+	}
+	if kvs.IsFileExist(filepath.Join(c.Cache.Dir, "v1-app.tar.gz")) {
+		t.Error("unverified artifact should not be cached")
+	}
+}
+
+func TestRunSharedCache(t *testing.T) {
+	data := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+	mr := miniredis.RunT(t)
+
+	// hosts share the remote cache but not the local cache directory
+	for i := 0; i < 2; i++ {
+		c := DefaultConfig()
+		c.Command = ASSETS
+		c.Registry = ts.URL + "/app.tar.gz"
+		c.Cache.Dir = t.TempDir()
+		c.Cache.URL = "redis://" + mr.Addr() + "/0"
+		d, err := New(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.root = t.TempDir()
+		d.notice = &unreachableNotice{}
+		if err := d.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if !kvs.IsFileExist(filepath.Join(d.root, symlinkDir, "app")) {
+			t.Errorf("host %d: artifact is not deployed", i)
+		}
+		if mr.Exists(kvs.DefaultRedisPrefix + currentKey) {
+			t.Errorf("host %d: current key should not be shared", i)
+		}
+	}
+}
+
+func TestStorageOptions(t *testing.T) {
+	tests := []struct {
+		config Config
+		want   storage.Options
+	}{
+		{Config{Registry: "s3://bucket/myapp?region=ap-northeast-1"}, storage.Options{S3Region: "ap-northeast-1"}},
+		{Config{Registry: "gs://bucket/myapp", GCSCredentials: "/etc/sa.json"}, storage.Options{GCSCredentialsFile: "/etc/sa.json"}},
+		{Config{Registry: "https://example.com/app.tar.gz"}, storage.Options{}},
+	}
+	for _, tt := range tests {
+		if got := storageOptions(tt.config); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.config.Registry, got, tt.want)
+		}
+	}
+}
+
+func TestDeployRequiresTag(t *testing.T) {
+	d := &Dewy{config: Config{Command: ASSETS}}
+	if err := d.Deploy(); err == nil {
+		t.Error("deploy without tag should be error")
+	}
+}
+
+func TestCheckInodes(t *testing.T) {
+	dir := t.TempDir()
+	if _, inodes, err := diskFree(dir); err != nil || inodes == 0 {
+		t.Skip("free inodes are not available")
+	}
+	if err := checkInodes(dir, 1, 0); err != nil {
+		t.Error(err)
+	}
+	if err := checkInodes(dir, math.MaxInt, 0); err == nil {
+		t.Error("expected error for entries exceeding free inodes")
+	}
+}
+
+func TestRunNoSpace(t *testing.T) {
+	data := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Registry = ts.URL + "/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	c.MaxConsecutiveFailures = 1
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = t.TempDir()
+	n := &recordNotice{}
+	d.notice = n
+	// the disk fills up while extracting
+	d.extractCheck = func(dst string, files int, bytes int64) error {
+		return &os.PathError{Op: "write", Path: filepath.Join(dst, "app"), Err: syscall.ENOSPC}
+	}
+
+	if err := d.Run(); !errors.Is(err, ErrNoSpace) {
+		t.Fatalf("got %v, want %v", err, ErrNoSpace)
+	}
+	if len(n.messages) == 0 || !strings.Contains(n.messages[len(n.messages)-1], "Disk is full") {
+		t.Errorf("disk-full should be notified: %v", n.messages)
+	}
+	if files, _ := os.ReadDir(filepath.Join(d.root, releasesDir)); len(files) != 0 {
+		t.Errorf("partial release should be removed, got %d releases", len(files))
+	}
+	if _, err := os.Lstat(filepath.Join(d.root, symlinkDir)); !os.IsNotExist(err) {
+		t.Error("symlink should not be created")
+	}
+	if q := d.Status().Quarantined; q != "" {
+		t.Errorf("disk full should not quarantine the release, got %s", q)
+	}
+}
+
+func TestIsNoSpace(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{syscall.ENOSPC, true},
+		{&os.PathError{Op: "write", Path: "app", Err: syscall.ENOSPC}, true},
+		{fmt.Errorf("app: writing file: %v", syscall.ENOSPC), true},
+		{errors.New("unexpected EOF"), false},
+	}
+	for _, tt := range tests {
+		if got := isNoSpace(tt.err); got != tt.want {
+			t.Errorf("isNoSpace(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRollback(t *testing.T) {
+	root := t.TempDir()
+	kv := &kvs.File{}
+	kv.Default()
+	if err := kv.SetDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	d := &Dewy{root: root, cache: kv, state: kv, config: Config{Command: ASSETS}}
+	if err := d.Rollback(); err == nil {
+		t.Error("expected error without the current release")
+	}
+
+	releases := filepath.Join(root, releasesDir)
+	names := []string{"20240101T000000Z", "20240102T000000Z", "20240103T000000Z"}
+	for i, n := range names {
+		p := filepath.Join(releases, n)
+		if err := os.MkdirAll(p, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(p, "app"), []byte(n), 0600); err != nil {
+			t.Fatal(err)
+		}
+		mt := time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC)
+		if err := os.Chtimes(p, mt, mt); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.recordRelease(p, fmt.Sprintf("v%d-app.tar.gz", i+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.linkCurrent(filepath.Join(releases, names[0])); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Rollback(); err == nil {
+		t.Error("expected error on the oldest release")
+	}
+
+	if err := d.linkCurrent(filepath.Join(releases, names[2])); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Write(currentKey, []byte("v3-app.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.readCurrent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(releases, names[1]); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if s := d.Status(); s.Deployed != "v2-app.tar.gz" || s.Quarantined != "v3-app.tar.gz" {
+		t.Errorf("got deployed %q and quarantined %q, want v2-app.tar.gz and v3-app.tar.gz", s.Deployed, s.Quarantined)
+	}
+	if d.isQuarantined("v4-app.tar.gz") {
+		t.Error("a newer version should not be quarantined")
+	}
+
+	// rolling back again goes further back, and the newest version is kept quarantined
+	// while this process plays Dewy running the server
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	defer d.writePid()()
+	if err := d.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := d.readCurrent(); got != filepath.Join(releases, names[0]) {
+		t.Errorf("got %s, want %s", got, filepath.Join(releases, names[0]))
+	}
+	if s := d.Status(); s.Deployed != "v1-app.tar.gz" || s.Quarantined != "v3-app.tar.gz" {
+		t.Errorf("got deployed %q and quarantined %q, want v1-app.tar.gz and v3-app.tar.gz", s.Deployed, s.Quarantined)
+	}
+	select {
+	case <-hup:
+	case <-time.After(5 * time.Second):
+		t.Error("the server of running Dewy is not restarted")
+	}
+}
+
+func TestDeployHooks(t *testing.T) {
+	root := t.TempDir()
+	kv := &kvs.File{}
+	kv.Default()
+	if err := kv.SetDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	key := "v1.0.0-app.tar.gz"
+	if err := kv.Write(key, artifact(t, "app.tar.gz", map[string]string{"app": "v1"})); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(t.TempDir(), "marker")
+	hook := `echo "$DEWY_RELEASE_TAG $DEWY_RELEASE_DIR" >> ` + marker
+	ctx := context.Background()
+
+	d := &Dewy{root: root, cache: kv, state: kv, config: Config{Command: ASSETS, BeforeDeploy: []string{"exit 1"}}}
+	if err := d.deployContext(ctx, key, "v1.0.0"); err == nil {
+		t.Fatal("failing before deploy hook should abort the deploy")
+	}
+	if _, err := os.Lstat(filepath.Join(root, symlinkDir)); !os.IsNotExist(err) {
+		t.Error("symlink should not be created")
+	}
+	if files, _ := os.ReadDir(filepath.Join(root, releasesDir)); len(files) != 0 {
+		t.Errorf("aborted release should be removed, got %d releases", len(files))
+	}
+
+	d.config.BeforeDeploy = []string{hook}
+	d.config.AfterDeploy = []string{hook}
+	if err := d.deployContext(ctx, key, "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.runAfterDeployHooks("v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	release, err := d.readCurrent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Repeat("v1.0.0 "+release+"\n", 2)
+	if string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+
+	// the tag of the current version is given to hooks of redeploys
+	// release directories are named by seconds
+	time.Sleep(time.Second)
+	if err := d.Redeploy(); err != nil {
+		t.Fatal(err)
+	}
+	release, err = d.readCurrent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = os.ReadFile(marker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want += "v1.0.0 " + release + "\n"
+	if string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+}
+
+func TestRunOffline(t *testing.T) {
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Offline = true
+	c.Cache.Dir = t.TempDir()
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = t.TempDir()
+	if err := d.Run(); err == nil {
+		t.Fatal("offline mode without the cached current version should be error")
+	}
+
+	key := "v1.0.0-app.tar.gz"
+	if err := d.state.Write(currentKey, []byte(key)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Run(); err == nil {
+		t.Fatal("offline mode without the cached artifact should be error")
+	}
+
+	if err := d.cache.Write(key, artifact(t, "app.tar.gz", map[string]string{"app": "v1"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !kvs.IsFileExist(filepath.Join(d.root, symlinkDir, "app")) {
+		t.Error("cached artifact is not deployed")
+	}
+	release, _ := d.readCurrent()
+	// release directories are named by seconds
+	time.Sleep(time.Second)
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := d.readCurrent(); got != release {
+		t.Errorf("deployed version should not be deployed again, got %s", got)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	root := t.TempDir()
+	release := filepath.Join(root, releasesDir, "20240101T000000Z")
+	if err := os.MkdirAll(release, 0755); err != nil {
+		t.Fatal(err)
+	}
+	d := &Dewy{root: root, config: Config{Command: SERVER, ReadyFile: "tmp/ready", ReadyTimeout: 2 * time.Second}}
+	if err := d.linkCurrent(release); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.waitReady(); err == nil {
+		t.Fatal("expected error without the ready file")
+	}
+
+	go func() {
+		time.Sleep(readyPollInterval)
+		if err := os.MkdirAll(filepath.Join(release, "tmp"), 0755); err != nil {
+			t.Error(err)
+		}
+		if err := os.WriteFile(filepath.Join(release, "tmp", "ready"), nil, 0644); err != nil {
+			t.Error(err)
+		}
+	}()
+	if err := d.waitReady(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIsDeployed(t *testing.T) {
+	root := t.TempDir()
+	kv := &kvs.File{}
+	kv.Default()
+	if err := kv.SetDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	d := &Dewy{root: root, cache: kv, state: kv, config: Config{Command: ASSETS}}
+	if d.isDeployed("v1.0.0-app.tar.gz") {
+		t.Error("nothing should be deployed")
+	}
+
+	if err := kv.Write("v1.0.0-app.tar.gz", artifact(t, "app.tar.gz", map[string]string{"app": "v1"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.deploy("v1.0.0-app.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if !d.isDeployed("v1.0.0-app.tar.gz") {
+		t.Error("v1.0.0 should be deployed")
+	}
+	if d.isDeployed("v1.0.1-app.tar.gz") {
+		t.Error("other versions should not be deployed")
+	}
+
+	// the symlink is switched to a release of an unknown version, such as by rolling back by hand
+	other := filepath.Join(d.releasesPath(), "20240101T000000Z")
+	if err := os.MkdirAll(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.linkCurrent(other); err != nil {
+		t.Fatal(err)
+	}
+	if d.isDeployed("v1.0.0-app.tar.gz") {
+		t.Error("v1.0.0 should not be deployed after the symlink is switched")
+	}
+
+	if err := os.RemoveAll(other); err != nil {
+		t.Fatal(err)
+	}
+	if d.hasCurrentRelease() {
+		t.Error("removed release should not be the current release")
+	}
+}
+
+func TestAfterDeployRestartFailure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not found")
+	}
+	root := t.TempDir()
+	kv := &kvs.File{}
+	kv.Default()
+	if err := kv.SetDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Write("v1-app.tar.gz", artifact(t, "app.tar.gz", map[string]string{"app": "v1"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Write("v2-app.tar.gz", artifact(t, "app.tar.gz", map[string]string{"app": "v2", "broken": ""})); err != nil {
+		t.Fatal(err)
+	}
+	// the server of the broken release exits immediately, running in the release by default
+	sc := &StarterConfig{command: "sh", args: []string{"-c", "if [ -f broken ]; then echo 'panic: broken' >&2; exit 2; fi; exec sleep 30"}}
+	n := &recordNotice{}
+	d := &Dewy{root: root, cache: kv, state: kv, notice: n, fg: newForeground(sc, nil), config: Config{Command: SERVER, Starter: sc}}
+	defer d.fg.stop(syscall.SIGKILL, time.Second)
+
+	if err := d.deploy("v1-app.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	v1, _ := d.readCurrent()
+	if err := d.afterDeploy(context.Background(), notice.Message{Tag: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	// release directories are named by seconds
+	time.Sleep(time.Second)
+	if err := d.deploy("v2-app.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	err := d.afterDeploy(context.Background(), notice.Message{Tag: "v2"})
+	if !errors.Is(err, ErrServerStart) {
+		t.Fatalf("got %v, want %v", err, ErrServerStart)
+	}
+	if got, _ := d.readCurrent(); got != v1 {
+		t.Errorf("got %s, want rolled back to %s", got, v1)
+	}
+	d.fg.mu.Lock()
+	running := d.fg.running
+	d.fg.mu.Unlock()
+	if !running {
+		t.Error("server should be restarted with the previous release")
+	}
+	last := n.messages[len(n.messages)-1]
+	if !strings.Contains(last, "Server failed to restart with v2") || !strings.Contains(last, "panic: broken") {
+		t.Errorf("restart failure should be notified with stderr of the server: %s", last)
+	}
+}
@@ -0,0 +1,135 @@
+package dewy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxDiffPaths is the maximum number of paths shown in the diff summary.
+const maxDiffPaths = 5
+
+// releaseDiff is the changed files between releases.
+type releaseDiff struct {
+	Added    []string
+	Modified []string
+	Removed  []string
+}
+
+// String returns the concise summary of the diff, such as "+1 ~2 -0 (a.txt, b.txt, c.txt)".
+func (r *releaseDiff) String() string {
+	s := fmt.Sprintf("+%d ~%d -%d", len(r.Added), len(r.Modified), len(r.Removed))
+	var paths []string
+	for _, p := range r.Added {
+		paths = append(paths, "+"+p)
+	}
+	for _, p := range r.Modified {
+		paths = append(paths, "~"+p)
+	}
+	for _, p := range r.Removed {
+		paths = append(paths, "-"+p)
+	}
+	if len(paths) == 0 {
+		return s
+	}
+	if len(paths) > maxDiffPaths {
+		paths = append(paths[:maxDiffPaths], fmt.Sprintf("and %d more", len(paths)-maxDiffPaths))
+	}
+	return fmt.Sprintf("%s (%s)", s, strings.Join(paths, ", "))
+}
+
+// diffReleases compares the files of release directories by size and content.
+func diffReleases(prev, next string) (*releaseDiff, error) {
+	before, err := listFiles(prev)
+	if err != nil {
+		return nil, err
+	}
+	after, err := listFiles(next)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &releaseDiff{}
+	for p, fi := range after {
+		old, ok := before[p]
+		if !ok {
+			r.Added = append(r.Added, p)
+			continue
+		}
+		same, err := sameFile(filepath.Join(prev, p), old, filepath.Join(next, p), fi)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			r.Modified = append(r.Modified, p)
+		}
+	}
+	for p := range before {
+		if _, ok := after[p]; !ok {
+			r.Removed = append(r.Removed, p)
+		}
+	}
+	sort.Strings(r.Added)
+	sort.Strings(r.Modified)
+	sort.Strings(r.Removed)
+
+	return r, nil
+}
+
+// listFiles returns regular files in the directory by the relative path.
+func listFiles(dir string) (map[string]fs.FileInfo, error) {
+	files := map[string]fs.FileInfo{}
+	err := filepath.WalkDir(dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !e.Type().IsRegular() || (e.Name() == deployLogName && filepath.Dir(p) == dir) {
+			return nil
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = fi
+		return nil
+	})
+	return files, err
+}
+
+func sameFile(a string, afi fs.FileInfo, b string, bfi fs.FileInfo) (bool, error) {
+	if afi.Size() != bfi.Size() {
+		return false, nil
+	}
+	ah, err := fileHash(a)
+	if err != nil {
+		return false, err
+	}
+	bh, err := fileHash(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ah, bh), nil
+}
+
+func fileHash(p string) ([]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,55 @@
+package dewy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for n, content := range files {
+		p := filepath.Join(dir, n)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDiffReleases(t *testing.T) {
+	prev := writeFiles(t, map[string]string{
+		"index.html":     "v1",
+		"css/app.css":    "body {}",
+		"img/logo.png":   "logo",
+		"old/legacy.txt": "legacy",
+	})
+	next := writeFiles(t, map[string]string{
+		"index.html":   "v2",
+		"css/app.css":  "body {}",
+		"img/logo.png": "LOGO",
+		"js/app.js":    "app",
+	})
+
+	got, err := diffReleases(prev, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "+1 ~2 -1 (+js/app.js, ~img/logo.png, ~index.html, -old/legacy.txt)"; got.String() != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReleaseDiffString(t *testing.T) {
+	r := &releaseDiff{Added: []string{"a", "b", "c", "d", "e", "f", "g"}}
+	if got, want := r.String(), "+7 ~0 -0 (+a, +b, +c, +d, +e, and 2 more)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := (&releaseDiff{}).String(), "+0 ~0 -0"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package dewy
+
+import "syscall"
+
+// diskFree returns available bytes and inodes of the filesystem containing the path.
+func diskFree(p string) (bytes uint64, inodes uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(p, &st); err != nil {
+		return 0, 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), st.Ffree, nil //nolint:unconvert
+}
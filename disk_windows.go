@@ -0,0 +1,10 @@
+//go:build windows
+
+package dewy
+
+import "errors"
+
+// diskFree is not supported on windows.
+func diskFree(p string) (bytes uint64, inodes uint64, err error) {
+	return 0, 0, errors.New("disk free is not supported on windows")
+}
@@ -0,0 +1,120 @@
+package dewy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/linyows/dewy/notice"
+	"github.com/linyows/dewy/registry"
+	ghrelease "github.com/linyows/dewy/registry/github_release"
+)
+
+// minDiskFree is the free space regarded as enough to deploy.
+const minDiskFree = 512 * 1024 * 1024
+
+// Check is the result of a doctor check.
+type Check struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Critical bool   `json:"critical"`
+	Message  string `json:"message"`
+}
+
+// notifyTestTimeout is the timeout to send the test notice to each notifier.
+const notifyTestTimeout = 30 * time.Second
+
+// NotifyTest sends a test notice to each configured notifier.
+func (d *Dewy) NotifyTest() []Check {
+	n, err := d.newNotice()
+	if err != nil {
+		return []Check{{Name: "notice", Critical: true, Message: err.Error()}}
+	}
+	notices := []notice.Notice{n}
+	if m, ok := n.(notice.Multi); ok {
+		notices = m
+	}
+
+	host, _ := os.Hostname()
+	msg := fmt.Sprintf("This is a test notice from Dewy on %s", host)
+	var checks []Check
+	for _, n := range notices {
+		ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), notice.MetaContextKey, true), notifyTestTimeout)
+		err := n.Notify(ctx, msg)
+		cancel()
+		c := Check{Name: n.String(), OK: err == nil, Critical: true, Message: "test notice is sent"}
+		if err != nil {
+			c.Message = err.Error()
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// Doctor checks the configuration and environment end-to-end.
+func (d *Dewy) Doctor() []Check {
+	ctx := context.Background()
+	var checks []Check
+
+	add := func(name string, critical bool, msg string, err error) {
+		c := Check{Name: name, OK: err == nil, Critical: critical, Message: msg}
+		if err != nil {
+			c.Message = err.Error()
+		}
+		checks = append(checks, c)
+	}
+
+	if repo, ok := d.registry.(*ghrelease.GithubRelease); ok {
+		scopes, err := repo.Ping(ctx)
+		if scopes == "" {
+			scopes = "none"
+		}
+		add("repository", true, fmt.Sprintf("%s is reachable (token scopes: %s)", repo.URL(), scopes), err)
+		add("api", true, "release and asset API are supported", repo.Probe(ctx))
+	}
+
+	if d.registry != nil {
+		var res *registry.CurrentResponse
+		artifact, err := expandArtifact(d.config.ArtifactName)
+		if err == nil {
+			res, err = d.registry.Current(&registry.CurrentRequest{
+				Arch:         runtime.GOARCH,
+				OS:           runtime.GOOS,
+				ArtifactName: artifact,
+				DryRun:       true,
+			})
+		}
+		msg := ""
+		if err == nil {
+			msg = fmt.Sprintf("%s is found in %s", res.ArtifactURL, res.Tag)
+		}
+		add("artifact", true, msg, err)
+	}
+
+	add("cache", true, fmt.Sprintf("%s is writable", d.cache.GetDir()), d.checkCache())
+
+	s := &notice.Slack{}
+	add("notice", false, "slack credentials are valid", s.Check(ctx))
+
+	dir := d.config.ReleasesRoot
+	if dir == "" {
+		dir = d.root
+	}
+	free, _, err := diskFree(dir)
+	if err == nil && free < minDiskFree {
+		err = fmt.Errorf("only %d MB available on %s", free/1024/1024, dir)
+	}
+	add("disk", true, fmt.Sprintf("%d MB available on %s", free/1024/1024, dir), err)
+
+	return checks
+}
+
+func (d *Dewy) checkCache() error {
+	key := fmt.Sprintf("doctor-%d", os.Getpid())
+	if err := d.cache.Write(key, []byte("ok")); err != nil {
+		return err
+	}
+	return d.cache.Delete(key)
+}
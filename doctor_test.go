@@ -0,0 +1,74 @@
+package dewy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNotifyTest(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	ng := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ng.Close()
+
+	d := &Dewy{config: Config{Notifiers: []string{ok.URL, ng.URL}}}
+	checks := d.NotifyTest()
+	if len(checks) != 2 {
+		t.Fatalf("got %d checks, want 2", len(checks))
+	}
+	if !checks[0].OK || checks[1].OK {
+		t.Errorf("unexpected results: %+v", checks)
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	t.Setenv("SLACK_TOKEN", "")
+	found := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+	}))
+	defer ts.Close()
+
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Registry = ts.URL + "/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	c.Notifiers = []string{"none"}
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = t.TempDir()
+
+	results := func() map[string]Check {
+		m := map[string]Check{}
+		for _, c := range d.Doctor() {
+			m[c.Name] = c
+		}
+		return m
+	}
+	got := results()
+	for _, name := range []string{"artifact", "cache", "notice", "disk"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("%s is not checked", name)
+		}
+	}
+	if !got["artifact"].OK || !got["cache"].OK {
+		t.Errorf("artifact and cache should pass: %+v", got)
+	}
+	if got["notice"].OK || got["notice"].Critical {
+		t.Errorf("notice without credentials should be a non-critical failure: %+v", got["notice"])
+	}
+
+	found = false
+	if got := results()["artifact"]; got.OK || !got.Critical {
+		t.Errorf("missing artifact should be a critical failure: %+v", got)
+	}
+}
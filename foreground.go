@@ -0,0 +1,243 @@
+package dewy
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	starter "github.com/lestrrat-go/server-starter"
+)
+
+const (
+	// foregroundStopTimeout is the time to wait for the server to exit before killing it.
+	foregroundStopTimeout = 30 * time.Second
+	// stderrTailSize is the size of the tail of stderr of the server attached to errors of exiting.
+	stderrTailSize = 2048
+	// stderrWaitDelay is the time to wait for stderr to be closed after the server exits,
+	// as processes spawned by the server may keep it open.
+	stderrWaitDelay = time.Second
+)
+
+// foreground runs the server as a direct child of Dewy without server-starter,
+// such as Dewy is the entrypoint of a container.
+type foreground struct {
+	config starter.Config
+	reaper *reaper
+	// exited receives the exit code when the server exits without being stopped.
+	exited chan int
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	done     chan struct{}
+	code     int
+	running  bool
+	stopping bool
+}
+
+func newForeground(c starter.Config, r *reaper) *foreground {
+	return &foreground{config: c, reaper: r, exited: make(chan int, 1)}
+}
+
+// start starts the server, and returns error if it exits within the wait.
+func (f *foreground) start(wait time.Duration) error {
+	cmd := exec.Command(f.config.Command(), f.config.Args()...)
+	cmd.Dir = f.config.Dir()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	stderr := &tailBuffer{size: stderrTailSize}
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+	cmd.WaitDelay = stderrWaitDelay
+
+	var ch <-chan syscall.WaitStatus
+	var err error
+	if f.reaper != nil {
+		ch, err = f.reaper.start(cmd)
+	} else {
+		err = cmd.Start()
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Server started as PID %d", cmd.Process.Pid)
+
+	done := make(chan struct{})
+	f.mu.Lock()
+	f.cmd, f.done = cmd, done
+	f.mu.Unlock()
+
+	go func() {
+		code := waitCommand(cmd, ch)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.code = code
+		unexpected := f.running && !f.stopping
+		f.running = false
+		close(done)
+		if unexpected {
+			log.Printf("[ERROR] Server exited with %d: %s", code, stderr)
+			select {
+			case f.exited <- code:
+			default:
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(wait):
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	select {
+	case <-done:
+		if s := stderr.String(); s != "" {
+			return fmt.Errorf("server exited with %d: %s", f.code, s)
+		}
+		return fmt.Errorf("server exited with %d", f.code)
+	default:
+	}
+	f.running = true
+	return nil
+}
+
+// stop sends the signal to the server, kills it after the timeout, and returns the exit code.
+func (f *foreground) stop(sig os.Signal, timeout time.Duration) int {
+	f.mu.Lock()
+	cmd, done := f.cmd, f.done
+	if cmd == nil {
+		f.mu.Unlock()
+		return 0
+	}
+	f.stopping = true
+	f.mu.Unlock()
+
+	if sig == nil {
+		sig = syscall.SIGTERM
+	}
+	log.Printf("[INFO] Send %s to server PID %d", sig, cmd.Process.Pid)
+	if err := cmd.Process.Signal(sig); err != nil {
+		log.Printf("[DEBUG] Signal failure: %s", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Printf("[WARN] Kill server PID %d not exiting in %s", cmd.Process.Pid, timeout)
+		_ = cmd.Process.Kill()
+		<-done
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopping = false
+	f.cmd = nil
+	return f.code
+}
+
+// restart stops the server by the signal on HUP, then starts it again.
+// Unlike server-starter, the listening port is not inherited, so the server is down while restarting.
+func (f *foreground) restart(wait time.Duration) error {
+	f.stop(f.config.SignalOnHUP(), foregroundStopTimeout)
+	return f.start(wait)
+}
+
+// tailBuffer keeps the tail of the written data up to the size.
+type tailBuffer struct {
+	mu   sync.Mutex
+	size int
+	buf  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.size; over > 0 {
+		b.buf = b.buf[over:]
+	}
+	return len(p), nil
+}
+
+// String returns the tail with surrounding spaces trimmed.
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(string(b.buf))
+}
+
+// waitCommand waits for the command by the reaper if any, and returns the exit code.
+func waitCommand(cmd *exec.Cmd, reaped <-chan syscall.WaitStatus) int {
+	if reaped != nil {
+		ws := <-reaped
+		// the process is already reaped, so this only releases resources of the command
+		_ = cmd.Wait()
+		return exitCode(ws)
+	}
+	_ = cmd.Wait()
+	ws, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok {
+		return cmd.ProcessState.ExitCode()
+	}
+	return exitCode(ws)
+}
+
+// exitCode returns the exit code like shells, 128+n for the process killed by the signal n.
+func exitCode(ws syscall.WaitStatus) int {
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return ws.ExitStatus()
+}
+
+// runCommand runs the command, waiting for it by the reaper if Dewy is the init process.
+func (d *Dewy) runCommand(cmd *exec.Cmd) error {
+	if d.reaper == nil {
+		return cmd.Run()
+	}
+	ch, err := d.reaper.start(cmd)
+	if err != nil {
+		return err
+	}
+	if code := waitCommand(cmd, ch); code != 0 {
+		return fmt.Errorf("exit status %d", code)
+	}
+	return nil
+}
+
+// waitForeground forwards the signal to the server and waits for it to exit,
+// or waits for the server to exit by itself, or stops it to execute the updated Dewy. It returns the received signal, or nil.
+// SIGHUP restarts the server like server-starter, such as by redeploy and rollback commands.
+func (d *Dewy) waitForeground() os.Signal {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	defer d.quitSelfUpdate()
+	for {
+		var sig os.Signal
+		select {
+		case sig = <-sigCh:
+			log.Printf("[DEBUG] PID %d received signal as %s", os.Getpid(), sig)
+			if sig == syscall.SIGHUP {
+				if err := d.restartServer(); err != nil {
+					log.Printf("[ERROR] Server restart failure: %#v", err)
+				}
+				continue
+			}
+			d.job.Quit <- true
+			d.exitCode = d.fg.stop(sig, foregroundStopTimeout)
+		case d.exitCode = <-d.fg.exited:
+			d.job.Quit <- true
+		case d.reexecTag = <-d.selfUpdated:
+			d.job.Quit <- true
+			d.exitCode = d.fg.stop(syscall.SIGTERM, foregroundStopTimeout)
+		}
+		return sig
+	}
+}
@@ -0,0 +1,119 @@
+package dewy
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestForeground(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not found")
+	}
+	sc := func(script string) *StarterConfig {
+		return &StarterConfig{command: "sh", args: []string{"-c", script}}
+	}
+
+	t.Run("stop forwards the signal", func(t *testing.T) {
+		f := newForeground(sc("trap 'exit 3' TERM; while :; do sleep 0.1; done"), nil)
+		if err := f.start(200 * time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		if got := f.stop(syscall.SIGTERM, 5*time.Second); got != 3 {
+			t.Errorf("got exit code %d, want 3", got)
+		}
+	})
+
+	t.Run("stop kills after timeout", func(t *testing.T) {
+		f := newForeground(sc("trap '' TERM; while :; do sleep 0.1; done"), nil)
+		if err := f.start(200 * time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := f.stop(syscall.SIGTERM, 200*time.Millisecond), 128+int(syscall.SIGKILL); got != want {
+			t.Errorf("got exit code %d, want %d", got, want)
+		}
+	})
+
+	t.Run("exit while starting", func(t *testing.T) {
+		f := newForeground(sc("echo 'listen: address already in use' >&2; exit 1"), nil)
+		err := f.start(time.Second)
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if want := "server exited with 1: listen: address already in use"; err.Error() != want {
+			t.Errorf("got %q, want %q", err, want)
+		}
+	})
+
+	t.Run("exit after started", func(t *testing.T) {
+		f := newForeground(sc("sleep 0.3; exit 7"), nil)
+		if err := f.start(100 * time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got := <-f.exited:
+			if got != 7 {
+				t.Errorf("got exit code %d, want 7", got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("exit is not notified")
+		}
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		f := newForeground(sc("while :; do sleep 0.1; done"), nil)
+		if err := f.start(100 * time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		old := f.cmd.Process.Pid
+		if err := f.restart(100 * time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		if f.cmd.Process.Pid == old {
+			t.Error("server is not restarted")
+		}
+		select {
+		case code := <-f.exited:
+			t.Errorf("stopped server should not be notified as exited: %d", code)
+		default:
+		}
+		f.stop(syscall.SIGTERM, 5*time.Second)
+	})
+
+	t.Run("restart sends the restart signal", func(t *testing.T) {
+		marker := filepath.Join(t.TempDir(), "marker")
+		conf := Config{RestartSignal: "USR2"}
+		c := newStarterConfig(conf, "0", []string{"sh", "-c", "trap 'echo USR2 > " + marker + "; exit 0' USR2; while :; do sleep 0.1; done"})
+		d := &Dewy{config: conf, fg: newForeground(c, nil)}
+		if err := d.fg.start(200 * time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		defer d.fg.stop(syscall.SIGTERM, 5*time.Second)
+		if err := d.restartServer(); err != nil {
+			t.Fatal(err)
+		}
+		if b, err := os.ReadFile(marker); err != nil || string(b) != "USR2\n" {
+			t.Errorf("old server should receive SIGUSR2: %q, %v", b, err)
+		}
+	})
+}
+
+func TestNewRestartSignal(t *testing.T) {
+	for _, s := range []string{"", "HUP", "usr2", "SIGTERM"} {
+		c := DefaultConfig()
+		c.Registry = "https://example.com/app.tar.gz"
+		c.RestartSignal = s
+		if _, err := New(c); err != nil {
+			t.Errorf("%q: %v", s, err)
+		}
+	}
+	c := DefaultConfig()
+	c.Registry = "https://example.com/app.tar.gz"
+	c.RestartSignal = "RELOAD"
+	if _, err := New(c); err == nil {
+		t.Error("unknown restart signal should be an error")
+	}
+}
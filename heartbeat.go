@@ -0,0 +1,42 @@
+package dewy
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const heartbeatTimeout = 10 * time.Second
+
+// heartbeat pings the dead man's switch after a successful cycle, at most once per HeartbeatInterval,
+// so that an external monitor alerts when Dewy stops polling.
+func (d *Dewy) heartbeat() {
+	if d.config.HeartbeatURL == "" {
+		return
+	}
+	d.Lock()
+	if !d.lastHeartbeat.IsZero() && time.Since(d.lastHeartbeat) < d.config.HeartbeatInterval {
+		d.Unlock()
+		return
+	}
+	d.lastHeartbeat = time.Now()
+	d.Unlock()
+
+	if err := ping(d.config.HeartbeatURL); err != nil {
+		log.Printf("[ERROR] Heartbeat failure: %#v", err)
+	}
+}
+
+func ping(u string) error {
+	cl := &http.Client{Timeout: heartbeatTimeout}
+	res, err := cl.Get(u)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat %s: %s", u, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package dewy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     int32
+	}{
+		{"every cycle", 0, 3},
+		{"throttled", time.Hour, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&got, 1)
+			}))
+			defer ts.Close()
+
+			d := &Dewy{config: Config{HeartbeatURL: ts.URL, HeartbeatInterval: tt.interval}}
+			for i := 0; i < 3; i++ {
+				d.heartbeat()
+			}
+			if got != tt.want {
+				t.Errorf("got %d pings, want %d", got, tt.want)
+			}
+		})
+	}
+}
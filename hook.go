@@ -0,0 +1,74 @@
+package dewy
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// runBeforeDeployHooks runs before deploy commands in order in the extracted release before the symlink swap.
+// The deploy is aborted if one fails.
+func (d *Dewy) runBeforeDeployHooks(release, tag string) error {
+	if len(d.config.BeforeDeploy) == 0 {
+		return nil
+	}
+	return d.runHooks("Before deploy", d.config.BeforeDeploy, false, release, tag)
+}
+
+// runAfterDeployHooks runs after deploy commands in order.
+func (d *Dewy) runAfterDeployHooks(tag string) error {
+	if len(d.config.AfterDeploy) == 0 {
+		return nil
+	}
+	release, _ := d.readCurrent()
+	return d.runHooks("After deploy", d.config.AfterDeploy, d.config.AfterDeployContinueOnError, release, tag)
+}
+
+// runHooks runs commands in the working directory of the release,
+// with DEWY_RELEASE_DIR and DEWY_RELEASE_TAG in the environment.
+func (d *Dewy) runHooks(name string, commands []string, continueOnError bool, release, tag string) error {
+	dir, err := d.workDir(release)
+	if err != nil {
+		return err
+	}
+	env := append(os.Environ(), "DEWY_RELEASE_DIR="+release, "DEWY_RELEASE_TAG="+tag)
+
+	var errs []string
+	for i, c := range commands {
+		log.Printf("[INFO] Execute %s hook[%d]: %s", strings.ToLower(name), i, c)
+		cmd := shellCommand(c)
+		cmd.Dir = dir
+		cmd.Env = env
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		err := d.runCommand(cmd)
+		if s := strings.TrimSpace(out.String()); s != "" {
+			log.Printf("[INFO] %s hook[%d] output: %s", name, i, s)
+		}
+		if err == nil {
+			continue
+		}
+		log.Printf("[ERROR] %s hook[%d] failure: %s", name, i, err)
+		if !continueOnError {
+			return fmt.Errorf("%s hook[%d] failed: %w", strings.ToLower(name), i, err)
+		}
+		errs = append(errs, fmt.Sprintf("[%d] %s", i, err))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s hooks failed: %s", strings.ToLower(name), strings.Join(errs, ", "))
+	}
+
+	return nil
+}
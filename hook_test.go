@@ -0,0 +1,43 @@
+package dewy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunAfterDeployHooks(t *testing.T) {
+	tests := []struct {
+		name            string
+		commands        []string
+		continueOnError bool
+		want            string
+		wantErr         string
+	}{
+		{"in order", []string{"echo 1", "echo 2", "echo 3"}, false, "1\n2\n3\n", ""},
+		{"stop on the first failure", []string{"echo 1", "exit 1", "echo 3"}, false, "1\n", "after deploy hook[1] failed"},
+		{"continue on error", []string{"exit 1", "echo 2", "exit 2"}, true, "2\n", "after deploy hooks failed: [0] exit status 1, [2] exit status 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			var commands []string
+			for _, c := range tt.commands {
+				commands = append(commands, c+" >> "+out)
+			}
+			d := &Dewy{root: t.TempDir(), config: Config{AfterDeploy: commands, AfterDeployContinueOnError: tt.continueOnError}}
+			err := d.runAfterDeployHooks("v1.0.0")
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("got %v, want %q", err, tt.wantErr)
+			}
+			b, _ := os.ReadFile(out)
+			if string(b) != tt.want {
+				t.Errorf("got %q, want %q", b, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+// Package httputil provides helpers of HTTP shared by notifiers and registries.
+package httputil
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ParseHeaders parses headers such as "Authorization: Bearer ${TOKEN}", expanding environment variables in values.
+func ParseHeaders(hh []string) (http.Header, error) {
+	h := http.Header{}
+	for _, v := range hh {
+		name, value, ok := strings.Cut(v, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header: %s", v)
+		}
+		h.Add(strings.TrimSpace(name), os.ExpandEnv(strings.TrimSpace(value)))
+	}
+	return h, nil
+}
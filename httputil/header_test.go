@@ -0,0 +1,24 @@
+package httputil
+
+import (
+	"testing"
+)
+
+func TestParseHeaders(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "secret")
+	h, err := ParseHeaders([]string{"Authorization: Bearer ${TEST_TOKEN}", " X-Role : web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("got %q, want %q", got, "Bearer secret")
+	}
+	if got := h.Get("X-Role"); got != "web" {
+		t.Errorf("got %q, want %q", got, "web")
+	}
+	for _, v := range []string{"invalid", ": value"} {
+		if _, err := ParseHeaders([]string{v}); err == nil {
+			t.Errorf("%q should be error", v)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// MaxRetryWait caps the wait to retry, not to block deploys such as until a rate limit resets.
+const MaxRetryWait = time.Minute
+
+// RetryWait reports whether the error is transient, and returns the wait before the retry of the attempt
+// counted from 0: the exponential backoff of the interval, or the time for the rate limit to reset if longer.
+// Rate limits resetting later than MaxRetryWait are not retried.
+func RetryWait(err error, interval time.Duration, attempt int) (time.Duration, bool) {
+	if !Retryable(err) {
+		return 0, false
+	}
+	wait := Backoff(interval, attempt)
+	if d := rateLimitWait(err); d > wait {
+		if d > MaxRetryWait {
+			return 0, false
+		}
+		wait = d
+	}
+	return wait, true
+}
+
+// Retryable reports whether the error is transient: rate limits, server errors, connection resets and timeouts.
+// Client errors such as not found are not retried.
+func Retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var rl *github.RateLimitError
+	var arl *github.AbuseRateLimitError
+	if errors.As(err, &rl) || errors.As(err, &arl) {
+		return true
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return retryableStatus(se.StatusCode)
+	}
+	var ge *github.ErrorResponse
+	if errors.As(err, &ge) {
+		return ge.Response != nil && retryableStatus(ge.Response.StatusCode)
+	}
+	// such as errors of S3
+	var sc interface{ StatusCode() int }
+	if errors.As(err, &sc) && sc.StatusCode() > 0 {
+		return retryableStatus(sc.StatusCode())
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// rateLimitWait returns the time for the rate limit of the error to reset, or zero if unknown.
+func rateLimitWait(err error) time.Duration {
+	var rl *github.RateLimitError
+	if errors.As(err, &rl) {
+		return time.Until(rl.Rate.Reset.Time)
+	}
+	var arl *github.AbuseRateLimitError
+	if errors.As(err, &arl) {
+		return arl.GetRetryAfter()
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.RetryAfter
+	}
+	return 0
+}
+
+// Backoff returns the wait before the retry of the attempt, doubling the interval up to MaxRetryWait
+// with jitter of up to half of it.
+func Backoff(interval time.Duration, attempt int) time.Duration {
+	wait := interval
+	for i := 0; i < attempt && wait < MaxRetryWait; i++ {
+		wait *= 2
+	}
+	if wait > MaxRetryWait {
+		wait = MaxRetryWait
+	}
+	if wait < 2 {
+		return wait
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)))
+}
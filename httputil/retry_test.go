@@ -0,0 +1,95 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&StatusError{StatusCode: http.StatusInternalServerError}, true},
+		{fmt.Errorf("wrapped: %w", &StatusError{StatusCode: http.StatusServiceUnavailable}), true},
+		{&StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{&StatusError{StatusCode: http.StatusNotFound}, false},
+		{&StatusError{StatusCode: http.StatusForbidden}, false},
+		{&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusBadGateway}}, true},
+		{&github.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}, false},
+		{&github.RateLimitError{}, true},
+		{&github.AbuseRateLimitError{}, true},
+		{syscall.ECONNRESET, true},
+		{io.ErrUnexpectedEOF, true},
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{errors.New("invalid url"), false},
+	}
+	for _, tt := range tests {
+		if got := Retryable(tt.err); got != tt.want {
+			t.Errorf("%v: got %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryWait(t *testing.T) {
+	retryAfter := 30 * time.Second
+	tests := []struct {
+		name   string
+		err    error
+		min    time.Duration
+		max    time.Duration
+		wantOK bool
+	}{
+		{"server error", &StatusError{StatusCode: http.StatusBadGateway}, 500 * time.Millisecond, time.Second, true},
+		{"retry after", &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 10 * time.Second}, 10 * time.Second, 10 * time.Second, true},
+		{"abuse rate limit", &github.AbuseRateLimitError{RetryAfter: &retryAfter}, retryAfter, retryAfter, true},
+		{"rate limit resetting soon", &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(20 * time.Second)}}}, 10 * time.Second, 20 * time.Second, true},
+		{"rate limit resetting later", &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(time.Hour)}}}, 0, 0, false},
+		{"client error", &StatusError{StatusCode: http.StatusNotFound}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := RetryWait(tt.err, time.Second, 0)
+			if ok != tt.wantOK {
+				t.Fatalf("got %t, want %t", ok, tt.wantOK)
+			}
+			if got < tt.min || got > tt.max {
+				t.Errorf("got %s, want between %s and %s", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestNewStatusError(t *testing.T) {
+	res := &http.Response{Status: "429 Too Many Requests", StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"5"}}}
+	e := NewStatusError("unexpected status", res)
+	if e.RetryAfter != 5*time.Second {
+		t.Errorf("got %s, want 5s", e.RetryAfter)
+	}
+	if got := e.Error(); got != "unexpected status: 429 Too Many Requests" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		want := time.Second << i
+		if want > MaxRetryWait {
+			want = MaxRetryWait
+		}
+		for j := 0; j < 100; j++ {
+			if got := Backoff(time.Second, i); got < want/2 || got > want {
+				t.Fatalf("attempt %d: got %s, want between %s and %s", i, got, want/2, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package httputil
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StatusError is returned when the server responds an unexpected status, so that callers can tell
+// transient server errors and rate limits from client errors.
+type StatusError struct {
+	Message    string
+	Status     string
+	StatusCode int
+	// RetryAfter is the wait requested by the Retry-After header in seconds, or zero.
+	RetryAfter time.Duration
+}
+
+// NewStatusError returns StatusError of the response.
+func NewStatusError(message string, res *http.Response) *StatusError {
+	e := &StatusError{Message: message, Status: res.Status, StatusCode: res.StatusCode}
+	if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && s > 0 {
+		e.RetryAfter = time.Duration(s) * time.Second
+	}
+	return e
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Message, e.Status)
+}
@@ -0,0 +1,47 @@
+package dewy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/linyows/dewy/otlp"
+)
+
+// install streams the cached artifact into the stdin of the install command instead of extracting it,
+// such as a package manager or an appliance loader. The artifact of the tag is deployed if the command succeeds.
+func (d *Dewy) install(ctx context.Context, key, tag string) error {
+	data, err := d.cache.Read(key)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Install %s by %s", key, d.config.InstallCommand)
+	span := otlp.SpanFromContext(ctx).Start("install")
+	cmd := shellCommand(d.config.InstallCommand)
+	cmd.Stdin = bytes.NewReader(data)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = d.runCommand(cmd)
+	span.End(err)
+	s := strings.TrimSpace(out.String())
+	if s != "" {
+		log.Printf("[INFO] Install command output: %s", s)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Install failure: %s", err)
+		return fmt.Errorf("install command failed: %w", err)
+	}
+
+	if err := d.recordTag(key, tag); err != nil {
+		return err
+	}
+	if err := d.state.Write(currentKey, []byte(key)); err != nil {
+		return err
+	}
+	d.deployedKey = key
+	return nil
+}
@@ -0,0 +1,62 @@
+package dewy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/linyows/dewy/kvs"
+)
+
+func TestInstall(t *testing.T) {
+	tests := []struct {
+		name    string
+		exit    string
+		wantErr bool
+	}{
+		{"success", "", false},
+		{"failure", "; exit 3", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kv := &kvs.File{}
+			kv.Default()
+			if err := kv.SetDir(t.TempDir()); err != nil {
+				t.Fatal(err)
+			}
+			if err := kv.Write("v1.0.0-app.pkg", []byte("package")); err != nil {
+				t.Fatal(err)
+			}
+			out := filepath.Join(t.TempDir(), "installed")
+			d := &Dewy{root: t.TempDir(), cache: kv, state: kv, config: Config{
+				Command:        ASSETS,
+				InstallCommand: "cat > " + out + tt.exit,
+			}}
+
+			err := d.deployContext(context.Background(), "v1.0.0-app.pkg", "v1.0.0")
+			if b, _ := os.ReadFile(out); string(b) != "package" {
+				t.Errorf("artifact is not streamed into the command: %q", b)
+			}
+			current, _ := kv.Read(currentKey)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want error")
+				}
+				if len(current) != 0 {
+					t.Errorf("failed install should not be current: %s", current)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(current) != "v1.0.0-app.pkg" {
+				t.Errorf("got current %q", current)
+			}
+			if kvs.IsFileExist(filepath.Join(d.root, symlinkDir)) {
+				t.Error("symlink should not be created")
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package kvs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// CompressionNone stores data as it is.
+	CompressionNone = "none"
+	// CompressionGzip stores data compressed by gzip.
+	CompressionGzip = "gzip"
+	// CompressionZstd stores data compressed by zstd.
+	CompressionZstd = "zstd"
+)
+
+// compressedMagic is the header of compressed data to distinguish it from
+// artifacts that are compressed by themselves.
+var compressedMagic = []byte("\x00dewy:")
+
+// ValidateCompression validates the compression type.
+func ValidateCompression(typ string) error {
+	switch typ {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+		return nil
+	default:
+		return fmt.Errorf("unsupported compression: %s", typ)
+	}
+}
+
+func compress(data []byte, typ string, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(compressedMagic)
+	buf.WriteString(typ + "\n")
+
+	switch typ {
+	case "", CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		w, err := gzip.NewWriterLevel(&buf, level)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionZstd:
+		var opts []zstd.EOption
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		w, err := zstd.NewWriter(nil, opts...)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(w.EncodeAll(data, nil))
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", typ)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func isCompressed(data []byte) bool {
+	return bytes.HasPrefix(data, compressedMagic)
+}
+
+// decompress detects the compression by the header and decompresses data.
+func decompress(data []byte) ([]byte, error) {
+	if !isCompressed(data) {
+		return data, nil
+	}
+	rest := data[len(compressedMagic):]
+	i := bytes.IndexByte(rest, '\n')
+	if i < 0 {
+		return nil, fmt.Errorf("invalid compression header")
+	}
+	typ := string(rest[:i])
+	body := rest[i+1:]
+
+	switch typ {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case CompressionZstd:
+		r, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return r.DecodeAll(body, nil)
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", typ)
+	}
+}
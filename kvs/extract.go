@@ -0,0 +1,267 @@
+package kvs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mholt/archiver/v3"
+	"github.com/nwaples/rardecode"
+)
+
+// Extractor extracts archives.
+type Extractor struct {
+	// Depth is the depth to extract archives contained in the archive.
+	// Nested archives are left as they are if zero.
+	Depth int
+	// MaxBytes is the limit of total uncompressed bytes. No limit if zero.
+	MaxBytes int64
+	// MaxFiles is the limit of the number of entries. No limit if zero.
+	MaxFiles int
+	// Check is called with the number of entries and uncompressed bytes of each archive, including nested ones,
+	// before writing, to abort the extraction such as for lack of disk space. The archive is read once more to count them.
+	Check func(dst string, files int, bytes int64) error
+
+	bytes int64
+	files int
+}
+
+// ErrExtractLimit is returned when the archive exceeds the extraction limits.
+var ErrExtractLimit = errors.New("archive exceeds the extraction limit")
+
+// Extract extracts src into dst.
+func (e *Extractor) Extract(src, dst string) error {
+	if !IsFileExist(src) {
+		return fmt.Errorf("File not found: %s", src)
+	}
+
+	src, cleanup, err := decompressFile(src)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	e.bytes, e.files = 0, 0
+	if err := e.unarchive(src, dst); err != nil {
+		return err
+	}
+
+	if e.Depth <= 0 {
+		return nil
+	}
+
+	for i := 0; ; i++ {
+		nested, err := findArchives(dst)
+		if err != nil {
+			return err
+		}
+		if len(nested) == 0 {
+			return nil
+		}
+		if i >= e.Depth {
+			return fmt.Errorf("nested archives exceed the depth limit %d: %s", e.Depth, nested[0])
+		}
+		for _, p := range nested {
+			// nested archives are extracted into their own directories not to overwrite files next to them
+			dir := archiveDir(p)
+			if err := os.Mkdir(dir, 0755); err != nil {
+				return fmt.Errorf("directory of the nested archive: %w", err)
+			}
+			if err := e.unarchive(p, dir); err != nil {
+				return err
+			}
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// unarchive extracts the archive src into dst in a single pass, counting entries and bytes written
+// to stop as soon as a limit is exceeded. Existing files are never overwritten.
+func (e *Extractor) unarchive(src, dst string) error {
+	if e.Check != nil {
+		files, bytes, err := count(src)
+		if err != nil {
+			return err
+		}
+		if err := e.Check(dst, files, bytes); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+
+	// errors of the callback are not wrapped by archiver
+	var walkErr error
+	err := archiver.Walk(src, func(f archiver.File) error {
+		e.files++
+		if e.MaxFiles > 0 && e.files > e.MaxFiles {
+			walkErr = fmt.Errorf("%w: more than %d files", ErrExtractLimit, e.MaxFiles)
+		} else {
+			walkErr = e.write(f, dst)
+		}
+		return walkErr
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+
+	return err
+}
+
+// write writes the entry of the archive into dst.
+func (e *Extractor) write(f archiver.File, dst string) error {
+	var name, link string
+	symlink, hardlink := f.Mode()&fs.ModeSymlink != 0, false
+	switch h := f.Header.(type) {
+	case *tar.Header:
+		name, link = h.Name, h.Linkname
+		switch h.Typeflag {
+		case tar.TypeLink:
+			hardlink = true
+		case tar.TypeXGlobalHeader:
+			// the pax global header of git-generated tarballs
+			return nil
+		}
+	case zip.FileHeader:
+		name = h.Name
+		if symlink {
+			// the target is the content
+			b, err := io.ReadAll(io.LimitReader(f, 4096))
+			if err != nil {
+				return err
+			}
+			link = strings.TrimSpace(string(b))
+		}
+	case *rardecode.FileHeader:
+		name = h.Name
+	default:
+		return fmt.Errorf("unsupported archive entry: %T", f.Header)
+	}
+	to := filepath.Join(dst, name)
+	if !within(dst, to) {
+		return fmt.Errorf("illegal path of the archive entry: %s", name)
+	}
+
+	if f.IsDir() {
+		return os.MkdirAll(to, f.Mode().Perm()|0700)
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	switch {
+	case hardlink:
+		from := filepath.Join(dst, link)
+		if !within(dst, from) {
+			return fmt.Errorf("illegal link of the archive entry: %s", name)
+		}
+		return os.Link(from, to)
+	case symlink:
+		return os.Symlink(link, to)
+	}
+
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, f.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(&countingWriter{w: out, e: e}, f)
+	if err == nil {
+		// the mode is not masked by umask
+		err = out.Chmod(f.Mode().Perm())
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// countingWriter counts bytes extracted by the extractor, failing when they exceed the limit.
+type countingWriter struct {
+	w io.Writer
+	e *Extractor
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.e.bytes += int64(len(p))
+	if c.e.MaxBytes > 0 && c.e.bytes > c.e.MaxBytes {
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrExtractLimit, c.e.MaxBytes)
+	}
+	return c.w.Write(p)
+}
+
+// count counts entries and uncompressed bytes of the archive without writing.
+func count(src string) (int, int64, error) {
+	var (
+		files int
+		bytes int64
+	)
+	err := archiver.Walk(src, func(f archiver.File) error {
+		files++
+		if f.IsDir() {
+			return nil
+		}
+		n, err := io.Copy(io.Discard, f)
+		bytes += n
+		return err
+	})
+
+	return files, bytes, err
+}
+
+// archiveExts are extensions of archives and compressions removed from names of nested archives for their directories.
+var archiveExts = map[string]bool{
+	".tar": true, ".zip": true, ".rar": true,
+	".gz": true, ".tgz": true, ".bz2": true, ".tbz2": true, ".xz": true, ".txz": true,
+	".lz4": true, ".tlz4": true, ".sz": true, ".tsz": true, ".zst": true, ".br": true, ".tbr": true,
+}
+
+// archiveDir returns the directory to extract the nested archive into, named after the archive without its extensions.
+func archiveDir(p string) string {
+	base := filepath.Base(p)
+	name := base
+	for archiveExts[strings.ToLower(filepath.Ext(name))] {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if name == "" || name == base {
+		name = base + ".d"
+	}
+	return filepath.Join(filepath.Dir(p), name)
+}
+
+// within reports whether the path is in the directory.
+func within(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// findArchives returns paths of archives in dir.
+func findArchives(dir string) ([]string, error) {
+	var archives []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		a, err := archiver.ByExtension(p)
+		if err != nil {
+			return nil
+		}
+		if _, ok := a.(archiver.Unarchiver); ok {
+			archives = append(archives, p)
+		}
+		return nil
+	})
+
+	return archives, err
+}
@@ -0,0 +1,162 @@
+package kvs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mholt/archiver/v3"
+)
+
+// nestedArchive creates an archive nesting app.txt in the given number of archives.
+func nestedArchive(t *testing.T, nest int) string {
+	t.Helper()
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.txt")
+	if err := os.WriteFile(p, []byte("this is app"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < nest; i++ {
+		a := filepath.Join(dir, fmt.Sprintf("level%d.tar.gz", i))
+		if err := archiver.Archive([]string{p}, a); err != nil {
+			t.Fatal(err)
+		}
+		p = a
+	}
+	return p
+}
+
+func TestExtractorExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		nest    int
+		depth   int
+		want    string
+		wantErr bool
+	}{
+		{"not nested", 1, 0, "app.txt", false},
+		{"nested without depth", 2, 0, "level0.tar.gz", false},
+		{"nested", 2, 1, "level0/app.txt", false},
+		{"too deep", 3, 1, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := nestedArchive(t, tt.nest)
+			dst := t.TempDir()
+			e := &Extractor{Depth: tt.depth}
+			err := e.Extract(src, dst)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsFileExist(filepath.Join(dst, tt.want)) {
+				t.Errorf("%s is not extracted", tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractorLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		nest     int
+		maxBytes int64
+		maxFiles int
+		wantErr  bool
+	}{
+		{"no limit", 2, 0, 0, false},
+		{"within limits", 2, 1024 * 1024, 10, false},
+		{"too many bytes", 1, 5, 0, true},
+		{"too many files in nested archives", 2, 0, 1, true},
+		{"too many bytes in nested archives", 2, 5, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := nestedArchive(t, tt.nest)
+			dst := t.TempDir()
+			e := &Extractor{Depth: 1, MaxBytes: tt.maxBytes, MaxFiles: tt.maxFiles}
+			err := e.Extract(src, dst)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrExtractLimit) {
+				t.Errorf("expected ErrExtractLimit, got %v", err)
+			}
+			if IsFileExist(filepath.Join(dst, "level0", "app.txt")) {
+				t.Error("app.txt should not be extracted")
+			}
+		})
+	}
+}
+
+func TestExtractorNestedDir(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app.txt")
+	if err := os.WriteFile(app, []byte("nested"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(dir, "app.tar.gz")
+	if err := archiver.Archive([]string{app}, nested); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(app, []byte("outer"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(t.TempDir(), "release.tar.gz")
+	if err := archiver.Archive([]string{app, nested}, src); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := t.TempDir()
+	var checked []string
+	e := &Extractor{Depth: 1, Check: func(dst string, _ int, _ int64) error {
+		checked = append(checked, dst)
+		return nil
+	}}
+	if err := e.Extract(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]string{"app.txt": "outer", "app/app.txt": "nested"} {
+		b, err := os.ReadFile(filepath.Join(dst, p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s: got %q, want %q", p, b, want)
+		}
+	}
+	if want := []string{dst, filepath.Join(dst, "app")}; !reflect.DeepEqual(checked, want) {
+		t.Errorf("checked %v, want %v", checked, want)
+	}
+}
+
+func TestExtractorCheck(t *testing.T) {
+	src := nestedArchive(t, 1)
+	dst := t.TempDir()
+	errCheck := errors.New("no space")
+	var files int
+	e := &Extractor{Check: func(_ string, n int, _ int64) error {
+		files = n
+		return errCheck
+	}}
+	if err := e.Extract(src, dst); !errors.Is(err, errCheck) {
+		t.Errorf("expected check error, got %v", err)
+	}
+	if files != 1 {
+		t.Errorf("got %d files, want 1", files)
+	}
+	if IsFileExist(filepath.Join(dst, "app.txt")) {
+		t.Error("app.txt should not be extracted")
+	}
+}
@@ -3,12 +3,11 @@ package kvs
 import (
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"sync"
-
-	"github.com/mholt/archiver/v3"
 )
 
 var (
@@ -16,6 +15,10 @@ var (
 	DefaultTempDir = createTempDir()
 	// DefaultMaxSize for data size.
 	DefaultMaxSize int64 = 64 * 1024 * 1024
+	// DefaultDirMode is the permission of created directories.
+	DefaultDirMode os.FileMode = 0755
+	// DefaultFileMode is the permission of written files.
+	DefaultFileMode os.FileMode = 0644
 )
 
 func createTempDir() string {
@@ -30,6 +33,14 @@ type File struct {
 	mutex    sync.Mutex //nolint
 	MaxItems int
 	MaxSize  int64
+	// Compression is the compression type of written data.
+	Compression string
+	// CompressionLevel is the compression level. The default level is used if zero.
+	CompressionLevel int
+	// DirMode is the permission of created directories. DefaultDirMode is used if zero.
+	DirMode os.FileMode
+	// FileMode is the permission of written files. DefaultFileMode is used if zero.
+	FileMode os.FileMode
 }
 
 // GetDir returns dir.
@@ -37,6 +48,29 @@ func (f *File) GetDir() string {
 	return f.dir
 }
 
+// SetDir sets dir and creates it if it does not exist.
+func (f *File) SetDir(dir string) error {
+	if err := os.MkdirAll(dir, f.dirMode()); err != nil {
+		return err
+	}
+	f.dir = dir
+	return nil
+}
+
+func (f *File) dirMode() os.FileMode {
+	if f.DirMode == 0 {
+		return DefaultDirMode
+	}
+	return f.DirMode
+}
+
+func (f *File) fileMode() os.FileMode {
+	if f.FileMode == 0 {
+		return DefaultFileMode
+	}
+	return f.FileMode
+}
+
 // Default sets to struct.
 func (f *File) Default() {
 	f.dir = DefaultTempDir
@@ -55,7 +89,7 @@ func (f *File) Read(key string) ([]byte, error) {
 		return nil, err
 	}
 
-	return content, nil
+	return decompress(content)
 }
 
 // Write data to file.
@@ -72,8 +106,13 @@ func (f *File) Write(key string, data []byte) error {
 		return errors.New("Max size has been reached")
 	}
 
+	data, err = compress(data, f.Compression, f.CompressionLevel)
+	if err != nil {
+		return err
+	}
+
 	p := filepath.Join(f.dir, key)
-	file, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	file, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.fileMode())
 	if err != nil {
 		return err
 	}
@@ -120,11 +159,44 @@ func (f *File) List() ([]string, error) {
 
 // ExtractArchive extracts by archive.
 func ExtractArchive(src, dst string) error {
-	if !IsFileExist(src) {
-		return fmt.Errorf("File not found: %s", src)
+	e := &Extractor{}
+	return e.Extract(src, dst)
+}
+
+// decompressFile writes the decompressed file into a temporary directory if the file is compressed by cache.
+func decompressFile(src string) (string, func(), error) {
+	nop := func() {}
+	f, err := os.Open(src)
+	if err != nil {
+		return "", nop, err
+	}
+	head := make([]byte, len(compressedMagic))
+	_, err = io.ReadFull(f, head)
+	f.Close()
+	if err != nil || !isCompressed(head) {
+		return src, nop, nil
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", nop, err
+	}
+	data, err = decompress(data)
+	if err != nil {
+		return "", nop, err
+	}
+	dir, err := os.MkdirTemp("", "dewy-extract-")
+	if err != nil {
+		return "", nop, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	p := filepath.Join(dir, filepath.Base(src))
+	if err := os.WriteFile(p, data, 0600); err != nil {
+		cleanup()
+		return "", nop, err
 	}
 
-	return archiver.Unarchive(src, dst)
+	return p, cleanup, nil
 }
 
 // IsFileExist checks file exists.
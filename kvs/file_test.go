@@ -1,6 +1,9 @@
 package kvs
 
 import (
+	"bytes"
+	"compress/gzip"
+	"os"
 	"path/filepath"
 	"reflect"
 	"testing"
@@ -116,3 +119,66 @@ func TestFileList(t *testing.T) {
 		t.Error("file not found in list")
 	}
 }
+
+func TestFileCompression(t *testing.T) {
+	data := bytes.Repeat([]byte("this is data for test"), 100)
+	for _, c := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		f := &File{}
+		f.Default()
+		f.Compression = c
+		if err := f.Write("testcompression", data); err != nil {
+			t.Fatal(err)
+		}
+		raw, err := os.ReadFile(filepath.Join(f.dir, "testcompression"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != CompressionNone && len(raw) >= len(data) {
+			t.Errorf("%s: data is not compressed", c)
+		}
+		content, err := f.Read("testcompression")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(content, data) {
+			t.Errorf("%s: reading is not correct", c)
+		}
+	}
+}
+
+func TestFileReadGzipArtifact(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte("this is artifact")); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	f := &File{}
+	f.Default()
+	if err := f.Write("test.gz", buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	content, err := f.Read("test.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(content, buf.Bytes()) {
+		t.Error("gzip artifact should not be decompressed")
+	}
+}
+
+func TestFileWriteMode(t *testing.T) {
+	f := &File{FileMode: 0600}
+	f.Default()
+	if err := f.Write("testmode", []byte("this is data for test")); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(f.dir, "testmode"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("got %o, want %o", fi.Mode().Perm(), 0600)
+	}
+}
@@ -15,6 +15,11 @@ type KVS interface {
 	GetDir() string
 }
 
+// Stager is the KVS storing data remotely, which stages data of the key in GetDir before it is read as a file.
+type Stager interface {
+	Stage(key string) error
+}
+
 // Config struct.
 type Config struct {
 }
@@ -24,6 +29,8 @@ func New(t string, c Config) (KVS, error) {
 	switch t {
 	case "file":
 		return &File{}, nil
+	case "memory":
+		return &Memory{}, nil
 	default:
 		return nil, errors.New("no provider")
 	}
@@ -1,6 +1,9 @@
 package kvs
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -9,3 +12,107 @@ func TestKV(t *testing.T) {
 		t.Error("")
 	}
 }
+
+// TestKVSParity runs the same cases on KVS implementations to guarantee they behave alike.
+func TestKVSParity(t *testing.T) {
+	impls := map[string]func(t *testing.T) KVS{
+		"file": func(t *testing.T) KVS {
+			f := &File{}
+			f.Default()
+			if err := f.SetDir(t.TempDir()); err != nil {
+				t.Fatal(err)
+			}
+			return f
+		},
+		"memory": func(t *testing.T) KVS {
+			m, err := NewMemory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				if err := m.Close(); err != nil {
+					t.Error(err)
+				}
+			})
+			return m
+		},
+	}
+	for name, newKVS := range impls {
+		t.Run(name, func(t *testing.T) {
+			kv := newKVS(t)
+			if list, err := kv.List(); err != nil || len(list) != 0 {
+				t.Fatalf("unexpected list of empty kvs: %v, %v", list, err)
+			}
+			if _, err := kv.Read("missing"); err == nil {
+				t.Error("reading missing key should be error")
+			}
+			if err := kv.Delete("missing"); err == nil {
+				t.Error("deleting missing key should be error")
+			}
+
+			if err := kv.Write("key", []byte("first")); err != nil {
+				t.Fatal(err)
+			}
+			if err := kv.Write("key", []byte("second")); err != nil {
+				t.Fatal(err)
+			}
+			got, err := kv.Read("key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "second" {
+				t.Errorf("got %s, want second", got)
+			}
+			if list, err := kv.List(); err != nil || !reflect.DeepEqual(list, []string{"key"}) {
+				t.Errorf("unexpected list: %v, %v", list, err)
+			}
+
+			// the data is read as a file in the directory to be extracted
+			if s, ok := kv.(Stager); ok {
+				if err := s.Stage("key"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			b, err := os.ReadFile(filepath.Join(kv.GetDir(), "key"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "second" {
+				t.Errorf("got %s in the directory, want second", b)
+			}
+
+			if err := kv.Delete("key"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := kv.Read("key"); err == nil {
+				t.Error("reading deleted key should be error")
+			}
+			if IsFileExist(filepath.Join(kv.GetDir(), "key")) {
+				t.Error("deleted data remains in the directory")
+			}
+		})
+	}
+}
+
+func TestMemoryClose(t *testing.T) {
+	m, err := NewMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := m.GetDir()
+	if err := m.Write("key", []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Stage("key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if IsFileExist(dir) {
+		t.Errorf("%s should be removed on close", dir)
+	}
+	if err := m.Stage("key"); err == nil {
+		t.Error("staging on the closed memory should be error")
+	}
+}
@@ -1,25 +1,129 @@
 package kvs
 
-// Memory struct.
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Memory stores data on memory for tests and one-shot deploys.
+// Data is staged in the directory of the instance, because artifacts are extracted from files.
 type Memory struct {
-	items map[string]*item //nolint
+	items map[string][]byte
+	dir   string
+	mu    sync.Mutex
+}
+
+var _ Stager = (*Memory)(nil)
+
+// NewMemory returns Memory staging data in a temporary directory, which is removed by Close.
+func NewMemory() (*Memory, error) {
+	dir, err := os.MkdirTemp("", "dewy-memory-")
+	if err != nil {
+		return nil, fmt.Errorf("memory failure to create the directory: %w", err)
+	}
+	m := &Memory{}
+	m.Default()
+	m.dir = dir
+	return m, nil
+}
+
+// Default sets to struct.
+func (m *Memory) Default() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items = map[string][]byte{}
+}
+
+// Close removes the directory of the instance where data is staged.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(m.dir)
+	m.dir = ""
+	return err
+}
+
+// GetDir returns the directory of the instance where data is staged.
+func (m *Memory) GetDir() string {
+	return m.dir
 }
 
 // Read data by key on memory.
-func (m *Memory) Read(key string) string {
-	return ""
+func (m *Memory) Read(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.items[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s on memory", os.ErrNotExist, key)
+	}
+	return append([]byte(nil), data...), nil
 }
 
 // Write data to memory.
-func (m *Memory) Write(data string) bool {
-	return true
+func (m *Memory) Write(key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.items == nil {
+		m.items = map[string][]byte{}
+	}
+	m.items[key] = append([]byte(nil), data...)
+	// the staged data is stale
+	if m.dir != "" {
+		if err := os.Remove(filepath.Join(m.dir, key)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
 }
 
-// Delete data by key on memory.
-func (m *Memory) Delete(key string) bool {
-	return true
+// Delete data by key on memory, and the staged data.
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.items[key]; !ok {
+		return fmt.Errorf("%w: %s on memory", os.ErrNotExist, key)
+	}
+	delete(m.items, key)
+	if m.dir != "" {
+		if err := os.Remove(filepath.Join(m.dir, key)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
 }
 
 // List returns keys from memory.
-func (m *Memory) List() {
+func (m *Memory) List() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.items))
+	for k := range m.items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Stage writes the data of the key into the directory of the instance.
+func (m *Memory) Stage(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.items[key]
+	if !ok {
+		return fmt.Errorf("%w: %s on memory", os.ErrNotExist, key)
+	}
+	if m.dir == "" {
+		return fmt.Errorf("memory has no directory to stage data")
+	}
+	p := filepath.Join(m.dir, key)
+	if IsFileExist(p) {
+		return nil
+	}
+	return os.WriteFile(p, data, DefaultFileMode)
 }
@@ -1,26 +1,207 @@
 package kvs
 
-// Redis struct.
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultRedisPrefix is the prefix of keys on Redis.
+	DefaultRedisPrefix = "dewy:"
+	// redisTimeout is the timeout of a command to Redis.
+	redisTimeout = 30 * time.Second
+)
+
+// Redis stores data on Redis so that the cache survives replacing hosts and is shared by them.
+// Data is also staged in the local directory, because artifacts are extracted from files.
+// State of the host, such as the current key, is not stored on Redis but in the local directory.
 type Redis struct {
-	items    map[string]*item //nolint
 	Host     string
 	Port     int
 	Password string
-	TTL      int
+	// DB is the database number.
+	DB int
+	// Prefix is the prefix of keys. DefaultRedisPrefix is used if empty.
+	Prefix string
+	// TTL is the expiration of written keys. No expiration if zero.
+	TTL time.Duration
+	// Local is the local directory where data is staged.
+	Local *File
+
+	mu sync.Mutex
+	cl *redis.Client
+}
+
+var _ Stager = (*Redis)(nil)
+
+// NewRedis returns Redis of the URL such as redis://:password@localhost:6379/0?prefix=dewy:&ttl=168h,
+// staging data in local.
+func NewRedis(urlstr string, local *File) (*Redis, error) {
+	u, err := url.Parse(urlstr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" {
+		return nil, fmt.Errorf("invalid redis url: %s", u.Redacted())
+	}
+	r := &Redis{Local: local}
+	r.Default()
+	if h := u.Hostname(); h != "" {
+		r.Host = h
+	}
+	if p := u.Port(); p != "" {
+		if r.Port, err = strconv.Atoi(p); err != nil {
+			return nil, fmt.Errorf("invalid redis port: %s", p)
+		}
+	}
+	if pw, ok := u.User.Password(); ok {
+		r.Password = pw
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if r.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid redis db: %s", db)
+		}
+	}
+	q := u.Query()
+	if p := q.Get("prefix"); p != "" {
+		r.Prefix = p
+	}
+	if ttl := q.Get("ttl"); ttl != "" {
+		if r.TTL, err = time.ParseDuration(ttl); err != nil {
+			return nil, fmt.Errorf("invalid redis ttl: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// Default sets to struct.
+func (r *Redis) Default() {
+	r.Host = "localhost"
+	r.Port = 6379
+	r.Prefix = DefaultRedisPrefix
+	if r.Local == nil {
+		r.Local = &File{}
+		r.Local.Default()
+	}
+}
+
+// GetDir returns the local directory where data is staged.
+func (r *Redis) GetDir() string {
+	return r.Local.GetDir()
+}
+
+// Read data by key on redis, and stage it.
+func (r *Redis) Read(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	data, err := r.client().Get(ctx, r.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s%s on redis", os.ErrNotExist, r.Prefix, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Local.Write(key, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Write data to redis, and stage it.
+func (r *Redis) Write(key string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := r.client().Set(ctx, r.Prefix+key, data, r.TTL).Err(); err != nil {
+		return err
+	}
+	return r.Local.Write(key, data)
+}
+
+// Delete key on redis, and the staged data.
+func (r *Redis) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := r.client().Del(ctx, r.Prefix+key).Err(); err != nil {
+		return err
+	}
+	if IsFileExist(filepath.Join(r.Local.GetDir(), key)) {
+		return r.Local.Delete(key)
+	}
+	return nil
+}
+
+// List returns keys under the prefix from redis.
+func (r *Redis) List() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	var keys []string
+	it := r.client().Scan(ctx, 0, escapeGlob(r.Prefix)+"*", 100).Iterator()
+	for it.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(it.Val(), r.Prefix))
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
 }
 
-// Read data by key on redis.
-func (r *Redis) Read(key string) {
+// Stage writes the data of the key into the local directory if not staged yet.
+func (r *Redis) Stage(key string) error {
+	if IsFileExist(filepath.Join(r.Local.GetDir(), key)) {
+		return nil
+	}
+	_, err := r.Read(key)
+	return err
 }
 
-// Write data to redis.
-func (r *Redis) Write(data string) {
+// Close closes the connections to redis.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cl == nil {
+		return nil
+	}
+	err := r.cl.Close()
+	r.cl = nil
+	return err
 }
 
-// Delete key on redis.
-func (r *Redis) Delete(key string) {
+// client returns the client of redis, connecting on the first use.
+func (r *Redis) client() *redis.Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cl == nil {
+		r.cl = redis.NewClient(&redis.Options{
+			Addr:         net.JoinHostPort(r.Host, strconv.Itoa(r.Port)),
+			Password:     r.Password,
+			DB:           r.DB,
+			DialTimeout:  redisTimeout,
+			ReadTimeout:  redisTimeout,
+			WriteTimeout: redisTimeout,
+		})
+	}
+	return r.cl
 }
 
-// List returns keys from redis.
-func (r *Redis) List() {
+// escapeGlob escapes characters of glob-style patterns of Redis.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
 }
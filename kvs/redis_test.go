@@ -0,0 +1,97 @@
+package kvs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewRedis(t *testing.T) {
+	r, err := NewRedis("redis://:secret@cache.example.com:6380/2?prefix=myapp:&ttl=1h", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Host != "cache.example.com" || r.Port != 6380 || r.Password != "secret" || r.DB != 2 || r.Prefix != "myapp:" || r.TTL != time.Hour {
+		t.Errorf("unexpected redis: %#v", r)
+	}
+	if r.Local == nil {
+		t.Error("local is not set by default")
+	}
+	if _, err := NewRedis("http://localhost", nil); err == nil {
+		t.Error("other scheme should be error")
+	}
+}
+
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("secret")
+	port, err := strconv.Atoi(mr.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := &File{}
+	local.Default()
+	if err := local.SetDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	r := &Redis{Host: mr.Host(), Port: port, Password: "secret", DB: 1, Prefix: "dewy:", TTL: time.Hour, Local: local}
+	mr.Select(1)
+	if err := mr.Set("other:key", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Write("v1.0.0.tar.gz", []byte("artifact")); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.DB(1).TTL("dewy:v1.0.0.tar.gz"); ttl != time.Hour {
+		t.Errorf("got ttl %s, want %s", ttl, time.Hour)
+	}
+	if err := r.Write("current.txt", []byte("v1.0.0.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	list, err := r.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(list)
+	if diff := cmp.Diff([]string{"current.txt", "v1.0.0.tar.gz"}, list); diff != "" {
+		t.Error(diff)
+	}
+
+	// the staged data is lost on a new host
+	if err := os.Remove(filepath.Join(local.GetDir(), "v1.0.0.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Stage("v1.0.0.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(r.GetDir(), "v1.0.0.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "artifact" {
+		t.Errorf("unexpected staged data: %s", b)
+	}
+
+	if err := r.Delete("v1.0.0.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read("v1.0.0.tar.gz"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if IsFileExist(filepath.Join(r.GetDir(), "v1.0.0.tar.gz")) {
+		t.Error("staged data is not deleted")
+	}
+
+	wrong := &Redis{Host: mr.Host(), Port: port, Password: "wrong", Prefix: "dewy:", Local: local}
+	if _, err := wrong.List(); err == nil {
+		t.Error("wrong password should be error")
+	}
+}
@@ -0,0 +1,149 @@
+package kvs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// S3 stores data on Amazon S3 so that the cache survives replacing hosts and is shared by them.
+// Data is also staged in the local directory, because artifacts are extracted from files.
+// State of the host, such as the current key, is not stored on S3 but in the local directory.
+type S3 struct {
+	Bucket string
+	// Prefix is the key prefix of data such as dewy/myapp/.
+	Prefix string
+	// Local is the local directory where data is staged.
+	Local *File
+	cl    s3iface.S3API
+}
+
+var _ Stager = (*S3)(nil)
+
+// NewS3 returns S3 of the URL such as s3://bucket/prefix?region=ap-northeast-1, staging data in local.
+func NewS3(urlstr string, local *File) (*S3, error) {
+	u, err := url.Parse(urlstr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 url: %s", urlstr)
+	}
+	cfg := aws.NewConfig()
+	if region := u.Query().Get("region"); region != "" {
+		cfg = cfg.WithRegion(region)
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &S3{Bucket: u.Host, Local: local, cl: s3.New(sess)}
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		s.Prefix = p + "/"
+	}
+	s.Default()
+	return s, nil
+}
+
+// Default sets to struct.
+func (s *S3) Default() {
+	if s.Local == nil {
+		s.Local = &File{}
+		s.Local.Default()
+	}
+}
+
+// GetDir returns the local directory where data is staged,
+// so that the artifact staged by Stage is extracted from the path joined with the key.
+func (s *S3) GetDir() string {
+	return s.Local.GetDir()
+}
+
+// Read data by key on s3, and stage it.
+func (s *S3) Read(key string) ([]byte, error) {
+	out, err := s.cl.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Prefix + key),
+	})
+	if err != nil {
+		var ae awserr.Error
+		if errors.As(err, &ae) && ae.Code() == s3.ErrCodeNoSuchKey {
+			return nil, fmt.Errorf("%w: s3://%s/%s%s", os.ErrNotExist, s.Bucket, s.Prefix, key)
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Local.Write(key, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Write data to s3, and stage it.
+func (s *S3) Write(key string, data []byte) error {
+	if _, err := s.cl.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Prefix + key),
+		Body:   bytes.NewReader(data),
+	}); err != nil {
+		return err
+	}
+	return s.Local.Write(key, data)
+}
+
+// Delete key on s3, and the staged data.
+func (s *S3) Delete(key string) error {
+	if _, err := s.cl.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Prefix + key),
+	}); err != nil {
+		return err
+	}
+	if IsFileExist(filepath.Join(s.Local.GetDir(), key)) {
+		return s.Local.Delete(key)
+	}
+	return nil
+}
+
+// List returns keys directly under the prefix from s3.
+func (s *S3) List() ([]string, error) {
+	var keys []string
+	err := s.cl.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.Bucket),
+		Prefix:    aws.String(s.Prefix),
+		Delimiter: aws.String("/"),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, o := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(aws.StringValue(o.Key), s.Prefix))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Stage writes the data of the key into the local directory if not staged yet.
+func (s *S3) Stage(key string) error {
+	if IsFileExist(filepath.Join(s.Local.GetDir(), key)) {
+		return nil
+	}
+	_, err := s.Read(key)
+	return err
+}
@@ -0,0 +1,119 @@
+package kvs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/google/go-cmp/cmp"
+)
+
+type mockS3 struct {
+	s3iface.S3API
+	objects map[string]string
+}
+
+func (m *mockS3) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	d, ok := m.objects[aws.StringValue(in.Key)]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "not found", nil)
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(d))}, nil
+}
+
+func (m *mockS3) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	b, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.objects[aws.StringValue(in.Key)] = string(b)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *mockS3) DeleteObject(in *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	delete(m.objects, aws.StringValue(in.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *mockS3) ListObjectsV2Pages(in *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	var contents []*s3.Object
+	for k := range m.objects {
+		rest, ok := strings.CutPrefix(k, aws.StringValue(in.Prefix))
+		if ok && !strings.Contains(rest, aws.StringValue(in.Delimiter)) {
+			contents = append(contents, &s3.Object{Key: aws.String(k)})
+		}
+	}
+	fn(&s3.ListObjectsV2Output{Contents: contents}, true)
+	return nil
+}
+
+func TestS3(t *testing.T) {
+	local := &File{}
+	local.Default()
+	if err := local.SetDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	m := &mockS3{objects: map[string]string{"dewy/other/key": "x"}}
+	s := &S3{Bucket: "bucket", Prefix: "dewy/", Local: local, cl: m}
+
+	if err := s.Write("v1.0.0.tar.gz", []byte("artifact")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write("current.txt", []byte("v1.0.0.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	list, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(list)
+	if diff := cmp.Diff([]string{"current.txt", "v1.0.0.tar.gz"}, list); diff != "" {
+		t.Error(diff)
+	}
+
+	// the staged data is lost on a new host
+	if err := os.Remove(filepath.Join(local.GetDir(), "v1.0.0.tar.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stage("v1.0.0.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(s.GetDir(), "v1.0.0.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "artifact" {
+		t.Errorf("unexpected staged data: %s", b)
+	}
+
+	if err := s.Delete("v1.0.0.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read("v1.0.0.tar.gz"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if IsFileExist(filepath.Join(s.GetDir(), "v1.0.0.tar.gz")) {
+		t.Error("staged data is not deleted")
+	}
+}
+
+func TestNewS3(t *testing.T) {
+	s, err := NewS3("s3://bucket/dewy/myapp/?region=ap-northeast-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Bucket != "bucket" || s.Prefix != "dewy/myapp/" || s.Local == nil {
+		t.Errorf("unexpected s3: %#v", s)
+	}
+	if _, err := NewS3("s3:///dewy", nil); err == nil {
+		t.Error("no bucket should be error")
+	}
+}
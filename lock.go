@@ -0,0 +1,40 @@
+package dewy
+
+import (
+	"log"
+	"os"
+)
+
+// lockDeploy acquires the host-wide deploy lock shared by Dewy instances, waiting for others to finish.
+// The lock is released by the returned function, or by the kernel if Dewy crashes.
+// Deploys within the instance, such as the rollback of the post deploy watch, are serialized without the lock file too.
+func (d *Dewy) lockDeploy() (func(), error) {
+	d.deploying.Lock()
+	if d.config.GlobalDeployLock == "" {
+		return d.deploying.Unlock, nil
+	}
+	f, err := os.OpenFile(d.config.GlobalDeployLock, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		d.deploying.Unlock()
+		return nil, err
+	}
+	locked, err := tryLockFile(f)
+	if err == nil && !locked {
+		log.Printf("[INFO] Wait for deploy lock %s held by another instance", d.config.GlobalDeployLock)
+		err = lockFile(f)
+	}
+	if err != nil {
+		f.Close()
+		d.deploying.Unlock()
+		return nil, err
+	}
+	log.Printf("[DEBUG] Acquired deploy lock %s", d.config.GlobalDeployLock)
+
+	return func() {
+		if err := unlockFile(f); err != nil {
+			log.Printf("[ERROR] Deploy lock failure: %#v", err)
+		}
+		f.Close()
+		d.deploying.Unlock()
+	}, nil
+}
@@ -0,0 +1,40 @@
+package dewy
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockDeploy(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "deploy.lock")
+	d1 := &Dewy{config: Config{GlobalDeployLock: p}}
+	d2 := &Dewy{config: Config{GlobalDeployLock: p}}
+
+	unlock, err := d1.lockDeploy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	acquired := make(chan func())
+	go func() {
+		u, err := d2.lockDeploy()
+		if err != nil {
+			t.Error(err)
+			u = func() {}
+		}
+		acquired <- u
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock should wait for another instance")
+	case <-time.After(200 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case u := <-acquired:
+		u()
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock should be acquired after released")
+	}
+}
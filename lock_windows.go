@@ -0,0 +1,22 @@
+//go:build windows
+
+package dewy
+
+import (
+	"errors"
+	"os"
+)
+
+var errLockNotSupported = errors.New("deploy lock is not supported on windows")
+
+func tryLockFile(f *os.File) (bool, error) {
+	return false, errLockNotSupported
+}
+
+func lockFile(f *os.File) error {
+	return errLockNotSupported
+}
+
+func unlockFile(f *os.File) error {
+	return errLockNotSupported
+}
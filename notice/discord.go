@@ -0,0 +1,141 @@
+package notice
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/linyows/dewy/httputil"
+)
+
+var (
+	// DiscordFooter variable.
+	DiscordFooter = "Dewy notice/discord"
+	// discordWebhookURL is the URL of Discord webhooks, followed by the ID and the token.
+	discordWebhookURL = "https://discord.com/api/webhooks/"
+	// slackLinkRe matches links of Slack such as <https://example.com|text>.
+	slackLinkRe = regexp.MustCompile(`<([^<>|\s]+)\|([^<>]+)>`)
+)
+
+// Discord posts messages as embeds to the Discord webhook URL.
+type Discord struct {
+	URL  string
+	Meta *Config
+}
+
+// isDiscordWebhook reports whether the URL is the webhook URL of Discord.
+func isDiscordWebhook(u string) bool {
+	for _, h := range []string{"https://discord.com/api/webhooks/", "https://discordapp.com/api/webhooks/"} {
+		if strings.HasPrefix(u, h) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Discord) String() string {
+	return "discord"
+}
+
+type discordPayload struct {
+	Username  string         `json:"username"`
+	AvatarURL string         `json:"avatar_url"`
+	Embeds    []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title,omitempty"`
+	URL         string              `json:"url,omitempty"`
+	Description string              `json:"description"`
+	Color       int                 `json:"color"`
+	Author      *discordEmbedAuthor `json:"author,omitempty"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+	Footer      *discordEmbedFooter `json:"footer,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"`
+}
+
+type discordEmbedAuthor struct {
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbedFooter struct {
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// Notify posts the message to the Discord webhook.
+func (d *Discord) Notify(ctx context.Context, message string) error {
+	p := discordPayload{
+		Username:  SlackUsername,
+		AvatarURL: SlackIconURL,
+		Embeds:    []discordEmbed{d.buildEmbed(message, ctx.Value(MetaContextKey) != nil)},
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return httputil.NewStatusError("discord webhook failure", res)
+	}
+	return nil
+}
+
+// discordMarkdown converts links of Slack in the message, such as of message templates, to markdown links of Discord.
+func discordMarkdown(message string) string {
+	return slackLinkRe.ReplaceAllString(message, "[$2]($1)")
+}
+
+func (d *Discord) buildEmbed(message string, meta bool) discordEmbed {
+	message = discordMarkdown(message)
+	e := discordEmbed{Color: d.genColor()}
+	if d.Meta == nil {
+		e.Description = message
+		return e
+	}
+	if !meta {
+		e.Description = fmt.Sprintf("%s of [%s](%s) on %s", message, d.Meta.Repo, d.Meta.RepoLink, hostname())
+		return e
+	}
+	e.Description = message
+	e.Title = d.Meta.Repo
+	e.URL = d.Meta.RepoLink
+	if d.Meta.Owner != "" {
+		e.Author = &discordEmbedAuthor{Name: d.Meta.Owner, URL: d.Meta.OwnerLink, IconURL: d.Meta.OwnerIcon}
+	}
+	e.Footer = &discordEmbedFooter{Text: DiscordFooter, IconURL: SlackFooterIcon}
+	e.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	for _, f := range d.Meta.Fields() {
+		e.Fields = append(e.Fields, discordEmbedField{Name: f.Title, Value: f.Value, Inline: f.Short})
+	}
+	return e
+}
+
+// genColor returns the color of the host like Slack.
+func (d *Discord) genColor() int {
+	sum := md5.Sum([]byte(hostname())) //nolint:gosec
+	return int(sum[0])<<16 | int(sum[1])<<8 | int(sum[2])
+}
@@ -0,0 +1,96 @@
+package notice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDiscord(t *testing.T) {
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		wantTitle  string
+		wantFields bool
+	}{
+		{"with meta", context.WithValue(context.Background(), MetaContextKey, true), "dewy", true},
+		{"without meta", context.Background(), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("unexpected content type: %s", r.Header.Get("Content-Type"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer ts.Close()
+
+			d := &Discord{URL: ts.URL, Meta: &Config{Repo: "dewy", RepoLink: "https://github.com/linyows/dewy", Owner: "linyows", Command: "server", Role: "web"}}
+			if err := d.Notify(tt.ctx, "deployed"); err != nil {
+				t.Fatal(err)
+			}
+			if got["username"] != SlackUsername {
+				t.Errorf("unexpected username: %v", got["username"])
+			}
+			embeds, ok := got["embeds"].([]any)
+			if !ok || len(embeds) != 1 {
+				t.Fatalf("unexpected embeds: %v", got["embeds"])
+			}
+			e := embeds[0].(map[string]any)
+			if _, ok := e["color"].(float64); !ok {
+				t.Errorf("color should be a number: %v", e["color"])
+			}
+			if title, _ := e["title"].(string); title != tt.wantTitle {
+				t.Errorf("got title %q, want %q", title, tt.wantTitle)
+			}
+			fields, _ := e["fields"].([]any)
+			if (len(fields) > 0) != tt.wantFields {
+				t.Fatalf("unexpected fields: %v", fields)
+			}
+			if tt.wantFields {
+				f := fields[0].(map[string]any)
+				if f["name"] != "Command" || f["value"] != "server" || f["inline"] != true {
+					t.Errorf("unexpected field: %v", f)
+				}
+				last := fields[len(fields)-1].(map[string]any)
+				if last["name"] != "Role" || last["value"] != "web" {
+					t.Errorf("unexpected last field: %v", last)
+				}
+			}
+		})
+	}
+}
+
+func TestDiscordFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	d := &Discord{URL: ts.URL, Meta: &Config{}}
+	if err := d.Notify(context.Background(), "deployed"); err == nil {
+		t.Error("non-2xx response should be error")
+	}
+}
+
+func TestDiscordMarkdown(t *testing.T) {
+	msg, err := Render(nil, EventDeployed, Message{Tag: "v1.2.3", URL: "https://example.com/app.tar.gz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Deployed [v1.2.3](https://example.com/app.tar.gz)"
+	if got := discordMarkdown(msg); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	d := &Discord{}
+	if got := d.buildEmbed("<https://example.com|a> and <https://example.org|b>", false).Description; got != "[a](https://example.com) and [b](https://example.org)" {
+		t.Errorf("unexpected description: %q", got)
+	}
+}
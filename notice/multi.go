@@ -0,0 +1,103 @@
+package notice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/linyows/dewy/httputil"
+)
+
+// Multi fans out messages to several notifiers.
+type Multi []Notice
+
+var _ Notice = (Multi)(nil)
+
+func (m Multi) String() string {
+	return "multi"
+}
+
+// Notify notifies all notifiers concurrently with the context, so that a slow or failing notifier
+// does not block others, and returns the joined errors.
+func (m Multi) Notify(ctx context.Context, message string) error {
+	errs := make([]error, len(m))
+	var wg sync.WaitGroup
+	for i, n := range m {
+		wg.Add(1)
+		go func(i int, n Notice) {
+			defer wg.Done()
+			if err := n.Notify(ctx, message); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", n, err)
+			}
+		}(i, n)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// NewFromURLs returns the notifier built from notifier URLs, which fans out if there are several.
+// The URL is "slack" or "slack://<channel>" for Slack, "discord://<id>/<token>" or the webhook URL of Discord for Discord,
+// http(s) URL for the webhook, and "none" to notify nothing.
+// "discord" and "webhook" read the URL from DISCORD_WEBHOOK_URL and WEBHOOK_URL.
+// Each of urls may be comma-separated URLs.
+func NewFromURLs(urls []string, meta *Config) (Notice, error) {
+	var m Multi
+	for _, us := range urls {
+		for _, u := range strings.Split(us, ",") {
+			if u = strings.TrimSpace(u); u == "" {
+				continue
+			}
+			n, err := newFromURL(u, meta)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, n)
+		}
+	}
+	switch len(m) {
+	case 0:
+		return nil, fmt.Errorf("no noticer")
+	case 1:
+		return m[0], nil
+	}
+	return m, nil
+}
+
+func newFromURL(u string, meta *Config) (Notice, error) {
+	switch u {
+	case "none":
+		return &None{}, nil
+	case "discord":
+		v := os.Getenv("DISCORD_WEBHOOK_URL")
+		if !isDiscordWebhook(v) {
+			return nil, fmt.Errorf("DISCORD_WEBHOOK_URL is required to be the webhook URL of Discord")
+		}
+		return &Discord{URL: v, Meta: meta}, nil
+	case "webhook":
+		v := os.Getenv("WEBHOOK_URL")
+		if !strings.HasPrefix(v, "http://") && !strings.HasPrefix(v, "https://") {
+			return nil, fmt.Errorf("WEBHOOK_URL is required to be http(s) URL")
+		}
+		return newFromURL(v, meta)
+	}
+	switch {
+	case u == "slack":
+		return &Slack{Meta: meta}, nil
+	case strings.HasPrefix(u, "slack://"):
+		return &Slack{Channel: strings.TrimPrefix(u, "slack://"), Meta: meta}, nil
+	case strings.HasPrefix(u, "discord://"):
+		return &Discord{URL: discordWebhookURL + strings.TrimPrefix(u, "discord://"), Meta: meta}, nil
+	case isDiscordWebhook(u):
+		return &Discord{URL: u, Meta: meta}, nil
+	case strings.HasPrefix(u, "http://"), strings.HasPrefix(u, "https://"):
+		h, err := httputil.ParseHeaders(meta.WebhookHeaders)
+		if err != nil {
+			return nil, err
+		}
+		return &Webhook{URL: u, Meta: meta, Header: h}, nil
+	}
+	return nil, fmt.Errorf("unsupported notifier: %s", u)
+}
@@ -0,0 +1,181 @@
+package notice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMulti(t *testing.T) {
+	failing := &flaky{failures: 1}
+	ok := &flaky{}
+	m := Multi{failing, ok}
+
+	if err := m.Notify(context.Background(), "hello"); err == nil {
+		t.Error("error of the failing notifier should be returned")
+	}
+	if len(ok.delivered) != 1 {
+		t.Errorf("other notifiers should be notified, got %d", len(ok.delivered))
+	}
+}
+
+type waiting struct {
+	err error
+}
+
+func (w *waiting) String() string {
+	return "waiting"
+}
+
+func (w *waiting) Notify(ctx context.Context, message string) error {
+	<-ctx.Done()
+	w.err = ctx.Err()
+	return w.err
+}
+
+func TestMultiCancel(t *testing.T) {
+	failing := &flaky{failures: 1}
+	ok := &flaky{}
+	w1, w2 := &waiting{}, &waiting{}
+	m := Multi{w1, failing, ok, w2}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := m.Notify(ctx, "hello")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("canceling should be returned, got %v", err)
+	}
+	for _, w := range []*waiting{w1, w2} {
+		if !errors.Is(w.err, context.Canceled) {
+			t.Errorf("canceling should propagate to all notifiers, got %v", w.err)
+		}
+	}
+	if failing.calls != 1 || len(ok.delivered) != 1 {
+		t.Errorf("all notifiers should be notified, got %d calls and %d delivered", failing.calls, len(ok.delivered))
+	}
+}
+
+func TestNewFromURLs(t *testing.T) {
+	tests := []struct {
+		urls    []string
+		want    string
+		wantErr bool
+	}{
+		{[]string{"slack"}, "slack", false},
+		{[]string{"slack://deploy"}, "slack", false},
+		{[]string{"https://example.com/hook"}, "webhook", false},
+		{[]string{"discord://123/token"}, "discord", false},
+		{[]string{"https://discord.com/api/webhooks/123/token"}, "discord", false},
+		{[]string{"slack", "https://example.com/hook"}, "multi", false},
+		{[]string{"slack, https://example.com/hook"}, "multi", false},
+		{[]string{"slack,"}, "slack", false},
+		{[]string{"slack,smtp://example.com"}, "", true},
+		{[]string{"smtp://example.com"}, "", true},
+		{nil, "", true},
+	}
+	for _, tt := range tests {
+		n, err := NewFromURLs(tt.urls, &Config{})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%v: unexpected error: %v", tt.urls, err)
+			continue
+		}
+		if err == nil && n.String() != tt.want {
+			t.Errorf("%v: got %s, want %s", tt.urls, n, tt.want)
+		}
+	}
+}
+
+func TestNewFromURLSelectors(t *testing.T) {
+	t.Setenv("DISCORD_WEBHOOK_URL", "https://discord.com/api/webhooks/123/token")
+	t.Setenv("WEBHOOK_URL", "https://example.com/hook")
+	tests := []struct {
+		selector string
+		want     Notice
+	}{
+		{"slack", &Slack{}},
+		{"discord", &Discord{}},
+		{"webhook", &Webhook{}},
+		{"none", &None{}},
+	}
+	for _, tt := range tests {
+		n, err := newFromURL(tt.selector, &Config{})
+		if err != nil {
+			t.Errorf("%s: %v", tt.selector, err)
+			continue
+		}
+		if got, want := fmt.Sprintf("%T", n), fmt.Sprintf("%T", tt.want); got != want {
+			t.Errorf("%s: got %s, want %s", tt.selector, got, want)
+		}
+	}
+	if err := (&None{}).Notify(context.Background(), "hello"); err != nil {
+		t.Errorf("none should notify nothing: %v", err)
+	}
+
+	t.Setenv("DISCORD_WEBHOOK_URL", "")
+	t.Setenv("WEBHOOK_URL", "")
+	for _, s := range []string{"discord", "webhook"} {
+		if _, err := newFromURL(s, &Config{}); err == nil {
+			t.Errorf("%s without the URL should be an error", s)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	t.Setenv("HOOK_TOKEN", "secret")
+	var (
+		got  webhookPayload
+		auth string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	n, err := newFromURL(ts.URL, &Config{
+		Repo:           "dewy",
+		RepoLink:       "https://github.com/linyows/dewy",
+		Command:        "server",
+		WebhookHeaders: []string{"Authorization: Bearer ${HOOK_TOKEN}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(context.WithValue(context.Background(), MetaContextKey, true), "deployed"); err != nil {
+		t.Fatal(err)
+	}
+	if got.Message != "deployed" || got.Repo != "dewy" || got.Host == "" || got.Link != "https://github.com/linyows/dewy" {
+		t.Errorf("unexpected payload: %+v", got)
+	}
+	if _, err := time.Parse(time.RFC3339, got.Time); err != nil {
+		t.Errorf("time should be RFC3339: %s", got.Time)
+	}
+	if len(got.Fields) == 0 || got.Fields[0].Title == "" {
+		t.Errorf("fields should be posted: %+v", got.Fields)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("got Authorization %q, want %q", auth, "Bearer secret")
+	}
+}
+
+func TestWebhookFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	w := &Webhook{URL: ts.URL, Meta: &Config{}}
+	if err := w.Notify(context.Background(), "deployed"); err == nil {
+		t.Error("non-2xx status should be an error")
+	}
+	if _, err := newFromURL(ts.URL, &Config{WebhookHeaders: []string{"invalid"}}); err == nil {
+		t.Error("invalid header should be an error")
+	}
+}
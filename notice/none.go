@@ -0,0 +1,17 @@
+package notice
+
+import "context"
+
+// None discards messages, so that notifying is safe without notifiers.
+type None struct{}
+
+var _ Notice = (*None)(nil)
+
+func (n *None) String() string {
+	return "none"
+}
+
+// Notify does nothing.
+func (n *None) Notify(ctx context.Context, message string) error {
+	return nil
+}
@@ -5,19 +5,20 @@ import (
 	"fmt"
 	"os"
 	"os/user"
+	"strings"
 )
 
 // Notice interface.
 type Notice interface {
 	String() string
-	Notify(ctx context.Context, message string)
+	Notify(ctx context.Context, message string) error
 }
 
 // Field struct.
 type Field struct {
-	Title string
-	Value string
-	Short bool
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
 }
 
 // Config struct.
@@ -29,12 +30,34 @@ type Config struct {
 	RepoLink  string
 	OwnerIcon string
 	OwnerLink string
+	Role      string
+	Tags      []string
+	// WebhookHeaders are headers of requests of webhooks such as "Authorization: Bearer ${TOKEN}".
+	WebhookHeaders []string
+}
+
+// Fields returns fields describing the deploy, attached to messages of notifiers supporting them.
+func (c *Config) Fields() []Field {
+	fields := []Field{
+		{Title: "Command", Value: c.Command, Short: true},
+		{Title: "Host", Value: hostname(), Short: true},
+		{Title: "User", Value: username(), Short: true},
+		{Title: "Source", Value: c.Source, Short: true},
+		{Title: "Working directory", Value: cwd(), Short: false},
+	}
+	if c.Role != "" {
+		fields = append(fields, Field{Title: "Role", Value: c.Role, Short: true})
+	}
+	if len(c.Tags) > 0 {
+		fields = append(fields, Field{Title: "Tags", Value: strings.Join(c.Tags, ", "), Short: true})
+	}
+	return fields
 }
 
 // New returns Notice.
 func New(n Notice) (Notice, error) {
 	switch n.String() {
-	case "slack":
+	case "slack", "discord", "webhook", "multi", "none":
 		return n, nil
 	default:
 		return nil, fmt.Errorf("no noticer")
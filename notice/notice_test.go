@@ -0,0 +1,33 @@
+package notice
+
+import "testing"
+
+func TestConfigFields(t *testing.T) {
+	tests := []struct {
+		name string
+		c    *Config
+		want map[string]string
+	}{
+		{"no role", &Config{Command: "server"}, map[string]string{"Command": "server"}},
+		{"role and tags", &Config{Command: "server", Role: "web", Tags: []string{"tokyo", "blue"}}, map[string]string{"Role": "web", "Tags": "tokyo, blue"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			for _, f := range tt.c.Fields() {
+				got[f.Title] = f.Value
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s: got %q, want %q", k, got[k], v)
+				}
+			}
+			if _, ok := got["Role"]; ok && tt.c.Role == "" {
+				t.Error("role should be omitted if not configured")
+			}
+			if _, ok := got["Tags"]; ok && len(tt.c.Tags) == 0 {
+				t.Error("tags should be omitted if not configured")
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package notice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/linyows/dewy/httputil"
+)
+
+var (
+	// ErrQueueFull is returned when the message is dropped because the queue is full.
+	ErrQueueFull = errors.New("notice queue is full")
+	// ErrMisconfigured is wrapped by failures of notifiers which retries never resolve, such as a missing token.
+	ErrMisconfigured = errors.New("notifier is misconfigured")
+)
+
+const queueSize = 100
+
+type queued struct {
+	ctx     context.Context
+	message string
+}
+
+// worker delivers messages with a notifier in order.
+type worker struct {
+	notice Notice
+	ch     chan queued
+	done   chan struct{}
+}
+
+// Queue delivers messages in the background and retries failures,
+// so that outages of the notifier never block or fail deploys.
+// Each notifier of Multi is queued on its own, so that a failure of one is retried
+// without sending the message again to others.
+type Queue struct {
+	name     string
+	workers  []*worker
+	attempts int
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	once     sync.Once
+}
+
+var _ Notice = (*Queue)(nil)
+
+// NewQueue returns Queue delivering messages with n up to attempts times.
+func NewQueue(n Notice, attempts int, interval time.Duration) *Queue {
+	if attempts < 1 {
+		attempts = 1
+	}
+	ns := []Notice{n}
+	if m, ok := n.(Multi); ok {
+		ns = m
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		name:     n.String(),
+		attempts: attempts,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for _, n := range ns {
+		w := &worker{notice: n, ch: make(chan queued, queueSize), done: make(chan struct{})}
+		q.workers = append(q.workers, w)
+		go q.run(w)
+	}
+	return q
+}
+
+func (q *Queue) String() string {
+	return q.name
+}
+
+// Notify queues the message without blocking.
+func (q *Queue) Notify(ctx context.Context, message string) error {
+	var errs []error
+	for _, w := range q.workers {
+		select {
+		case w.ch <- queued{ctx: ctx, message: message}:
+		default:
+			errs = append(errs, fmt.Errorf("%s: %w", w.notice, ErrQueueFull))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close delivers queued messages until the timeout and stops the queue,
+// canceling notifiers still delivering.
+func (q *Queue) Close(timeout time.Duration) {
+	q.once.Do(func() {
+		for _, w := range q.workers {
+			close(w.ch)
+		}
+	})
+	defer q.cancel()
+	deadline := time.After(timeout)
+	for _, w := range q.workers {
+		select {
+		case <-w.done:
+		case <-deadline:
+			log.Printf("[ERROR] Notice queue is closed with undelivered messages")
+			return
+		}
+	}
+}
+
+func (q *Queue) run(w *worker) {
+	defer close(w.done)
+	for m := range w.ch {
+		// the message is delivered after the caller returns, so the queue cancels it instead of the caller
+		ctx, cancel := context.WithCancel(context.WithoutCancel(m.ctx))
+		stop := context.AfterFunc(q.ctx, cancel)
+		err := q.deliver(ctx, w.notice, m.message)
+		stop()
+		cancel()
+		if err != nil {
+			log.Printf("[ERROR] Notice dropped: %s", m.message)
+		}
+	}
+}
+
+// deliver notifies the message up to attempts times while the failure is retryable.
+func (q *Queue) deliver(ctx context.Context, n Notice, message string) error {
+	var err error
+	for i := 0; i < q.attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return errors.Join(err, ctx.Err())
+			case <-time.After(q.interval):
+			}
+		}
+		if err = n.Notify(ctx, message); err == nil {
+			return nil
+		}
+		log.Printf("[ERROR] Notice failure of %s (%d/%d): %s", n, i+1, q.attempts, err)
+		if !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// retryable reports whether the failure may be resolved by a retry,
+// which is neither a misconfiguration nor a client error except rate limits.
+func retryable(err error) bool {
+	if errors.Is(err, ErrMisconfigured) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *httputil.StatusError
+	if errors.As(err, &se) {
+		return httputil.Retryable(se)
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package notice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linyows/dewy/httputil"
+)
+
+type flaky struct {
+	mu        sync.Mutex
+	failures  int
+	err       error
+	calls     int
+	delivered []string
+}
+
+func (f *flaky) String() string {
+	return "flaky"
+}
+
+func (f *flaky) Notify(ctx context.Context, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		if f.err != nil {
+			return f.err
+		}
+		return errors.New("unreachable")
+	}
+	f.delivered = append(f.delivered, message)
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		attempts  int
+		want      int
+		wantCalls int
+	}{
+		{"delivered", 0, nil, 3, 1, 1},
+		{"delivered by retry", 2, nil, 3, 1, 3},
+		{"dropped", 3, nil, 3, 0, 3},
+		{"misconfigured", 3, fmt.Errorf("%w: token is required", ErrMisconfigured), 3, 0, 1},
+		{"client error", 3, &httputil.StatusError{Message: "webhook failure", Status: "404 Not Found", StatusCode: 404}, 3, 0, 1},
+		{"rate limit", 2, &httputil.StatusError{Message: "webhook failure", Status: "429 Too Many Requests", StatusCode: 429}, 3, 1, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &flaky{failures: tt.failures, err: tt.err}
+			q := NewQueue(f, tt.attempts, time.Millisecond)
+			ctx, cancel := context.WithCancel(context.Background())
+			if err := q.Notify(ctx, "hello"); err != nil {
+				t.Fatal(err)
+			}
+			// canceling the context of the caller does not affect delivery
+			cancel()
+			q.Close(time.Second)
+			if len(f.delivered) != tt.want {
+				t.Errorf("got %d delivered, want %d", len(f.delivered), tt.want)
+			}
+			if f.calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", f.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestQueueMulti(t *testing.T) {
+	failing := &flaky{failures: 1}
+	ok := &flaky{}
+	q := NewQueue(Multi{failing, ok}, 3, time.Millisecond)
+	if err := q.Notify(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	q.Close(time.Second)
+	if len(failing.delivered) != 1 {
+		t.Errorf("failing notifier should be retried, got %d delivered", len(failing.delivered))
+	}
+	if ok.calls != 1 {
+		t.Errorf("other notifiers should not be notified again, got %d calls", ok.calls)
+	}
+}
+
+type blocking struct {
+	canceled chan error
+}
+
+func (b *blocking) String() string {
+	return "blocking"
+}
+
+func (b *blocking) Notify(ctx context.Context, message string) error {
+	<-ctx.Done()
+	b.canceled <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestQueueCloseCancel(t *testing.T) {
+	b := &blocking{canceled: make(chan error, 1)}
+	q := NewQueue(b, 3, time.Hour)
+	if err := q.Notify(context.WithValue(context.Background(), MetaContextKey, true), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	q.Close(10 * time.Millisecond)
+	select {
+	case err := <-b.canceled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("closing the queue should cancel the delivering notifier")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/md5" //nolint:gosec
 	"fmt"
-	"log"
 	"os"
 	"strings"
 	"time"
@@ -25,6 +24,17 @@ var (
 	SlackFooterIcon = SlackIconURL
 )
 
+// slackMisconfigured are errors of the Slack API caused by the token or the channel.
+var slackMisconfigured = map[string]bool{
+	"not_authed":        true,
+	"invalid_auth":      true,
+	"account_inactive":  true,
+	"token_revoked":     true,
+	"channel_not_found": true,
+	"not_in_channel":    true,
+	"is_archived":       true,
+}
+
 type key int
 
 // MetaContextKey for context key.
@@ -41,8 +51,7 @@ func (s *Slack) String() string {
 	return "slack"
 }
 
-// Notify posts message to Slack channel.
-func (s *Slack) Notify(ctx context.Context, message string) {
+func (s *Slack) setup() error {
 	if t := os.Getenv("SLACK_TOKEN"); t != "" {
 		s.Token = t
 	}
@@ -53,8 +62,24 @@ func (s *Slack) Notify(ctx context.Context, message string) {
 		s.Channel = defaultSlackChannel
 	}
 	if s.Token == "" {
-		log.Printf("[ERROR] Slack token is required")
-		return
+		return fmt.Errorf("%w: slack token is required", ErrMisconfigured)
+	}
+	return nil
+}
+
+// Check checks the Slack token is valid.
+func (s *Slack) Check(ctx context.Context) error {
+	if err := s.setup(); err != nil {
+		return err
+	}
+	_, err := slack.New(s.Token).Auth().Test().Do(ctx)
+	return err
+}
+
+// Notify posts message to Slack channel.
+func (s *Slack) Notify(ctx context.Context, message string) error {
+	if err := s.setup(); err != nil {
+		return err
 	}
 
 	cl := slack.New(s.Token)
@@ -63,8 +88,12 @@ func (s *Slack) Notify(ctx context.Context, message string) {
 	_, err := cl.Chat().PostMessage(s.Channel).Username(SlackUsername).
 		IconURL(SlackIconURL).Attachment(&at).Text("").Do(ctx)
 	if err != nil {
-		log.Printf("[ERROR] Slack postMessage failure: %#v", err)
+		if slackMisconfigured[err.Error()] {
+			return fmt.Errorf("slack postMessage failure: %w: %s", ErrMisconfigured, err)
+		}
+		return fmt.Errorf("slack postMessage failure: %w", err)
 	}
+	return nil
 }
 
 func (s *Slack) genColor() string {
@@ -85,12 +114,9 @@ func (s *Slack) buildAttachment(message string, meta bool) objects.Attachment {
 		at.Footer = SlackFooter
 		at.FooterIcon = SlackFooterIcon
 		at.Timestamp = objects.Timestamp(time.Now().Unix())
-		at.Fields.
-			Append(&objects.AttachmentField{Title: "Command", Value: s.Meta.Command, Short: true}).
-			Append(&objects.AttachmentField{Title: "Host", Value: hostname(), Short: true}).
-			Append(&objects.AttachmentField{Title: "User", Value: username(), Short: true}).
-			Append(&objects.AttachmentField{Title: "Source", Value: s.Meta.Source, Short: true}).
-			Append(&objects.AttachmentField{Title: "Working directory", Value: cwd(), Short: false})
+		for _, f := range s.Meta.Fields() {
+			at.Fields.Append(&objects.AttachmentField{Title: f.Title, Value: f.Value, Short: f.Short})
+		}
 	} else {
 		at.Text = fmt.Sprintf("%s of <%s|%s> on %s", message, s.Meta.RepoLink, s.Meta.Repo, hostname())
 	}
@@ -0,0 +1,118 @@
+package notice
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// Events for message templates.
+const (
+	// EventStart is notified when Dewy starts.
+	EventStart = "start"
+	// EventStop is notified when Dewy stops.
+	EventStop = "stop"
+	// EventDetect is notified when a new artifact is detected.
+	EventDetect = "detect"
+	// EventServerStart is notified when the server starts.
+	EventServerStart = "server-start"
+	// EventServerRestart is notified when the server restarts.
+	EventServerRestart = "server-restart"
+	// EventServerStartFailure is notified when the server fails to start.
+	EventServerStartFailure = "server-start-failure"
+	// EventServerRestartFailure is notified when the server fails to restart.
+	EventServerRestartFailure = "server-restart-failure"
+	// EventDeployed is notified when the artifact is deployed, with the diff of files if enabled.
+	EventDeployed = "deployed"
+	// EventQuarantine is notified when the artifact is quarantined after consecutive failures.
+	EventQuarantine = "quarantine"
+	// EventDrain is notified when a new artifact is detected but the deploy is deferred by the drain file.
+	EventDrain = "drain"
+	// EventDiskFull is notified when the disk fills up while extracting the artifact.
+	EventDiskFull = "disk-full"
+	// EventUnhealthy is notified when the server fails health checks repeatedly after deploy.
+	EventUnhealthy = "unhealthy"
+	// EventVerifyFailure is notified when the downloaded artifact fails verification and is not deployed.
+	EventVerifyFailure = "verify-failure"
+)
+
+// DefaultTemplates are message templates used when no template is configured.
+var DefaultTemplates = map[string]string{
+	EventStart:                "Automatic shipping started by Dewy",
+	EventStop:                 `{{if .Signal}}Stop receiving "{{.Signal}}" signal{{else}}Stop: {{.Error}}{{end}}`,
+	EventDetect:               "New shipping <{{.URL}}|{{.Tag}}> was detected",
+	EventServerStart:          "Server starting",
+	EventServerRestart:        "Server restarting",
+	EventServerStartFailure:   "Server failed to start with {{.Tag}}: {{.Error}}",
+	EventServerRestartFailure: "Server failed to restart with {{.Tag}} and rolled back: {{.Error}}",
+	EventDeployed:             "Deployed <{{.URL}}|{{.Tag}}>{{if .Diff}}: {{.Diff}}{{end}}",
+	EventQuarantine:           "Shipping {{.Tag}} is quarantined, remove quarantine.txt in the cache to retry: {{.Error}}",
+	EventDrain:                "New shipping <{{.URL}}|{{.Tag}}> is pending while draining",
+	EventDiskFull:             ":rotating_light: Disk is full on {{.Host}}: {{.Disk}}, shipping {{.Tag}} failed and the current release is kept",
+	EventUnhealthy:            "Server became unhealthy with {{.Tag}} after deploy: {{.Error}}",
+	EventVerifyFailure:        ":rotating_light: Shipping <{{.URL}}|{{.Tag}}> failed verification and is not deployed: {{.Error}}",
+}
+
+// Message is the data for message templates.
+type Message struct {
+	// Tag is the tag of the artifact.
+	Tag string
+	// URL is the URL of the artifact.
+	URL string
+	// Host is the hostname.
+	Host string
+	// User is the username running Dewy.
+	User string
+	// Duration is the elapsed time of the current deploy.
+	Duration time.Duration
+	// Signal is the received signal.
+	Signal string
+	// Error is the error message of the failure.
+	Error string
+	// Diff is the summary of changed files from the previous release.
+	Diff string
+	// Disk is the directory and the free space of the filesystem that filled up.
+	Disk string
+}
+
+// ValidateTemplates validates message templates.
+func ValidateTemplates(templates map[string]string) error {
+	for event, text := range templates {
+		if _, ok := DefaultTemplates[event]; !ok {
+			return fmt.Errorf("unknown notice event: %s", event)
+		}
+		if _, err := template.New(event).Parse(text); err != nil {
+			return fmt.Errorf("invalid notice template for %s: %w", event, err)
+		}
+	}
+	return nil
+}
+
+// Render renders the message of the event, falling back to the default template.
+func Render(templates map[string]string, event string, m Message) (string, error) {
+	text, ok := templates[event]
+	if !ok {
+		text, ok = DefaultTemplates[event]
+		if !ok {
+			return "", fmt.Errorf("unknown notice event: %s", event)
+		}
+	}
+	if m.Host == "" {
+		m.Host = hostname()
+	}
+	if m.User == "" {
+		m.User = username()
+	}
+
+	tmpl, err := template.New(event).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, m); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
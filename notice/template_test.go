@@ -0,0 +1,59 @@
+package notice
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	m := Message{Tag: "v1.0.0", URL: "https://example.com/app.tar.gz", Host: "web1", User: "deploy", Duration: 3 * time.Second}
+	tests := []struct {
+		name      string
+		templates map[string]string
+		event     string
+		want      string
+		wantErr   bool
+	}{
+		{"default", nil, EventDetect, "New shipping <https://example.com/app.tar.gz|v1.0.0> was detected", false},
+		{"custom", map[string]string{EventDetect: ":rocket: {{.Tag}} on {{.Host}} by {{.User}} in {{.Duration}}"}, EventDetect, ":rocket: v1.0.0 on web1 by deploy in 3s", false},
+		{"fallback for other events", map[string]string{EventDetect: "{{.Tag}}"}, EventServerStart, "Server starting", false},
+		{"unknown event", nil, "unknown", "", true},
+		{"unknown field", map[string]string{EventDetect: "{{.Version}}"}, EventDetect, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.templates, tt.event, m)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates map[string]string
+		wantErr   bool
+	}{
+		{"valid", map[string]string{EventDetect: "{{.Tag}}", EventStop: "{{.Signal}}"}, false},
+		{"unknown event", map[string]string{"deploy": "{{.Tag}}"}, true},
+		{"invalid syntax", map[string]string{EventDetect: "{{.Tag"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateTemplates(tt.templates); (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
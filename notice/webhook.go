@@ -0,0 +1,65 @@
+package notice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/linyows/dewy/httputil"
+)
+
+// Webhook posts messages as JSON to the URL.
+type Webhook struct {
+	URL  string
+	Meta *Config
+	// Header is added to requests, such as for authentication.
+	Header http.Header
+}
+
+func (w *Webhook) String() string {
+	return "webhook"
+}
+
+type webhookPayload struct {
+	Message string  `json:"message"`
+	Fields  []Field `json:"fields"`
+	Host    string  `json:"host"`
+	Link    string  `json:"link,omitempty"`
+	Time    string  `json:"time"`
+	Repo    string  `json:"repo,omitempty"`
+}
+
+// Notify posts the message to the webhook URL.
+func (w *Webhook) Notify(ctx context.Context, message string) error {
+	p := webhookPayload{Message: message, Fields: []Field{}, Host: hostname(), Time: time.Now().UTC().Format(time.RFC3339)}
+	if w.Meta != nil {
+		p.Repo = w.Meta.Repo
+		p.Link = w.Meta.RepoLink
+		if ctx.Value(MetaContextKey) != nil {
+			p.Fields = w.Meta.Fields()
+		}
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for k, v := range w.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return httputil.NewStatusError("webhook failure", res)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// exportTimeout is the timeout to export a trace.
+const exportTimeout = 10 * time.Second
+
+// Tracer exports traces to an OpenTelemetry collector by OTLP/HTTP in JSON.
+// All methods of nil Tracer and nil Span do nothing.
+type Tracer struct {
+	url      string
+	resource []attribute
+	cl       *http.Client
+}
+
+// New returns Tracer exporting to the endpoint like "http://localhost:4318", with resource attributes.
+func New(endpoint string, attrs map[string]string) (*Tracer, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid otlp endpoint: %s", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	t := &Tracer{url: u.String(), cl: &http.Client{Timeout: exportTimeout}}
+	t.resource = append(t.resource, newAttribute("service.name", "dewy"))
+	for k, v := range attrs {
+		t.resource = append(t.resource, newAttribute(k, v))
+	}
+	return t, nil
+}
+
+// Start starts the root span of a new trace.
+func (t *Tracer) Start(name string) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{tracer: t, traceID: newID(16), spanID: newID(8), name: name, start: time.Now()}
+	s.root = s
+	return s
+}
+
+// Span is an operation in a trace.
+type Span struct {
+	tracer   *Tracer
+	root     *Span
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    []attribute
+	err      error
+
+	// ended spans of the trace, only in the root
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// Start starts the child span.
+func (s *Span) Start(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return &Span{tracer: s.tracer, root: s.root, traceID: s.traceID, spanID: newID(8), parentID: s.spanID, name: name, start: time.Now()}
+}
+
+// SetAttr sets the attribute of string, bool, int, int64 or float64.
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, newAttribute(key, value))
+}
+
+// End ends the span with the error if failed. The trace is exported when the root span ends.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	s.root.mu.Lock()
+	s.root.spans = append(s.root.spans, s)
+	spans := append([]*Span{}, s.root.spans...)
+	s.root.mu.Unlock()
+	if s.root == s {
+		s.tracer.export(spans)
+	}
+}
+
+// Len returns the number of ended spans of the trace.
+func (s *Span) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.root.mu.Lock()
+	defer s.root.mu.Unlock()
+	return len(s.root.spans)
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns the context carrying the span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, s)
+}
+
+// SpanFromContext returns the span in the context, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanContextKey{}).(*Span)
+	return s
+}
+
+func (t *Tracer) export(spans []*Span) {
+	var data []spanData
+	for _, s := range spans {
+		d := spanData{
+			TraceID:           s.traceID,
+			SpanID:            s.spanID,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        s.attrs,
+			Status:            status{Code: statusOK},
+		}
+		if s.err != nil {
+			d.Status = status{Code: statusError, Message: s.err.Error()}
+		}
+		data = append(data, d)
+	}
+	body, err := json.Marshal(request{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: t.resource},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "dewy"}, Spans: data}},
+	}}})
+	if err != nil {
+		log.Printf("[ERROR] OTLP failure: %#v", err)
+		return
+	}
+	res, err := t.cl.Post(t.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[ERROR] OTLP failure: %#v", err)
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		log.Printf("[ERROR] OTLP failure: %s", res.Status)
+	}
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,74 @@
+package otlp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	var got request
+	var path string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	tr, err := New(ts.URL, map[string]string{"host.name": "web1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tr.Start("run")
+	child := root.Start("download")
+	child.SetAttr("dewy.size", 1024)
+	child.End(nil)
+	failed := root.Start("extract")
+	failed.End(errors.New("no space"))
+	if root.Len() != 2 {
+		t.Errorf("got %d spans, want 2", root.Len())
+	}
+	root.End(nil)
+
+	if path != "/v1/traces" {
+		t.Errorf("got path %s", path)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	rootData := spans[2]
+	if rootData.Name != "run" || rootData.ParentSpanID != "" || len(rootData.TraceID) != 32 {
+		t.Errorf("unexpected root span: %+v", rootData)
+	}
+	for _, s := range spans[:2] {
+		if s.TraceID != rootData.TraceID || s.ParentSpanID != rootData.SpanID {
+			t.Errorf("span %s is not a child of the root", s.Name)
+		}
+	}
+	if v := spans[0].Attributes[0].Value.IntValue; v == nil || *v != "1024" {
+		t.Errorf("unexpected attribute: %+v", spans[0].Attributes)
+	}
+	if spans[1].Status.Code != statusError || spans[1].Status.Message != "no space" {
+		t.Errorf("unexpected status: %+v", spans[1].Status)
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tr *Tracer
+	s := tr.Start("run")
+	c := s.Start("fetch")
+	c.SetAttr("dewy.tag", "v1.0.0")
+	c.End(nil)
+	s.End(nil)
+	if s.Len() != 0 {
+		t.Error("nil span should have no spans")
+	}
+}
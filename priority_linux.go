@@ -0,0 +1,39 @@
+//go:build linux
+
+package dewy
+
+import (
+	"log"
+	"runtime"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// runLowPriority runs f on a dedicated thread with the nice value and the idle I/O scheduling class,
+// not to starve the running server. The thread is discarded after f, as unprivileged processes
+// cannot raise the priority back. Goroutines started by f, such as of parallel decompression, are not affected.
+func runLowPriority(nice int, idleIO bool, f func() error) error {
+	ch := make(chan error, 1)
+	go func() {
+		// the thread exits with the goroutine without UnlockOSThread
+		runtime.LockOSThread()
+		tid := syscall.Gettid()
+		if nice != 0 {
+			if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice); err != nil {
+				log.Printf("[WARN] Nice failure: %s", err)
+			}
+		}
+		if idleIO {
+			if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprioClassIdle<<ioprioClassShift); errno != 0 {
+				log.Printf("[WARN] I/O priority failure: %s", errno)
+			}
+		}
+		ch <- f()
+	}()
+	return <-ch
+}
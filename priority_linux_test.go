@@ -0,0 +1,35 @@
+//go:build linux
+
+package dewy
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func TestRunLowPriority(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	before, err := syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got int
+	err = runLowPriority(10, true, func() error {
+		var err error
+		// the raw value of getpriority(2) is 20 - nice
+		got, err = syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 20-10 {
+		t.Errorf("got nice %d, want 10", 20-got)
+	}
+	if after, _ := syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid()); after != before {
+		t.Errorf("priority of the caller should be kept, got nice %d", 20-after)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package dewy
+
+import "log"
+
+// runLowPriority runs f, as lowering the priority of a thread is supported only on Linux.
+func runLowPriority(nice int, idleIO bool, f func() error) error {
+	log.Print("[WARN] Priority of extraction is supported only on Linux")
+	return f()
+}
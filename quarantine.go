@@ -0,0 +1,91 @@
+package dewy
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/linyows/dewy/notice"
+)
+
+const (
+	// quarantineKey is the cache key of the quarantined artifact. Removing it clears the quarantine.
+	quarantineKey = "quarantine.txt"
+	// pinKey is the cache key of the artifact deployed by the deploy command until the loop quarantines
+	// the version the registry serves instead.
+	pinKey = "pin.txt"
+)
+
+// isQuarantined reports whether the artifact is quarantined by consecutive failures, a rollback or a deploy.
+func (d *Dewy) isQuarantined(cacheKey string) bool {
+	key, err := d.state.Read(quarantineKey)
+	return err == nil && string(key) == cacheKey
+}
+
+// recordFailure counts consecutive failures of the artifact and quarantines it
+// when the failures reach MaxConsecutiveFailures.
+func (d *Dewy) recordFailure(ctx context.Context, cacheKey string, msg notice.Message, err error) {
+	if d.config.MaxConsecutiveFailures <= 0 {
+		return
+	}
+	d.Lock()
+	if d.failedKey != cacheKey {
+		d.failedKey = cacheKey
+		d.failures = 0
+	}
+	d.failures++
+	failures := d.failures
+	d.Unlock()
+
+	if failures < d.config.MaxConsecutiveFailures {
+		return
+	}
+	if werr := d.state.Write(quarantineKey, []byte(cacheKey)); werr != nil {
+		log.Printf("[ERROR] Quarantine failure: %#v", werr)
+		return
+	}
+	log.Printf("[WARN] %s is quarantined after %d consecutive failures", cacheKey, failures)
+	msg.Error = fmt.Sprintf("%d consecutive failures: %s", failures, err)
+	d.notify(ctx, notice.EventQuarantine, msg)
+}
+
+// pin records the artifact deployed by the deploy command, which is not quarantined anymore.
+func (d *Dewy) pin(cacheKey string) error {
+	if d.isQuarantined(cacheKey) {
+		if err := d.state.Delete(quarantineKey); err != nil {
+			return err
+		}
+	}
+	if err := d.state.Write(pinKey, []byte(cacheKey)); err != nil {
+		return err
+	}
+	log.Printf("[INFO] %s is pinned by the deploy", cacheKey)
+	return nil
+}
+
+// holdPin quarantines the artifact the registry serves if it is not the one pinned by the deploy command,
+// the same as a rollback, so that the loop keeps the pinned one until a different version is released.
+func (d *Dewy) holdPin(cacheKey string) {
+	pinned, err := d.state.Read(pinKey)
+	if err != nil {
+		return
+	}
+	if string(pinned) != cacheKey {
+		if err := d.state.Write(quarantineKey, []byte(cacheKey)); err != nil {
+			log.Printf("[ERROR] Quarantine failure: %#v", err)
+			return
+		}
+		log.Printf("[INFO] %s is quarantined by the deploy of %s", cacheKey, pinned)
+	}
+	if err := d.state.Delete(pinKey); err != nil {
+		log.Printf("[ERROR] Pin delete failure: %#v", err)
+	}
+}
+
+// resetFailures clears consecutive failures after a successful deploy.
+func (d *Dewy) resetFailures() {
+	d.Lock()
+	defer d.Unlock()
+	d.failedKey = ""
+	d.failures = 0
+}
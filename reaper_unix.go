@@ -0,0 +1,68 @@
+//go:build !windows
+
+package dewy
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// reaper reaps exited children including orphans, which are reparented to the init process.
+type reaper struct {
+	mu      sync.Mutex
+	waiters map[int]chan syscall.WaitStatus
+}
+
+// newReaper returns the reaper if Dewy is the init process, such as the entrypoint of a container.
+func newReaper() *reaper {
+	if os.Getpid() != 1 {
+		return nil
+	}
+	r := &reaper{waiters: map[int]chan syscall.WaitStatus{}}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGCHLD)
+	go func() {
+		for range ch {
+			r.reap()
+		}
+	}()
+	return r
+}
+
+// start starts the command and returns the channel receiving its wait status.
+func (r *reaper) start(cmd *exec.Cmd) (<-chan syscall.WaitStatus, error) {
+	// lock not to reap the process before it is registered
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	ch := make(chan syscall.WaitStatus, 1)
+	r.waiters[cmd.Process.Pid] = ch
+	return ch, nil
+}
+
+func (r *reaper) reap() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		var ws syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &ws, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || pid <= 0 {
+			return
+		}
+		if ch, ok := r.waiters[pid]; ok {
+			ch <- ws
+			delete(r.waiters, pid)
+			continue
+		}
+		log.Printf("[DEBUG] Reaped orphan PID %d", pid)
+	}
+}
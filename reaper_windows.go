@@ -0,0 +1,21 @@
+//go:build windows
+
+package dewy
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// reaper is not supported on windows.
+type reaper struct{}
+
+// newReaper returns nil on windows.
+func newReaper() *reaper {
+	return nil
+}
+
+func (r *reaper) start(cmd *exec.Cmd) (<-chan syscall.WaitStatus, error) {
+	return nil, errors.New("reaper is not supported on windows")
+}
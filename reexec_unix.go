@@ -0,0 +1,13 @@
+//go:build !windows
+
+package dewy
+
+import (
+	"os"
+	"syscall"
+)
+
+// execSelf replaces this process with the executable, keeping the arguments and the environment.
+func execSelf(exe string) error {
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package dewy
+
+import "errors"
+
+func execSelf(exe string) error {
+	return errors.New("executing the updated Dewy is not supported on windows")
+}
@@ -0,0 +1,11 @@
+package gcsreg
+
+// Config struct.
+type Config struct {
+	// Bucket is the bucket of artifacts.
+	Bucket string
+	// Prefix is the object prefix under which artifacts are put as <prefix>/<tag>/<artifact>.
+	Prefix string
+	// CredentialsFile is the path of the service account JSON. Application Default Credentials are used if empty.
+	CredentialsFile string
+}
@@ -0,0 +1,172 @@
+package gcsreg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/linyows/dewy/registry"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+const (
+	Scheme      = "gcs"
+	SchemeShort = "gs"
+)
+
+// bucket is the operations of the bucket used by the registry.
+type bucket interface {
+	objects(ctx context.Context, prefix string) ([]*storage.ObjectAttrs, error)
+	put(ctx context.Context, name string, content []byte) error
+}
+
+// GCS is the registry of artifacts put in a Google Cloud Storage bucket as <prefix>/<tag>/<artifact>.
+// The artifact of the latest generation is deployed, and the generation distinguishes the content.
+type GCS struct {
+	bucket string
+	prefix string
+	cl     bucket
+}
+
+var _ registry.Registry = (*GCS)(nil)
+
+// New returns GCS.
+func New(c Config) (*GCS, error) {
+	if c.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required")
+	}
+	var opts []option.ClientOption
+	if c.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(c.CredentialsFile))
+	}
+	cl, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &GCS{
+		bucket: c.Bucket,
+		prefix: strings.Trim(c.Prefix, "/"),
+		cl:     &clientBucket{h: cl.Bucket(c.Bucket)},
+	}, nil
+}
+
+// ParseURL returns Config of the registry URL such as gs://bucket/prefix.
+func ParseURL(urlstr string) (Config, error) {
+	u, err := url.Parse(urlstr)
+	if err != nil {
+		return Config{}, err
+	}
+	if (u.Scheme != Scheme && u.Scheme != SchemeShort) || u.Host == "" {
+		return Config{}, fmt.Errorf("invalid registry: %s", urlstr)
+	}
+	return Config{
+		Bucket: u.Host,
+		Prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+// Current returns the artifact of the latest generation named the artifact name.
+func (g *GCS) Current(req *registry.CurrentRequest) (*registry.CurrentResponse, error) {
+	prefix := ""
+	if g.prefix != "" {
+		prefix = g.prefix + "/"
+	}
+	objs, err := g.cl.objects(context.Background(), prefix)
+	if err != nil {
+		return nil, err
+	}
+	var found *storage.ObjectAttrs
+	for _, o := range objs {
+		tag, name, ok := g.split(o.Name)
+		if !ok || tag == "" || name != req.ArtifactName {
+			continue
+		}
+		if found == nil || o.Generation > found.Generation {
+			found = o
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("artifact not found: %s://%s/%s", SchemeShort, g.bucket, path.Join(g.prefix, "*", req.ArtifactName))
+	}
+
+	tag, _, _ := g.split(found.Name)
+	log.Printf("[DEBUG] Fetched: %s#%d as %s", found.Name, found.Generation, tag)
+
+	return &registry.CurrentResponse{
+		ID:          time.Now().Format(registry.ISO8601),
+		Tag:         tag,
+		ArtifactURL: g.artifactURL(found.Name),
+		PublishedAt: found.Created,
+		Revision:    strconv.FormatInt(found.Generation, 10),
+	}, nil
+}
+
+// split returns the tag and the name of the object as <prefix>/<tag>/<name>.
+func (g *GCS) split(name string) (string, string, bool) {
+	if g.prefix != "" {
+		if !strings.HasPrefix(name, g.prefix+"/") {
+			return "", "", false
+		}
+		name = strings.TrimPrefix(name, g.prefix+"/")
+	}
+	tag, n, ok := strings.Cut(name, "/")
+	if !ok || strings.Contains(n, "/") {
+		return "", "", false
+	}
+	return tag, n, true
+}
+
+// artifactURL returns the URL of the object to download. The credentials are given to the storage,
+// not in the URL to be notified.
+func (g *GCS) artifactURL(name string) string {
+	return fmt.Sprintf("%s://%s/%s", SchemeShort, g.bucket, name)
+}
+
+// Report puts the shipping marker next to the artifact.
+func (g *GCS) Report(req *registry.ReportRequest) error {
+	if req.Err != nil {
+		return req.Err
+	}
+	name, content := registry.ShippingMarker(req)
+
+	return g.cl.put(context.Background(), path.Join(g.prefix, req.Tag, name), content)
+}
+
+// clientBucket is the bucket of the storage client.
+type clientBucket struct {
+	h *storage.BucketHandle
+}
+
+func (b *clientBucket) objects(ctx context.Context, prefix string) ([]*storage.ObjectAttrs, error) {
+	var objs []*storage.ObjectAttrs
+	it := b.h.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		o, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, o)
+	}
+	return objs, nil
+}
+
+func (b *clientBucket) put(ctx context.Context, name string, content []byte) error {
+	w := b.h.Object(name).NewWriter(ctx)
+	w.ContentType = "text/plain"
+	if _, err := w.Write(content); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,139 @@
+package gcsreg
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/linyows/dewy/registry"
+)
+
+type fakeBucket struct {
+	objs   []*storage.ObjectAttrs
+	prefix string
+	puts   map[string]string
+}
+
+func (b *fakeBucket) objects(_ context.Context, prefix string) ([]*storage.ObjectAttrs, error) {
+	b.prefix = prefix
+	var objs []*storage.ObjectAttrs
+	for _, o := range b.objs {
+		if strings.HasPrefix(o.Name, prefix) {
+			objs = append(objs, o)
+		}
+	}
+	return objs, nil
+}
+
+func (b *fakeBucket) put(_ context.Context, name string, content []byte) error {
+	if b.puts == nil {
+		b.puts = map[string]string{}
+	}
+	b.puts[name] = string(content)
+	return nil
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    Config
+		wantErr bool
+	}{
+		{"gs://bucket/myapp", Config{Bucket: "bucket", Prefix: "myapp"}, false},
+		{"gcs://bucket/path/to/myapp/", Config{Bucket: "bucket", Prefix: "path/to/myapp"}, false},
+		{"gs://bucket", Config{Bucket: "bucket"}, false},
+		{"s3://bucket/myapp", Config{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ParseURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		objs    []*storage.ObjectAttrs
+		wantTag string
+		wantRev string
+		wantErr bool
+	}{
+		{
+			"latest generation",
+			[]*storage.ObjectAttrs{
+				{Name: "myapp/v1.0.0/app_linux_amd64.tar.gz", Generation: 100},
+				{Name: "myapp/v1.2.0/app_linux_amd64.tar.gz", Generation: 300},
+				{Name: "myapp/v1.1.0/app_linux_amd64.tar.gz", Generation: 200},
+			},
+			"v1.2.0", "300", false,
+		},
+		{
+			"other artifacts and layouts are skipped",
+			[]*storage.ObjectAttrs{
+				{Name: "myapp/v1.0.0/app_linux_amd64.tar.gz", Generation: 100},
+				{Name: "myapp/v2.0.0/app_darwin_arm64.tar.gz", Generation: 200},
+				{Name: "myapp/app_linux_amd64.tar.gz", Generation: 300},
+				{Name: "myapp/v3.0.0/nested/app_linux_amd64.tar.gz", Generation: 400},
+				{Name: "other/v4.0.0/app_linux_amd64.tar.gz", Generation: 500},
+			},
+			"v1.0.0", "100", false,
+		},
+		{
+			"not found",
+			[]*storage.ObjectAttrs{{Name: "myapp/v1.0.0/app_darwin_arm64.tar.gz", Generation: 100}},
+			"", "", true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &fakeBucket{objs: tt.objs}
+			g := &GCS{bucket: "bucket", prefix: "myapp", cl: b}
+			res, err := g.Current(&registry.CurrentRequest{ArtifactName: "app_linux_amd64.tar.gz"})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.prefix != "myapp/" {
+				t.Errorf("unexpected prefix: %s", b.prefix)
+			}
+			if res.Tag != tt.wantTag || res.Revision != tt.wantRev {
+				t.Errorf("got %s (%s), want %s (%s)", res.Tag, res.Revision, tt.wantTag, tt.wantRev)
+			}
+			if want := "gs://bucket/myapp/" + tt.wantTag + "/app_linux_amd64.tar.gz"; res.ArtifactURL != want {
+				t.Errorf("got %s, want %s", res.ArtifactURL, want)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	b := &fakeBucket{}
+	g := &GCS{bucket: "bucket", prefix: "myapp", cl: b}
+	if err := g.Report(&registry.ReportRequest{Tag: "v1.0.0", Role: "web", DeployerID: "ci"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.puts) != 1 {
+		t.Fatalf("got %d markers, want 1", len(b.puts))
+	}
+	for k, v := range b.puts {
+		if !strings.HasPrefix(k, "myapp/v1.0.0/shipped_to_") || !strings.Contains(k, "_as_web_by_ci_at_") {
+			t.Errorf("unexpected marker: %s", k)
+		}
+		if !strings.HasSuffix(v, "\ndeployer: ci") {
+			t.Errorf("unexpected content: %s", v)
+		}
+	}
+}
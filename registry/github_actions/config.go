@@ -0,0 +1,12 @@
+package ghactions
+
+// Config struct.
+type Config struct {
+	Owner string
+	Repo  string
+	// Branch is the branch whose latest successful build is deployed.
+	Branch string
+	// Workflow is the file name of the workflow building the artifact, such as build.yml.
+	// All workflows of the repository are considered if empty.
+	Workflow string
+}
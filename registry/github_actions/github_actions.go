@@ -0,0 +1,131 @@
+package ghactions
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+	"github.com/k1LoW/go-github-client/v55/factory"
+	"github.com/linyows/dewy/registry"
+	ghactions "github.com/linyows/dewy/storage/github_actions"
+)
+
+const (
+	Scheme = "github_actions"
+	// shortSHALength is the length of the head SHA used as the tag.
+	shortSHALength = 12
+)
+
+// GithubActions is the registry deploying the artifact of the latest successful workflow run on a branch.
+type GithubActions struct {
+	owner    string
+	repo     string
+	branch   string
+	workflow string
+	cl       *github.Client
+}
+
+var _ registry.Registry = (*GithubActions)(nil)
+
+// New returns GithubActions.
+func New(c Config) (*GithubActions, error) {
+	if c.Branch == "" {
+		return nil, fmt.Errorf("branch is required for %s", Scheme)
+	}
+	cl, err := factory.NewGithubClient()
+	if err != nil {
+		return nil, err
+	}
+	return &GithubActions{
+		owner:    c.Owner,
+		repo:     c.Repo,
+		branch:   c.Branch,
+		workflow: c.Workflow,
+		cl:       cl,
+	}, nil
+}
+
+// Current returns the artifact of the latest successful workflow run on the branch.
+func (g *GithubActions) Current(req *registry.CurrentRequest) (*registry.CurrentResponse, error) {
+	ctx := context.Background()
+	run, err := g.latestRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts, _, err := g.cl.Actions.ListWorkflowRunArtifacts(ctx, g.owner, g.repo, run.GetID(), &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, err
+	}
+	a, err := findArtifact(artifacts.Artifacts, req.ArtifactName)
+	if err != nil {
+		return nil, fmt.Errorf("run %d: %w", run.GetID(), err)
+	}
+	log.Printf("[DEBUG] Fetched: %s of run %d at %s", a.GetName(), run.GetID(), run.GetHeadSHA())
+
+	sha := run.GetHeadSHA()
+	if len(sha) > shortSHALength {
+		sha = sha[:shortSHALength]
+	}
+	au := fmt.Sprintf("%s://%s/%s/%s/%d/%s.zip", ghactions.Scheme, g.owner, g.repo, ghactions.Artifacts, a.GetID(), a.GetName())
+
+	return &registry.CurrentResponse{
+		ID:          time.Now().Format(registry.ISO8601),
+		Tag:         sha,
+		ArtifactURL: au,
+		PublishedAt: run.GetUpdatedAt().Time,
+	}, nil
+}
+
+// latestRun returns the latest successful workflow run on the branch.
+func (g *GithubActions) latestRun(ctx context.Context) (*github.WorkflowRun, error) {
+	opt := &github.ListWorkflowRunsOptions{
+		Branch:      g.branch,
+		Status:      "success",
+		ListOptions: github.ListOptions{PerPage: 1},
+	}
+	var (
+		runs *github.WorkflowRuns
+		err  error
+	)
+	if g.workflow != "" {
+		runs, _, err = g.cl.Actions.ListWorkflowRunsByFileName(ctx, g.owner, g.repo, g.workflow, opt)
+	} else {
+		runs, _, err = g.cl.Actions.ListRepositoryWorkflowRuns(ctx, g.owner, g.repo, opt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(runs.WorkflowRuns) == 0 {
+		return nil, fmt.Errorf("%w: no successful workflow run on %s", registry.ErrNotReady, g.branch)
+	}
+	return runs.WorkflowRuns[0], nil
+}
+
+// findArtifact returns the artifact by the name, or the only artifact if the name is empty.
+func findArtifact(artifacts []*github.Artifact, name string) (*github.Artifact, error) {
+	var candidates []*github.Artifact
+	for _, a := range artifacts {
+		if a.GetExpired() {
+			continue
+		}
+		if name != "" && a.GetName() != name {
+			continue
+		}
+		candidates = append(candidates, a)
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("artifact not found: %s", name)
+	case 1:
+		return candidates[0], nil
+	}
+	return nil, fmt.Errorf("artifact not found in %d artifacts, specify the artifact explicitly", len(candidates))
+}
+
+// Report does nothing because workflow runs have no place to record shipping.
+func (g *GithubActions) Report(req *registry.ReportRequest) error {
+	return req.Err
+}
@@ -0,0 +1,68 @@
+package ghactions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v55/github"
+	"github.com/linyows/dewy/registry"
+)
+
+func testGithubActions(t *testing.T, mux *http.ServeMux) *GithubActions {
+	t.Helper()
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	cl := github.NewClient(nil)
+	u, err := url.Parse(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl.BaseURL = u
+	return &GithubActions{owner: "linyows", repo: "dewy", branch: "main", workflow: "build.yml", cl: cl}
+}
+
+func TestCurrent(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/actions/workflows/build.yml/runs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("branch") != "main" || r.URL.Query().Get("status") != "success" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		fmt.Fprintf(w, `{"total_count":1,"workflow_runs":[{"id":10,"head_sha":%q}]}`, sha)
+	})
+	mux.HandleFunc("/repos/linyows/dewy/actions/runs/10/artifacts", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count":2,"artifacts":[{"id":20,"name":"dewy_linux_amd64"},{"id":21,"name":"coverage"}]}`)
+	})
+	g := testGithubActions(t, mux)
+
+	res, err := g.Current(&registry.CurrentRequest{ArtifactName: "dewy_linux_amd64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.Tag, sha[:shortSHALength]; got != want {
+		t.Errorf("tag: got %s, want %s", got, want)
+	}
+	if got, want := res.ArtifactURL, "github_actions://linyows/dewy/artifacts/20/dewy_linux_amd64.zip"; got != want {
+		t.Errorf("artifact url: got %s, want %s", got, want)
+	}
+
+	if _, err := g.Current(&registry.CurrentRequest{}); err == nil {
+		t.Error("ambiguous artifacts should be error")
+	}
+}
+
+func TestCurrentNoRun(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/actions/workflows/build.yml/runs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count":0,"workflow_runs":[]}`)
+	})
+	g := testGithubActions(t, mux)
+
+	if _, err := g.Current(&registry.CurrentRequest{}); !errors.Is(err, registry.ErrNotReady) {
+		t.Errorf("got %v, want ErrNotReady", err)
+	}
+}
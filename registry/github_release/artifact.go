@@ -0,0 +1,99 @@
+package ghrelease
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// DefaultArtifactAliases are other names of OS and architectures used in artifact names,
+// tried when the artifact rendered with Go's names is not found.
+var DefaultArtifactAliases = map[string]string{
+	"amd64":  "x86_64",
+	"arm64":  "aarch64",
+	"386":    "i386",
+	"darwin": "macos",
+}
+
+// ArtifactData is the data to render the artifact name template such as myapp_{{.OS}}_{{.Arch}}.tar.gz.
+type ArtifactData struct {
+	OS   string
+	Arch string
+}
+
+// isArtifactTemplate reports whether the artifact name is a template.
+func isArtifactTemplate(name string) bool {
+	return strings.Contains(name, "{{")
+}
+
+func renderArtifact(tmpl string, data ArtifactData) (string, error) {
+	t, err := template.New("artifact").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid artifact template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid artifact template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// artifactNames returns names of the artifact to match in order of preference,
+// which are rendered with the OS and the architecture, and then with their aliases.
+func (g *GithubRelease) artifactNames(name, goos, arch string) ([]string, error) {
+	if !isArtifactTemplate(name) {
+		return []string{name}, nil
+	}
+	oses := []string{goos}
+	if a, ok := g.alias(goos); ok {
+		oses = append(oses, a)
+	}
+	arches := []string{arch}
+	if a, ok := g.alias(arch); ok {
+		arches = append(arches, a)
+	}
+	var names []string
+	seen := map[string]bool{}
+	for _, o := range oses {
+		for _, a := range arches {
+			n, err := renderArtifact(name, ArtifactData{OS: o, Arch: a})
+			if err != nil {
+				return nil, err
+			}
+			if !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+	}
+	return names, nil
+}
+
+// alias returns the alias of the OS or the architecture, configured ones taking precedence over defaults.
+func (g *GithubRelease) alias(s string) (string, bool) {
+	if a, ok := g.artifactAliases[s]; ok {
+		return a, a != ""
+	}
+	a, ok := DefaultArtifactAliases[s]
+	return a, ok
+}
+
+// matchArtifact returns the asset matching the first of names, by the name or also by the label with MatchLabel.
+func (g *GithubRelease) matchArtifact(assets []*github.ReleaseAsset, names []string) (*github.ReleaseAsset, error) {
+	for _, n := range names {
+		for _, v := range assets {
+			// the label is stable even if the name is hashed
+			if v.GetName() == n || (g.matchLabel && v.GetLabel() == n) {
+				return v, nil
+			}
+		}
+	}
+	var available []string
+	for _, v := range assets {
+		available = append(available, v.GetName())
+	}
+	return nil, fmt.Errorf("artifact not found: %s (available: %s)", strings.Join(names, ", "), strings.Join(available, ", "))
+}
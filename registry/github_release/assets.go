@@ -0,0 +1,28 @@
+package ghrelease
+
+import (
+	"context"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// releaseAssets returns all assets of the release, which are paginated for releases with many assets.
+func (g *GithubRelease) releaseAssets(ctx context.Context, release *github.RepositoryRelease) ([]*github.ReleaseAsset, error) {
+	var all []*github.ReleaseAsset
+	page := 1
+	for {
+		assets, res, err := g.cl.Repositories.ListReleaseAssets(ctx, g.owner, g.repo, release.GetID(), &github.ListOptions{
+			Page:    page,
+			PerPage: 100,
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, assets...)
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+	return all, nil
+}
@@ -0,0 +1,58 @@
+package ghrelease
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/go-github/v55/github"
+	"github.com/linyows/dewy/registry"
+)
+
+// waitChecks checks that all statuses and check runs of the commit of the tag are green.
+func (g *GithubRelease) waitChecks(ctx context.Context, tag string) error {
+	sha, _, err := g.cl.Repositories.GetCommitSHA1(ctx, g.owner, g.repo, tag, "")
+	if err != nil {
+		return err
+	}
+
+	status, _, err := g.cl.Repositories.GetCombinedStatus(ctx, g.owner, g.repo, sha, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return err
+	}
+	// the combined state is pending when there are no statuses
+	if status.GetTotalCount() > 0 {
+		switch status.GetState() {
+		case "success":
+		case "pending":
+			return fmt.Errorf("%w: statuses of %s are pending", registry.ErrNotReady, tag)
+		default:
+			return fmt.Errorf("statuses of %s are %s", tag, status.GetState())
+		}
+	}
+
+	opts := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		runs, res, err := g.cl.Checks.ListCheckRunsForRef(ctx, g.owner, g.repo, sha, opts)
+		if err != nil {
+			return err
+		}
+		for _, r := range runs.CheckRuns {
+			if r.GetStatus() != "completed" {
+				return fmt.Errorf("%w: check %s of %s is %s", registry.ErrNotReady, r.GetName(), tag, r.GetStatus())
+			}
+			switch r.GetConclusion() {
+			case "success", "neutral", "skipped":
+			default:
+				return fmt.Errorf("check %s of %s is %s", r.GetName(), tag, r.GetConclusion())
+			}
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+	log.Printf("[DEBUG] Checks of %s (%s) are green", tag, sha)
+
+	return nil
+}
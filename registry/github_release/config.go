@@ -1,10 +1,25 @@
 package ghrelease
 
+import "time"
+
 // Config struct.
 type Config struct {
 	Owner                 string
 	Repo                  string
 	Artifact              string
 	PreRelease            bool
+	Environment           string
+	MinReleaseAge         time.Duration
+	SourceArchive         bool
+	RequireChecksGreen    bool
+	Tag                   string
+	VersionRegex          string
+	VersionSourceURL      string
+	VersionSourceHeaders  []string
+	MatchLabel            bool
+	RequireSignedTag      bool
+	AllowedSigners        []string
+	SemverConstraint      string
+	ArtifactAliases       map[string]string
 	DisableRecordShipping bool // FIXME: For testing. Remove this.
 }
@@ -0,0 +1,148 @@
+package ghrelease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/go-github/v55/github"
+	"github.com/linyows/dewy/registry"
+)
+
+// deploymentDescription is the description of deployments and statuses by Dewy,
+// which tells them from statuses set by reviewers.
+const deploymentDescription = "Deploy by Dewy"
+
+// ErrDeploymentStopped is returned when the deployment for the tag is rejected or failed,
+// so that the release is not deployed until a different version is released.
+var ErrDeploymentStopped = errors.New("deployment is stopped")
+
+// deployment returns the GitHub deployment for the tag, creating it if it does not exist.
+func (g *GithubRelease) deployment(ctx context.Context, tag string, create bool) (*github.Deployment, error) {
+	ds, _, err := g.cl.Repositories.ListDeployments(ctx, g.owner, g.repo, &github.DeploymentsListOptions{
+		Ref:         tag,
+		Environment: g.environment,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ds) > 0 {
+		return ds[0], nil
+	}
+	if !create {
+		return nil, fmt.Errorf("deployment not found: %s", tag)
+	}
+
+	d, _, err := g.cl.Repositories.CreateDeployment(ctx, g.owner, g.repo, &github.DeploymentRequest{
+		Ref:              github.String(tag),
+		Environment:      github.String(g.environment),
+		AutoMerge:        github.Bool(false),
+		RequiredContexts: &[]string{},
+		Description:      github.String(deploymentDescription),
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Create deployment %d for %s to %s", d.GetID(), tag, g.environment)
+
+	return d, nil
+}
+
+// waitDeployment checks that the deployment for the tag is approved and marks it in progress.
+// If the environment requires reviewers, the deployment is approved by a reviewer marking it in progress or success.
+// The status of the finished deployment is kept, since the release is checked every polling after deployed,
+// while a rejected or failed deployment stops the release.
+func (g *GithubRelease) waitDeployment(ctx context.Context, tag string) error {
+	d, err := g.deployment(ctx, tag, true)
+	if err != nil {
+		return err
+	}
+
+	ss, _, err := g.cl.Repositories.ListDeploymentStatuses(ctx, g.owner, g.repo, d.GetID(), &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return err
+	}
+	if len(ss) > 0 {
+		switch s := ss[0]; s.GetState() {
+		case "failure", "error":
+			return fmt.Errorf("%w: deployment %d to %s is %s: %s", ErrDeploymentStopped, d.GetID(), g.environment, s.GetState(), s.GetDescription())
+		case "waiting", "queued", "pending":
+			return fmt.Errorf("%w: deployment %d to %s is %s", registry.ErrNotReady, d.GetID(), g.environment, s.GetState())
+		}
+	}
+	reviewed, err := g.requiresReviewers(ctx)
+	if err != nil {
+		return err
+	}
+	if reviewed && !approved(ss) {
+		return fmt.Errorf("%w: deployment %d to %s is waiting for approval", registry.ErrNotReady, d.GetID(), g.environment)
+	}
+	if len(ss) > 0 {
+		// in progress, success or inactive
+		return nil
+	}
+
+	return g.updateDeploymentStatus(ctx, d.GetID(), "in_progress")
+}
+
+// requiresReviewers reports whether the protection rules of the environment require reviewers.
+func (g *GithubRelease) requiresReviewers(ctx context.Context) (bool, error) {
+	env, res, err := g.cl.Repositories.GetEnvironment(ctx, g.owner, g.repo, g.environment)
+	if err != nil {
+		// the environment is created by the first deployment without protection rules
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, r := range env.ProtectionRules {
+		if r.GetType() == "required_reviewers" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// approved reports whether statuses include one marking the deployment in progress or success by others than Dewy.
+func approved(ss []*github.DeploymentStatus) bool {
+	for _, s := range ss {
+		if s.GetDescription() == deploymentDescription {
+			continue
+		}
+		if s.GetState() == "in_progress" || s.GetState() == "success" {
+			return true
+		}
+	}
+	return false
+}
+
+// finishDeployment marks the deployment for the tag as success or failure.
+func (g *GithubRelease) finishDeployment(ctx context.Context, tag string, deployErr error) error {
+	d, err := g.deployment(ctx, tag, false)
+	if err != nil {
+		return err
+	}
+	state := "success"
+	if deployErr != nil {
+		state = "failure"
+	}
+
+	return g.updateDeploymentStatus(ctx, d.GetID(), state)
+}
+
+func (g *GithubRelease) updateDeploymentStatus(ctx context.Context, id int64, state string) error {
+	_, _, err := g.cl.Repositories.CreateDeploymentStatus(ctx, g.owner, g.repo, id, &github.DeploymentStatusRequest{
+		State:       github.String(state),
+		Environment: github.String(g.environment),
+		Description: github.String(deploymentDescription),
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] Update deployment %d status to %s", id, state)
+
+	return nil
+}
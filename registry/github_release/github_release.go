@@ -1,34 +1,55 @@
 package ghrelease
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"net/url"
-	"os"
+	"path"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-github/v55/github"
 	"github.com/google/go-querystring/query"
 	"github.com/k1LoW/go-github-client/v55/factory"
+	"github.com/linyows/dewy/httputil"
 	"github.com/linyows/dewy/registry"
 	ghrelease "github.com/linyows/dewy/storage/github_release"
 )
 
 const (
 	// ISO8601 for time format.
-	ISO8601 = "20060102T150405Z0700"
+	ISO8601 = registry.ISO8601
 	Scheme  = "github_release"
 )
 
 // GithubRelease struct.
 type GithubRelease struct {
-	owner      string
-	repo       string
-	prerelease bool
-	cl         *github.Client
+	owner         string
+	repo          string
+	prerelease    bool
+	environment   string
+	minReleaseAge time.Duration
+	sourceArchive bool
+	requireChecks bool
+	matchLabel    bool
+	tag           string
+	versionRegex  *regexp.Regexp
+	versioned     bool
+	lastUpdatedAt map[string]time.Time
+	mu            sync.Mutex
+	cl            *github.Client
+
+	versionSourceURL    string
+	versionSourceHeader http.Header
+	requireSignedTag    bool
+	allowedSigners      []string
+	retryInterval       time.Duration
+	semverConstraint    *semverConstraint
+	artifactAliases     map[string]string
 }
 
 var _ registry.Registry = (*GithubRelease)(nil)
@@ -40,10 +61,45 @@ func New(c Config) (*GithubRelease, error) {
 		return nil, err
 	}
 	g := &GithubRelease{
-		owner:      c.Owner,
-		repo:       c.Repo,
-		prerelease: c.PreRelease,
-		cl:         cl,
+		owner:         c.Owner,
+		repo:          c.Repo,
+		prerelease:    c.PreRelease,
+		environment:   c.Environment,
+		minReleaseAge: c.MinReleaseAge,
+		sourceArchive: c.SourceArchive,
+		requireChecks: c.RequireChecksGreen,
+		matchLabel:    c.MatchLabel,
+		tag:           c.Tag,
+		cl:            cl,
+
+		requireSignedTag: c.RequireSignedTag,
+		allowedSigners:   c.AllowedSigners,
+		retryInterval:    shippingRetryInterval,
+		artifactAliases:  c.ArtifactAliases,
+	}
+	if c.VersionRegex != "" {
+		g.versioned = true
+		if g.versionRegex, err = compileVersionRegex(c.VersionRegex); err != nil {
+			return nil, err
+		}
+	}
+	if c.SemverConstraint != "" {
+		if g.versionRegex != nil {
+			return nil, fmt.Errorf("version regex and semver constraint cannot be used together")
+		}
+		if g.semverConstraint, err = parseSemverConstraint(c.SemverConstraint); err != nil {
+			return nil, err
+		}
+		g.versioned = true
+	}
+	if c.VersionSourceURL != "" {
+		if c.Tag != "" {
+			return nil, fmt.Errorf("tag and version source cannot be used together")
+		}
+		g.versionSourceURL = c.VersionSourceURL
+		if g.versionSourceHeader, err = httputil.ParseHeaders(c.VersionSourceHeaders); err != nil {
+			return nil, err
+		}
 	}
 	return g, nil
 }
@@ -86,6 +142,15 @@ func (g *GithubRelease) URL() string {
 	return fmt.Sprintf("%s/%s", g.OwnerURL(), g.repo)
 }
 
+// Ping checks the repository is reachable and returns the OAuth scopes of the token.
+func (g *GithubRelease) Ping(ctx context.Context) (string, error) {
+	_, res, err := g.cl.Repositories.Get(ctx, g.owner, g.repo)
+	if err != nil {
+		return "", err
+	}
+	return res.Header.Get("X-OAuth-Scopes"), nil
+}
+
 // Current returns current artifact.
 func (g *GithubRelease) Current(req *registry.CurrentRequest) (*registry.CurrentResponse, error) {
 	release, err := g.latest()
@@ -94,83 +159,185 @@ func (g *GithubRelease) Current(req *registry.CurrentRequest) (*registry.Current
 	}
 	var artifactName string
 
+	if g.requireSignedTag && !req.DryRun {
+		if err := g.verifySignedTag(context.Background(), release.GetTagName()); err != nil {
+			return nil, err
+		}
+	}
+
+	if g.requireChecks && !req.DryRun {
+		if err := g.waitChecks(context.Background(), release.GetTagName()); err != nil {
+			return nil, err
+		}
+	}
+
+	if g.sourceArchive {
+		return g.sourceArchiveResponse(release, req.DryRun)
+	}
+
+	assets, err := g.releaseAssets(context.Background(), release)
+	if err != nil {
+		return nil, err
+	}
+
 	if req.ArtifactName != "" {
-		artifactName = req.ArtifactName
-		found := false
-		for _, v := range release.Assets {
-			if v.GetName() == artifactName {
-				found = true
-				log.Printf("[DEBUG] Fetched: %+v", v)
-				break
-			}
+		names, err := g.artifactNames(req.ArtifactName, req.OS, req.Arch)
+		if err != nil {
+			return nil, err
 		}
-		if !found {
-			return nil, fmt.Errorf("artifact not found: %s", artifactName)
+		v, err := g.matchArtifact(assets, names)
+		if err != nil {
+			return nil, err
 		}
+		artifactName = v.GetName()
+		log.Printf("[DEBUG] Fetched: %+v", v)
 	} else {
-		archMatchs := []string{req.Arch}
-		if req.Arch == "amd64" {
-			archMatchs = append(archMatchs, "x86_64")
-		}
-		osMatchs := []string{req.OS}
-		if req.OS == "darwin" {
-			osMatchs = append(osMatchs, "macos")
-		}
-		found := false
-		for _, v := range release.Assets {
-			n := strings.ToLower(v.GetName())
-			for _, arch := range archMatchs {
-				if strings.Contains(n, arch) {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-			found = false
-			for _, os := range osMatchs {
-				if strings.Contains(n, os) {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
-			}
-			artifactName = v.GetName()
-			log.Printf("[DEBUG] Fetched: %+v", v)
-			break
+		artifactName, err = findArtifact(assets, req.Arch, req.OS)
+		if err != nil {
+			return nil, err
 		}
-		if !found {
-			return nil, fmt.Errorf("artifact not found: %s", artifactName)
+	}
+
+	if g.environment != "" && !req.DryRun {
+		if err := g.waitDeployment(context.Background(), release.GetTagName()); err != nil {
+			return nil, err
 		}
 	}
 
 	au := fmt.Sprintf("%s://%s/%s/tag/%s/%s", ghrelease.Scheme, g.owner, g.repo, release.GetTagName(), artifactName)
 
-	return &registry.CurrentResponse{
+	res := &registry.CurrentResponse{
 		ID:          time.Now().Format(ISO8601),
 		Tag:         release.GetTagName(),
 		ArtifactURL: au,
-	}, nil
+		PublishedAt: release.GetPublishedAt().Time,
+	}
+	// the asset may be uploaded again to the release, or the tag may be repointed to new assets,
+	// so detect the change by the asset
+	for _, v := range assets {
+		if v.GetName() == artifactName {
+			res.Revision = g.assetRevision(v)
+			break
+		}
+	}
+
+	return res, nil
+}
+
+// CacheKey keys the artifact by the tag and the asset ID, so that the asset uploaded again to the same release is deployed.
+func (g *GithubRelease) CacheKey(res *registry.CurrentResponse) string {
+	name := path.Base(res.ArtifactURL)
+	if res.Revision == "" {
+		return fmt.Sprintf("%s-%s", res.Tag, name)
+	}
+	return fmt.Sprintf("%s-%s-%s", res.Tag, res.Revision, name)
+}
+
+// assetRevision returns the revision of the asset by its ID, which changes whenever the asset is uploaded again.
+// The update time is used only if the ID is unknown, since the clock of either side may be wrong.
+func (g *GithubRelease) assetRevision(a *github.ReleaseAsset) string {
+	updatedAt := a.GetUpdatedAt().Time
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.lastUpdatedAt == nil {
+		g.lastUpdatedAt = map[string]time.Time{}
+	}
+	if last, ok := g.lastUpdatedAt[a.GetName()]; ok && updatedAt.Before(last) {
+		log.Printf("[WARN] updated_at of %s moved backward from %s to %s, check the clock", a.GetName(), last.Format(time.RFC3339), updatedAt.Format(time.RFC3339))
+	}
+	g.lastUpdatedAt[a.GetName()] = updatedAt
+
+	if a.GetID() != 0 {
+		return fmt.Sprintf("%d", a.GetID())
+	}
+	return fmt.Sprintf("%d", updatedAt.Unix())
+}
+
+// sourceArchiveResponse returns the source tarball of the release as the artifact.
+func (g *GithubRelease) sourceArchiveResponse(release *github.RepositoryRelease, dryRun bool) (*registry.CurrentResponse, error) {
+	tag := release.GetTagName()
+	if g.environment != "" && !dryRun {
+		if err := g.waitDeployment(context.Background(), tag); err != nil {
+			return nil, err
+		}
+	}
+	name := fmt.Sprintf("%s-%s.tar.gz", g.repo, strings.TrimPrefix(tag, "v"))
+	au := fmt.Sprintf("%s://%s/%s/%s/%s/%s", ghrelease.Scheme, g.owner, g.repo, ghrelease.SourceArchive, tag, name)
+
+	res := &registry.CurrentResponse{
+		ID:          time.Now().Format(ISO8601),
+		Tag:         tag,
+		ArtifactURL: au,
+		PublishedAt: release.GetPublishedAt().Time,
+	}
+	if g.tag != "" {
+		// the moving tag is detected by the commit it points to
+		sha, _, err := g.cl.Repositories.GetCommitSHA1(context.Background(), g.owner, g.repo, tag, "")
+		if err != nil {
+			return nil, err
+		}
+		res.Revision = sha
+	}
+
+	return res, nil
 }
 
 func (g *GithubRelease) latest() (*github.RepositoryRelease, error) {
 	ctx := context.Background()
-	var r *github.RepositoryRelease
+	tag := g.tag
+	if g.versionSourceURL != "" {
+		var err error
+		if tag, err = g.desiredTag(ctx); err != nil {
+			return nil, err
+		}
+		log.Printf("[DEBUG] Desired tag is %s", tag)
+	}
+	if tag != "" {
+		r, _, err := g.cl.Repositories.GetReleaseByTag(ctx, g.owner, g.repo, tag)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+	// listing all releases is only for the version ordering, as the latest release is a single request
+	if g.versioned {
+		return g.highest(ctx)
+	}
+	if g.minReleaseAge > 0 {
+		return g.agedLatest(ctx)
+	}
 	if g.prerelease {
-		opt := &github.ListOptions{Page: 1}
-		rr, _, err := g.cl.Repositories.ListReleases(ctx, g.owner, g.repo, opt)
+		return g.latestWithPrerelease(ctx)
+	}
+	r, _, err := g.cl.Repositories.GetLatestRelease(ctx, g.owner, g.repo)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// latestWithPrerelease returns the newest release including prereleases, skipping drafts.
+// The latest release is returned if there are only drafts.
+func (g *GithubRelease) latestWithPrerelease(ctx context.Context) (*github.RepositoryRelease, error) {
+	page := 1
+	for {
+		releases, res, err := g.cl.Repositories.ListReleases(ctx, g.owner, g.repo, &github.ListOptions{
+			Page:    page,
+			PerPage: 100,
+		})
 		if err != nil {
 			return nil, err
 		}
-		for _, v := range rr {
-			if *v.Draft {
+		for _, v := range releases {
+			if v.GetDraft() {
 				continue
 			}
-			return r, nil
+			return v, nil
+		}
+		if res.NextPage == 0 {
+			break
 		}
+		page = res.NextPage
 	}
 	r, _, err := g.cl.Repositories.GetLatestRelease(ctx, g.owner, g.repo)
 	if err != nil {
@@ -179,15 +346,48 @@ func (g *GithubRelease) latest() (*github.RepositoryRelease, error) {
 	return r, nil
 }
 
+// agedLatest returns the latest release published before the minimum release age.
+func (g *GithubRelease) agedLatest(ctx context.Context) (*github.RepositoryRelease, error) {
+	page := 1
+	for {
+		releases, res, err := g.cl.Repositories.ListReleases(ctx, g.owner, g.repo, &github.ListOptions{
+			Page:    page,
+			PerPage: 100,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range releases {
+			if v.GetDraft() || (v.GetPrerelease() && !g.prerelease) {
+				continue
+			}
+			if age := time.Since(v.GetPublishedAt().Time); age < g.minReleaseAge {
+				log.Printf("[DEBUG] Skip %s published %s ago", v.GetTagName(), age.Truncate(time.Second))
+				continue
+			}
+			return v, nil
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+
+	return nil, fmt.Errorf("%w: no release older than %s", registry.ErrNotReady, g.minReleaseAge)
+}
+
 // Report report shipping.
 func (g *GithubRelease) Report(req *registry.ReportRequest) error {
+	ctx := context.Background()
+	if g.environment != "" {
+		if err := g.finishDeployment(ctx, req.Tag, req.Err); err != nil {
+			log.Printf("[ERROR] Deployment status failure: %#v", err)
+		}
+	}
 	if req.Err != nil {
 		return req.Err
 	}
-	ctx := context.Background()
-	now := time.Now().UTC().Format(ISO8601)
-	hostname, _ := os.Hostname()
-	info := fmt.Sprintf("shipped to %s at %s", strings.ToLower(hostname), now)
+	name, content := registry.ShippingMarker(req)
 
 	page := 1
 	for {
@@ -201,7 +401,7 @@ func (g *GithubRelease) Report(req *registry.ReportRequest) error {
 		for _, r := range releases {
 			if r.GetTagName() == req.Tag {
 				s := fmt.Sprintf("repos/%s/%s/releases/%d/assets", g.owner, g.repo, r.GetID())
-				opt := &github.UploadOptions{Name: strings.Replace(info, " ", "_", -1) + ".txt"}
+				opt := &github.UploadOptions{Name: name}
 
 				u, err := url.Parse(s)
 				if err != nil {
@@ -212,18 +412,7 @@ func (g *GithubRelease) Report(req *registry.ReportRequest) error {
 					return err
 				}
 				u.RawQuery = qs.Encode()
-				b := []byte(info)
-				r := bytes.NewReader(b)
-				req, err := g.cl.NewUploadRequest(u.String(), r, int64(len(b)), "text/plain")
-				if err != nil {
-					return err
-				}
-
-				asset := new(github.ReleaseAsset)
-				if _, err := g.cl.Do(ctx, req, asset); err != nil {
-					return err
-				}
-				return nil
+				return g.uploadShipping(ctx, u.String(), content)
 			}
 		}
 		if res.NextPage == 0 {
@@ -234,3 +423,63 @@ func (g *GithubRelease) Report(req *registry.ReportRequest) error {
 
 	return fmt.Errorf("release not found: %s", req.Tag)
 }
+
+// companionSuffixes are suffixes of files accompanying artifacts such as checksums and signatures.
+var companionSuffixes = []string{".sha1", ".sha256", ".sha512", ".md5", ".asc", ".sig", ".minisig", ".pem", ".crt", "checksums.txt", "sha256sums"}
+
+// isCompanion reports whether the asset is a checksum or signature file rather than an artifact.
+func isCompanion(name string) bool {
+	n := strings.ToLower(name)
+	for _, s := range companionSuffixes {
+		if strings.HasSuffix(n, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// findArtifact returns the only asset except companions, or the asset matching the OS and architecture.
+// Companions are never selected since they often contain the artifact name, such as app_linux_amd64.tar.gz.sha256.
+func findArtifact(assets []*github.ReleaseAsset, arch, goos string) (string, error) {
+	var candidates []*github.ReleaseAsset
+	for _, v := range assets {
+		if !isCompanion(v.GetName()) {
+			candidates = append(candidates, v)
+		}
+	}
+	if len(candidates) == 1 {
+		log.Printf("[DEBUG] Fetched: %+v", candidates[0])
+		return candidates[0].GetName(), nil
+	}
+
+	archMatchs := []string{arch}
+	if arch == "amd64" {
+		archMatchs = append(archMatchs, "x86_64")
+	}
+	osMatchs := []string{goos}
+	if goos == "darwin" {
+		osMatchs = append(osMatchs, "macos")
+	}
+	for _, v := range candidates {
+		n := strings.ToLower(v.GetName())
+		if !containsAny(n, archMatchs) || !containsAny(n, osMatchs) {
+			continue
+		}
+		log.Printf("[DEBUG] Fetched: %+v", v)
+		return v.GetName(), nil
+	}
+
+	if len(candidates) > 1 {
+		return "", fmt.Errorf("artifact not found for %s/%s in %d assets, specify the artifact explicitly", goos, arch, len(candidates))
+	}
+	return "", fmt.Errorf("artifact not found")
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,891 @@
+package ghrelease
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+	"github.com/linyows/dewy/httputil"
+	"github.com/linyows/dewy/registry"
+)
+
+func assets(names ...string) []*github.ReleaseAsset {
+	var a []*github.ReleaseAsset
+	for _, n := range names {
+		a = append(a, &github.ReleaseAsset{Name: github.String(n)})
+	}
+	return a
+}
+
+func TestFindArtifact(t *testing.T) {
+	tests := []struct {
+		name    string
+		assets  []*github.ReleaseAsset
+		want    string
+		wantErr bool
+	}{
+		{"single asset", assets("app.tar.gz"), "app.tar.gz", false},
+		{"single asset with companions", assets("app.tar.gz", "app.tar.gz.sha256", "checksums.txt", "app.tar.gz.sig"), "app.tar.gz", false},
+		{"match os and arch", assets("app_darwin_arm64.tar.gz", "app_linux_amd64.tar.gz"), "app_linux_amd64.tar.gz", false},
+		{"match x86_64", assets("app_darwin_x86_64.tar.gz", "app_linux_x86_64.tar.gz"), "app_linux_x86_64.tar.gz", false},
+		{"match with companions", assets("app_linux_amd64.tar.gz.sha256", "app_linux_amd64.tar.gz.sig", "app_darwin_arm64.tar.gz", "app_linux_amd64.tar.gz", "SHA256SUMS"), "app_linux_amd64.tar.gz", false},
+		{"only companions", assets("app_linux_amd64.tar.gz.sha256", "app_linux_amd64.tar.gz.asc"), "", true},
+		{"multiple assets", assets("app.tar.gz", "app.zip"), "", true},
+		{"no assets", assets(), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findArtifact(tt.assets, "amd64", "linux")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func testGithubRelease(t *testing.T, mux *http.ServeMux) *GithubRelease {
+	t.Helper()
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	cl := github.NewClient(nil)
+	u, err := url.Parse(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl.BaseURL = u
+	return &GithubRelease{owner: "linyows", repo: "dewy", cl: cl}
+}
+
+func TestWaitChecks(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       string
+		runs         string
+		wantErr      bool
+		wantNotReady bool
+	}{
+		{"green", `{"state":"success","total_count":1}`, `{"total_count":1,"check_runs":[{"name":"test","status":"completed","conclusion":"success"}]}`, false, false},
+		{"no statuses", `{"state":"pending","total_count":0}`, `{"total_count":0,"check_runs":[]}`, false, false},
+		{"pending status", `{"state":"pending","total_count":1}`, `{"total_count":0,"check_runs":[]}`, true, true},
+		{"failed status", `{"state":"failure","total_count":1}`, `{"total_count":0,"check_runs":[]}`, true, false},
+		{"running check", `{"state":"success","total_count":1}`, `{"total_count":1,"check_runs":[{"name":"test","status":"in_progress"}]}`, true, true},
+		{"failed check", `{"state":"success","total_count":1}`, `{"total_count":1,"check_runs":[{"name":"test","status":"completed","conclusion":"failure"}]}`, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy/commits/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "abc123")
+			})
+			mux.HandleFunc("/repos/linyows/dewy/commits/abc123/status", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.status)
+			})
+			mux.HandleFunc("/repos/linyows/dewy/commits/abc123/check-runs", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.runs)
+			})
+			g := testGithubRelease(t, mux)
+			err := g.waitChecks(context.Background(), "v1.0.0")
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if errors.Is(err, registry.ErrNotReady) != tt.wantNotReady {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestShippedHosts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/releases/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"tag_name":"v1.0.0"}`)
+	})
+	mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"name":"dewy_linux_amd64.tar.gz"},
+			{"name":"shipped_to_web1_at_20240101T000000Z.txt"},
+			{"name":"shipped_to_worker1_as_worker_at_20240101T000000Z.txt"},
+			{"name":"shipped_to_batch1_as_worker_by_team-data_at_20240101T000000Z.txt"},
+			{"name":"shipped_to_ci1_by_pipeline_at_20240101T000000Z.txt"}
+		]`)
+	})
+	g := testGithubRelease(t, mux)
+	got, err := g.ShippedHosts("v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"web1", "worker1", "batch1", "ci1"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCurrentMovingTag(t *testing.T) {
+	updatedAt := "2024-01-01T00:00:00Z"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/releases/tags/stable", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"tag_name":"stable"}`)
+	})
+	mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"name":"dewy_linux_amd64.tar.gz","updated_at":%q}]`, updatedAt)
+	})
+	g := testGithubRelease(t, mux)
+	g.tag = "stable"
+
+	req := &registry.CurrentRequest{ArtifactName: "dewy_linux_amd64.tar.gz"}
+	before, err := g.Current(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Tag != "stable" || before.Revision == "" {
+		t.Errorf("unexpected response: %+v", before)
+	}
+
+	updatedAt = "2024-02-01T00:00:00Z"
+	after, err := g.Current(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Revision == after.Revision {
+		t.Error("revision should change when the tag is moved")
+	}
+}
+
+func TestLatestPrerelease(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages []string
+		want  string
+	}{
+		{
+			name:  "newest prerelease",
+			pages: []string{`[{"tag_name":"v1.1.0-rc1","prerelease":true},{"tag_name":"v1.0.0"}]`},
+			want:  "v1.1.0-rc1",
+		},
+		{
+			name:  "skip drafts",
+			pages: []string{`[{"tag_name":"v1.2.0","draft":true},{"tag_name":"v1.1.0-rc1","prerelease":true}]`},
+			want:  "v1.1.0-rc1",
+		},
+		{
+			name: "first page of drafts",
+			pages: []string{
+				`[{"tag_name":"v1.2.0","draft":true}]`,
+				`[{"tag_name":"v1.1.0"}]`,
+			},
+			want: "v1.1.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy/releases", func(w http.ResponseWriter, r *http.Request) {
+				page := 1
+				fmt.Sscan(r.URL.Query().Get("page"), &page)
+				if page < len(tt.pages) {
+					w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d>; rel="next"`, r.URL.Path, page+1))
+				}
+				fmt.Fprint(w, tt.pages[page-1])
+			})
+			mux.HandleFunc("/repos/linyows/dewy/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"tag_name":"v1.0.0"}`)
+			})
+			g := testGithubRelease(t, mux)
+			g.prerelease = true
+
+			r, err := g.latest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.GetTagName(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAgedLatest(t *testing.T) {
+	now := time.Now()
+	published := func(d time.Duration) string { return now.Add(-d).UTC().Format(time.RFC3339) }
+	tests := []struct {
+		name     string
+		releases string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "skip young releases",
+			releases: fmt.Sprintf(`[{"tag_name":"v1.2.0","published_at":%q},{"tag_name":"v1.1.0","published_at":%q}]`, published(time.Minute), published(48*time.Hour)),
+			want:     "v1.1.0",
+		},
+		{
+			name:     "skip drafts and prereleases",
+			releases: fmt.Sprintf(`[{"tag_name":"v1.3.0","draft":true,"published_at":%q},{"tag_name":"v1.2.0-rc1","prerelease":true,"published_at":%q},{"tag_name":"v1.1.0","published_at":%q}]`, published(48*time.Hour), published(48*time.Hour), published(48*time.Hour)),
+			want:     "v1.1.0",
+		},
+		{
+			name:     "no aged release",
+			releases: fmt.Sprintf(`[{"tag_name":"v1.2.0","published_at":%q}]`, published(time.Minute)),
+			wantErr:  registry.ErrNotReady,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy/releases", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.releases)
+			})
+			g := testGithubRelease(t, mux)
+			g.minReleaseAge = time.Hour
+
+			r, err := g.latest()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.GetTagName(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrentSourceArchive(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"tag_name":"v1.2.3"}`)
+	})
+	mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("assets should not be listed for the source archive")
+		fmt.Fprint(w, `[]`)
+	})
+	g := testGithubRelease(t, mux)
+	g.sourceArchive = true
+
+	res, err := g.Current(&registry.CurrentRequest{Arch: "amd64", OS: "linux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "github_release://linyows/dewy/tarball/v1.2.3/dewy-1.2.3.tar.gz"; res.ArtifactURL != want {
+		t.Errorf("got %s, want %s", res.ArtifactURL, want)
+	}
+	if res.Tag != "v1.2.3" {
+		t.Errorf("got tag %s", res.Tag)
+	}
+}
+
+func TestWaitDeployment(t *testing.T) {
+	const reviewers = `{"protection_rules":[{"type":"wait_timer"},{"type":"required_reviewers"}]}`
+	tests := []struct {
+		name        string
+		environment string
+		deployments string
+		statuses    string
+		wantCreated bool
+		wantState   string
+		wantErr     error
+	}{
+		{"create deployment", "", `[]`, `[]`, true, "in_progress", nil},
+		{"no reviewers", `{}`, `[{"id":1}]`, `[]`, false, "in_progress", nil},
+		{"waiting", "", `[{"id":1}]`, `[{"state":"waiting"}]`, false, "", registry.ErrNotReady},
+		{"queued", "", `[{"id":1}]`, `[{"state":"queued"}]`, false, "", registry.ErrNotReady},
+		{"in progress", "", `[{"id":1}]`, `[{"state":"in_progress"}]`, false, "", nil},
+		{"success is kept", "", `[{"id":1}]`, `[{"state":"success"}]`, false, "", nil},
+		{"inactive is kept", "", `[{"id":1}]`, `[{"state":"inactive"}]`, false, "", nil},
+		{"failure stops", "", `[{"id":1}]`, `[{"state":"failure"}]`, false, "", ErrDeploymentStopped},
+		{"error stops", "", `[{"id":1}]`, `[{"state":"error"}]`, false, "", ErrDeploymentStopped},
+		{"waiting for approval", reviewers, `[]`, `[]`, true, "", registry.ErrNotReady},
+		{"marked by Dewy only", reviewers, `[{"id":1}]`, `[{"state":"in_progress","description":"Deploy by Dewy"}]`, false, "", registry.ErrNotReady},
+		{"approved", reviewers, `[{"id":1}]`, `[{"state":"in_progress","description":"Approved by alice"}]`, false, "", nil},
+		{"deployed after approved", reviewers, `[{"id":1}]`, `[{"state":"success","description":"Deploy by Dewy"},{"state":"in_progress"}]`, false, "", nil},
+		{"rejected", reviewers, `[{"id":1}]`, `[{"state":"failure","description":"Rejected by alice"}]`, false, "", ErrDeploymentStopped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created bool
+			var state string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy/deployments", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					created = true
+					fmt.Fprint(w, `{"id":1}`)
+					return
+				}
+				fmt.Fprint(w, tt.deployments)
+			})
+			mux.HandleFunc("/repos/linyows/dewy/deployments/1/statuses", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					var req github.DeploymentStatusRequest
+					if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+						t.Error(err)
+					}
+					state = req.GetState()
+					fmt.Fprint(w, `{}`)
+					return
+				}
+				fmt.Fprint(w, tt.statuses)
+			})
+			mux.HandleFunc("/repos/linyows/dewy/environments/production", func(w http.ResponseWriter, r *http.Request) {
+				if tt.environment == "" {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, tt.environment)
+			})
+			g := testGithubRelease(t, mux)
+			g.environment = "production"
+
+			err := g.waitDeployment(context.Background(), "v1.0.0")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("created: got %v, want %v", created, tt.wantCreated)
+			}
+			if state != tt.wantState {
+				t.Errorf("state: got %q, want %q", state, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestFinishDeployment(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"success", nil, "success"},
+		{"failure", errors.New("deploy failed"), "failure"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var state string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy/deployments", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					t.Error("deployment should not be created when finished")
+				}
+				fmt.Fprint(w, `[{"id":1}]`)
+			})
+			mux.HandleFunc("/repos/linyows/dewy/deployments/1/statuses", func(w http.ResponseWriter, r *http.Request) {
+				var req github.DeploymentStatusRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Error(err)
+				}
+				state = req.GetState()
+				fmt.Fprint(w, `{}`)
+			})
+			g := testGithubRelease(t, mux)
+			g.environment = "production"
+
+			if err := g.finishDeployment(context.Background(), "v1.0.0", tt.err); err != nil {
+				t.Fatal(err)
+			}
+			if state != tt.want {
+				t.Errorf("got %q, want %q", state, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheKeyReuploadedAsset(t *testing.T) {
+	assetID := 10
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"tag_name":"v1.0.0"}`)
+	})
+	mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"id":%d,"name":"dewy_linux_amd64.tar.gz"}]`, assetID)
+	})
+	g := testGithubRelease(t, mux)
+
+	req := &registry.CurrentRequest{ArtifactName: "dewy_linux_amd64.tar.gz", DryRun: true}
+	before, err := g.Current(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := g.CacheKey(before), "v1.0.0-10-dewy_linux_amd64.tar.gz"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	assetID = 11
+	after, err := g.Current(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.CacheKey(before) == g.CacheKey(after) {
+		t.Error("cache key should change when the asset is uploaded again")
+	}
+}
+
+func TestCurrentPaginatedAssets(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"tag_name":"v1.0.0","assets":[{"name":"dewy_darwin_arm64.tar.gz"}]}`)
+	})
+	mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `[{"name":"dewy_linux_amd64.tar.gz"}]`)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=2>; rel="next"`, r.Host, r.URL.Path))
+		fmt.Fprint(w, `[{"name":"dewy_darwin_arm64.tar.gz"}]`)
+	})
+	g := testGithubRelease(t, mux)
+
+	res, err := g.Current(&registry.CurrentRequest{ArtifactName: "dewy_linux_amd64.tar.gz", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.ArtifactURL, "github_release://linyows/dewy/tag/v1.0.0/dewy_linux_amd64.tar.gz"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestAssetRevision(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	asset := func(id int64, updatedAt string) *github.ReleaseAsset {
+		ts, err := time.Parse(time.RFC3339, updatedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &github.ReleaseAsset{ID: github.Int64(id), Name: github.String("app.tar.gz"), UpdatedAt: &github.Timestamp{Time: ts}}
+	}
+	g := &GithubRelease{}
+
+	first := g.assetRevision(asset(10, "2024-02-01T00:00:00Z"))
+	// the clock went backward without uploading again
+	second := g.assetRevision(asset(10, "2024-01-01T00:00:00Z"))
+	if first != second {
+		t.Errorf("revision should not change by backward updated_at: %s, %s", first, second)
+	}
+	if !strings.Contains(logs.String(), "moved backward") {
+		t.Error("backward updated_at should be warned")
+	}
+
+	// uploaded again with the wrong clock
+	third := g.assetRevision(asset(11, "2023-12-01T00:00:00Z"))
+	if third == second {
+		t.Error("revision should change when the asset is uploaded again")
+	}
+}
+
+func TestCurrentWithCompanions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"tag_name":"v1.0.0"}`)
+	})
+	mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"name":"dewy_linux_amd64.tar.gz.sig"},
+			{"name":"checksums.txt"},
+			{"name":"dewy_linux_amd64.tar.gz"}
+		]`)
+	})
+	g := testGithubRelease(t, mux)
+
+	tests := []struct {
+		artifact string
+		want     string
+	}{
+		{"", "dewy_linux_amd64.tar.gz"},
+		{"checksums.txt", "checksums.txt"},
+	}
+	for _, tt := range tests {
+		res, err := g.Current(&registry.CurrentRequest{Arch: "amd64", OS: "linux", ArtifactName: tt.artifact, DryRun: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := path.Base(res.ArtifactURL); got != tt.want {
+			t.Errorf("artifact %q: got %s, want %s", tt.artifact, got, tt.want)
+		}
+	}
+}
+
+func TestCurrentVersionSource(t *testing.T) {
+	t.Setenv("ROLLOUT_TOKEN", "secret")
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		wantTag string
+		wantErr bool
+	}{
+		{"plain text", "v1.0.0\n", http.StatusOK, "v1.0.0", false},
+		{"json tag", `{"tag":"v1.0.0"}`, http.StatusOK, "v1.0.0", false},
+		{"json version", `{"version":"v1.0.0"}`, http.StatusOK, "v1.0.0", false},
+		{"empty", "", http.StatusOK, "", true},
+		{"unauthorized", "", http.StatusUnauthorized, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/desired", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			mux.HandleFunc("/repos/linyows/dewy/releases/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"id":1,"tag_name":"v1.0.0"}`)
+			})
+			mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `[{"name":"dewy_linux_amd64.tar.gz"}]`)
+			})
+			g := testGithubRelease(t, mux)
+			g.versionSourceURL = g.cl.BaseURL.String() + "desired"
+			h, err := httputil.ParseHeaders([]string{"Authorization: Bearer ${ROLLOUT_TOKEN}"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			g.versionSourceHeader = h
+
+			res, err := g.Current(&registry.CurrentRequest{Arch: "amd64", OS: "linux", DryRun: true})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Tag != tt.wantTag {
+				t.Errorf("got %s, want %s", res.Tag, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestArtifactNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		goos    string
+		arch    string
+		aliases map[string]string
+		want    []string
+	}{
+		{"app.tar.gz", "linux", "amd64", nil, []string{"app.tar.gz"}},
+		{"app_{{.OS}}_{{.Arch}}.tar.gz", "linux", "riscv64", nil, []string{"app_linux_riscv64.tar.gz"}},
+		{"app_{{.OS}}_{{.Arch}}.tar.gz", "linux", "amd64", nil, []string{"app_linux_amd64.tar.gz", "app_linux_x86_64.tar.gz"}},
+		{"app_{{.OS}}_{{.Arch}}.tar.gz", "darwin", "arm64", nil, []string{
+			"app_darwin_arm64.tar.gz", "app_darwin_aarch64.tar.gz", "app_macos_arm64.tar.gz", "app_macos_aarch64.tar.gz",
+		}},
+		{"app_{{.OS}}_{{.Arch}}.tar.gz", "linux", "amd64", map[string]string{"amd64": "x64", "linux": "Linux"}, []string{
+			"app_linux_amd64.tar.gz", "app_linux_x64.tar.gz", "app_Linux_amd64.tar.gz", "app_Linux_x64.tar.gz",
+		}},
+		{"app_{{.OS}}_{{.Arch}}.tar.gz", "linux", "amd64", map[string]string{"amd64": ""}, []string{"app_linux_amd64.tar.gz"}},
+	}
+	for _, tt := range tests {
+		g := &GithubRelease{artifactAliases: tt.aliases}
+		got, err := g.artifactNames(tt.name, tt.goos, tt.arch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s for %s/%s: got %v, want %v", tt.name, tt.goos, tt.arch, got, tt.want)
+		}
+	}
+
+	g := &GithubRelease{}
+	if _, err := g.artifactNames("app_{{.Version}}.tar.gz", "linux", "amd64"); err == nil {
+		t.Error("unknown field should be error")
+	}
+}
+
+func TestCurrentArtifactTemplate(t *testing.T) {
+	tests := []struct {
+		arch    string
+		want    string
+		wantErr string
+	}{
+		{"amd64", "app_linux_x86_64.tar.gz", ""},
+		{"arm64", "app_linux_arm64.tar.gz", ""},
+		{"386", "", "available: app_linux_x86_64.tar.gz, app_linux_arm64.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arch, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"id":1,"tag_name":"v1.0.0"}`)
+			})
+			mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `[{"name":"app_linux_x86_64.tar.gz"},{"name":"app_linux_arm64.tar.gz"}]`)
+			})
+			g := testGithubRelease(t, mux)
+
+			res, err := g.Current(&registry.CurrentRequest{
+				Arch:         tt.arch,
+				OS:           "linux",
+				ArtifactName: "app_{{.OS}}_{{.Arch}}.tar.gz",
+				DryRun:       true,
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %v, want error containing %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path.Base(res.ArtifactURL) != tt.want {
+				t.Errorf("got %s, want %s", res.ArtifactURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrentMatchLabel(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"tag_name":"v1.0.0"}`)
+	})
+	mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"name":"app-3f2a9c.tar.gz","label":"app_linux_amd64.tar.gz"}]`)
+	})
+
+	tests := []struct {
+		matchLabel bool
+		wantErr    bool
+	}{
+		{false, true},
+		{true, false},
+	}
+	for _, tt := range tests {
+		g := testGithubRelease(t, mux)
+		g.matchLabel = tt.matchLabel
+		res, err := g.Current(&registry.CurrentRequest{ArtifactName: "app_linux_amd64.tar.gz", DryRun: true})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("matchLabel %v: expected error", tt.matchLabel)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := res.ArtifactURL, "github_release://linyows/dewy/tag/v1.0.0/app-3f2a9c.tar.gz"; got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestVerifySignedTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		tag     string
+		signers []string
+		wantErr bool
+	}{
+		{"verified", `{"object":{"type":"tag","sha":"abc"}}`, `{"tagger":{"email":"alice@example.com"},"verification":{"verified":true,"reason":"valid"}}`, nil, false},
+		{"allowed signer", `{"object":{"type":"tag","sha":"abc"}}`, `{"tagger":{"email":"alice@example.com"},"verification":{"verified":true,"reason":"valid"}}`, []string{"Alice@example.com"}, false},
+		{"not allowed signer", `{"object":{"type":"tag","sha":"abc"}}`, `{"tagger":{"email":"mallory@example.com"},"verification":{"verified":true,"reason":"valid"}}`, []string{"alice@example.com"}, true},
+		{"unverified", `{"object":{"type":"tag","sha":"abc"}}`, `{"tagger":{"email":"alice@example.com"},"verification":{"verified":false,"reason":"unsigned"}}`, nil, true},
+		{"lightweight", `{"object":{"type":"commit","sha":"abc"}}`, ``, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy/git/ref/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.ref)
+			})
+			mux.HandleFunc("/repos/linyows/dewy/git/tags/abc", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.tag)
+			})
+			g := testGithubRelease(t, mux)
+			g.allowedSigners = tt.signers
+			err := g.verifySignedTag(context.Background(), "v1.0.0")
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReportDeployerID(t *testing.T) {
+	var name, content string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/releases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":1,"tag_name":"v1.0.0"}]`)
+	})
+	mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+		name = r.URL.Query().Get("name")
+		b, _ := io.ReadAll(r.Body)
+		content = string(b)
+		fmt.Fprint(w, `{}`)
+	})
+	g := testGithubRelease(t, mux)
+	g.cl.UploadURL = g.cl.BaseURL
+
+	if err := g.Report(&registry.ReportRequest{Tag: "v1.0.0", Role: "web", Tags: []string{"tokyo", "blue"}, DeployerID: "team-a"}); err != nil {
+		t.Fatal(err)
+	}
+	hostname, _ := os.Hostname()
+	if got := shippedHost(name); got != strings.ToLower(hostname) {
+		t.Errorf("got host %s from %s", got, name)
+	}
+	if !strings.Contains(name, "_as_web_by_team-a_at_") {
+		t.Errorf("deployer is not in the name: %s", name)
+	}
+	if !strings.Contains(content, "deployer: team-a") {
+		t.Errorf("deployer is not in the content: %s", content)
+	}
+	if !strings.Contains(content, "tags: tokyo, blue") {
+		t.Errorf("tags are not in the content: %s", content)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	fromGitHub := func(w http.ResponseWriter) { w.Header().Set("X-GitHub-Request-Id", "1") }
+	tests := []struct {
+		name       string
+		repo       func(w http.ResponseWriter)
+		releases   func(w http.ResponseWriter)
+		version    bool
+		want       error
+		wantSubstr string
+	}{
+		{
+			name:     "github",
+			repo:     func(w http.ResponseWriter) { fromGitHub(w); fmt.Fprint(w, `{}`) },
+			releases: func(w http.ResponseWriter) { fromGitHub(w); fmt.Fprint(w, `[]`) },
+		},
+		{
+			name:       "gitea",
+			repo:       func(w http.ResponseWriter) { http.NotFound(w, nil) },
+			version:    true,
+			want:       ErrIncompatible,
+			wantSubstr: "Gitea 1.21.0",
+		},
+		{
+			name:       "no release api",
+			repo:       func(w http.ResponseWriter) { fromGitHub(w); fmt.Fprint(w, `{}`) },
+			releases:   func(w http.ResponseWriter) { fromGitHub(w); http.NotFound(w, nil) },
+			want:       ErrIncompatible,
+			wantSubstr: "release API is not found",
+		},
+		{
+			name:       "repository not found on github",
+			repo:       func(w http.ResponseWriter) { fromGitHub(w); http.NotFound(w, nil) },
+			wantSubstr: "404",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy", func(w http.ResponseWriter, r *http.Request) { tt.repo(w) })
+			mux.HandleFunc("/repos/linyows/dewy/releases", func(w http.ResponseWriter, r *http.Request) { tt.releases(w) })
+			if tt.version {
+				mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, `{"version":"1.21.0"}`) })
+			}
+			g := testGithubRelease(t, mux)
+
+			err := g.Probe(context.Background())
+			if tt.want == nil && tt.wantSubstr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("want error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && errors.Is(err, ErrIncompatible) {
+				t.Errorf("got %v, want not incompatible", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantSubstr) {
+				t.Errorf("got %v, want containing %s", err, tt.wantSubstr)
+			}
+		})
+	}
+}
+
+func TestReportRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"success", []int{http.StatusCreated}, 1, false},
+		{"server errors", []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusCreated}, 3, false},
+		{"persistent server error", []int{http.StatusBadGateway}, shippingAttempts, true},
+		{"not found", []int{http.StatusNotFound}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy/releases", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `[{"id":1,"tag_name":"v1.0.0"}]`)
+			})
+			mux.HandleFunc("/repos/linyows/dewy/releases/1/assets", func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[len(tt.statuses)-1]
+				if calls < len(tt.statuses) {
+					status = tt.statuses[calls]
+				}
+				calls++
+				w.WriteHeader(status)
+				fmt.Fprint(w, `{}`)
+			})
+			g := testGithubRelease(t, mux)
+			g.cl.UploadURL = g.cl.BaseURL
+
+			err := g.Report(&registry.ReportRequest{Tag: "v1.0.0"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
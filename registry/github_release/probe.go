@@ -0,0 +1,79 @@
+package ghrelease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// ErrIncompatible is returned when the endpoint does not support the API required by the registry.
+var ErrIncompatible = errors.New("endpoint is incompatible with github_release")
+
+// Probe checks the endpoint supports the release and asset API, so that a provider mismatch,
+// such as pointing GITHUB_ENDPOINT at Gitea, is reported clearly instead of as confusing 404s.
+func (g *GithubRelease) Probe(ctx context.Context) error {
+	_, res, err := g.cl.Repositories.Get(ctx, g.owner, g.repo)
+	if err != nil {
+		if isNotFound(err) && !isGitHub(res) {
+			return g.incompatible(ctx, "repository API is not found")
+		}
+		return err
+	}
+
+	releases, _, err := g.cl.Repositories.ListReleases(ctx, g.owner, g.repo, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		if isNotFound(err) {
+			return g.incompatible(ctx, "release API is not found")
+		}
+		return err
+	}
+	if len(releases) == 0 {
+		return nil
+	}
+	if _, _, err := g.cl.Repositories.ListReleaseAssets(ctx, g.owner, g.repo, releases[0].GetID(), &github.ListOptions{PerPage: 1}); err != nil {
+		if isNotFound(err) {
+			return g.incompatible(ctx, "release asset API is not found")
+		}
+		return err
+	}
+	return nil
+}
+
+// incompatible returns ErrIncompatible with the server identified and the provider suggested.
+func (g *GithubRelease) incompatible(ctx context.Context, reason string) error {
+	server := "an unknown server"
+	suggest := "check GITHUB_ENDPOINT"
+	if v, ok := g.giteaVersion(ctx); ok {
+		server = fmt.Sprintf("Gitea %s", v)
+		suggest = "use the http registry with the release asset URL"
+	}
+	return fmt.Errorf("%w: %s on %s (%s), %s", ErrIncompatible, reason, g.cl.BaseURL, server, suggest)
+}
+
+// giteaVersion returns the version of Gitea or Forgejo serving the endpoint.
+func (g *GithubRelease) giteaVersion(ctx context.Context) (string, bool) {
+	req, err := g.cl.NewRequest(http.MethodGet, "version", nil)
+	if err != nil {
+		return "", false
+	}
+	var v struct {
+		Version string `json:"version"`
+	}
+	if _, err := g.cl.Do(ctx, req, &v); err != nil || v.Version == "" {
+		return "", false
+	}
+	return v.Version, true
+}
+
+// isGitHub reports whether the response is from GitHub or GitHub Enterprise Server.
+func isGitHub(res *github.Response) bool {
+	return res != nil && (res.Header.Get("X-GitHub-Request-Id") != "" || res.Header.Get("X-GitHub-Enterprise-Version") != "")
+}
+
+func isNotFound(err error) bool {
+	var e *github.ErrorResponse
+	return errors.As(err, &e) && e.Response != nil && e.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,85 @@
+package ghrelease
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// comparator is a condition of a version like ">=1.2.0".
+type comparator struct {
+	op string
+	v  string
+}
+
+// semverConstraint is conditions of versions, all of which a version satisfies.
+type semverConstraint struct {
+	raw         string
+	comparators []comparator
+}
+
+// operators of comparators, longer ones first to match.
+var operators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// parseSemverConstraint parses the constraint such as ">=1.2.0 <2.0.0", whose comparators are
+// separated by spaces or commas. A version without an operator means "=".
+func parseSemverConstraint(s string) (*semverConstraint, error) {
+	c := &semverConstraint{raw: s}
+	fields := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	for i := 0; i < len(fields); i++ {
+		op := "="
+		for _, o := range operators {
+			if strings.HasPrefix(fields[i], o) {
+				op = o
+				break
+			}
+		}
+		ver := strings.TrimPrefix(fields[i], op)
+		// allow a space between the operator and the version
+		if ver == "" && i+1 < len(fields) {
+			i++
+			ver = fields[i]
+		}
+		v, ok := canonicalSemver(ver)
+		if !ok {
+			return nil, fmt.Errorf("invalid version in semver constraint: %q", s)
+		}
+		c.comparators = append(c.comparators, comparator{op: op, v: v})
+	}
+	if len(c.comparators) == 0 {
+		return nil, fmt.Errorf("invalid semver constraint: %q", s)
+	}
+	return c, nil
+}
+
+// check reports whether the semantic version with the "v" prefix satisfies all comparators.
+func (c *semverConstraint) check(v string) bool {
+	for _, cmp := range c.comparators {
+		r := semver.Compare(v, cmp.v)
+		var ok bool
+		switch cmp.op {
+		case "=":
+			ok = r == 0
+		case "!=":
+			ok = r != 0
+		case ">":
+			ok = r > 0
+		case ">=":
+			ok = r >= 0
+		case "<":
+			ok = r < 0
+		case "<=":
+			ok = r <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the constraint as given.
+func (c *semverConstraint) String() string {
+	return c.raw
+}
@@ -0,0 +1,127 @@
+package ghrelease
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCompareSemver(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		want int
+	}{
+		{"v1.2.3", "1.2.3", 0},
+		{"v1.10.0", "v1.9.9", 1},
+		{"1.2.0-rc1", "1.2.0", -1},
+		{"1.2.0-rc.2", "1.2.0-rc.10", -1},
+		{"1.2.0-alpha", "1.2.0-1", 1},
+		{"1.2.0-alpha", "1.2.0-alpha.1", -1},
+		{"1.2.0+build.1", "1.2.0", 0},
+	}
+	for _, tt := range tests {
+		g := &GithubRelease{}
+		a, ok := g.parseVersion(tt.a)
+		if !ok {
+			t.Fatalf("%s is not parsed", tt.a)
+		}
+		b, ok := g.parseVersion(tt.b)
+		if !ok {
+			t.Fatalf("%s is not parsed", tt.b)
+		}
+		got := a.compare(b)
+		if (got > 0) != (tt.want > 0) || (got < 0) != (tt.want < 0) {
+			t.Errorf("compare %s and %s: got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseSemverConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+		wantErr    bool
+	}{
+		{">=1.2.0 <2.0.0", "1.5.0", true, false},
+		{">=1.2.0 <2.0.0", "2.0.0", false, false},
+		{">=1.2.0, <2.0.0", "1.2.0", true, false},
+		{">= 1.2.0 < 2.0.0", "1.1.9", false, false},
+		{">=1.2.0", "1.2.0-rc1", false, false},
+		{"1.2.0", "v1.2.0", true, false},
+		{"!=1.2.0", "1.2.0", false, false},
+		{"~>1.2", "", false, true},
+		{"", "", false, true},
+	}
+	for _, tt := range tests {
+		c, err := parseSemverConstraint(tt.constraint)
+		if err != nil {
+			if !tt.wantErr {
+				t.Errorf("%q: %s", tt.constraint, err)
+			}
+			continue
+		}
+		if tt.wantErr {
+			t.Errorf("%q: want error", tt.constraint)
+			continue
+		}
+		v, _ := canonicalSemver(tt.version)
+		if got := c.check(v); got != tt.want {
+			t.Errorf("%q check %s: got %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestHighestSatisfying(t *testing.T) {
+	releases := `[
+		{"tag_name":"v2.0.0"},
+		{"tag_name":"v1.3.0-rc1","prerelease":true},
+		{"tag_name":"v1.2.0"},
+		{"tag_name":"v1.2.0-rc1","prerelease":true},
+		{"tag_name":"v1.4.0","draft":true},
+		{"tag_name":"nightly"}
+	]`
+	tests := []struct {
+		constraint string
+		prerelease bool
+		want       string
+		wantErr    bool
+	}{
+		{">=1.0.0 <2.0.0", false, "v1.2.0", false},
+		{">=1.0.0 <2.0.0", true, "v1.3.0-rc1", false},
+		{">=1.2.0-rc1 <1.2.0", true, "v1.2.0-rc1", false},
+		{">=3.0.0", false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy/releases", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, releases)
+			})
+			g := testGithubRelease(t, mux)
+			g.prerelease = tt.prerelease
+			c, err := parseSemverConstraint(tt.constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g.semverConstraint = c
+
+			r, err := g.highest(context.Background())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.constraint) {
+					t.Errorf("want error naming the constraint, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.GetTagName(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
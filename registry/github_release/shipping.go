@@ -0,0 +1,90 @@
+package ghrelease
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+	"github.com/linyows/dewy/httputil"
+)
+
+const (
+	// shippingPrefix is the prefix of shipping marker assets uploaded by Report.
+	shippingPrefix = "shipped_to_"
+	// shippingAttempts is the number of attempts to upload a shipping marker.
+	shippingAttempts = 4
+	// shippingRetryInterval is the first interval to retry uploading, doubled for each retry.
+	shippingRetryInterval = time.Second
+)
+
+// uploadShipping uploads the shipping marker, retrying with backoff on rate limits and server errors.
+func (g *GithubRelease) uploadShipping(ctx context.Context, u string, content []byte) error {
+	var err error
+	for i := 1; i <= shippingAttempts; i++ {
+		var req *http.Request
+		req, err = g.cl.NewUploadRequest(u, bytes.NewReader(content), int64(len(content)), "text/plain")
+		if err != nil {
+			return err
+		}
+		_, err = g.cl.Do(ctx, req, new(github.ReleaseAsset))
+		if err == nil {
+			return nil
+		}
+		wait, ok := httputil.RetryWait(err, g.retryInterval, i-1)
+		if !ok || i == shippingAttempts {
+			break
+		}
+		log.Printf("[WARN] Shipping upload failure, retry %d/%d in %s: %s", i, shippingAttempts-1, wait, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return err
+}
+
+// ShippedHosts returns hosts that recorded shipping of the tag with markers.
+func (g *GithubRelease) ShippedHosts(tag string) ([]string, error) {
+	ctx := context.Background()
+	release, _, err := g.cl.Repositories.GetReleaseByTag(ctx, g.owner, g.repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := g.releaseAssets(ctx, release)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []string
+	for _, a := range assets {
+		if h := shippedHost(a.GetName()); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts, nil
+}
+
+// shippedHost returns the host of the shipping marker name such as shipped_to_host_at_20060102T150405Z.txt
+// or shipped_to_host_as_role_by_deployer_at_20060102T150405Z.txt.
+func shippedHost(name string) string {
+	if !strings.HasPrefix(name, shippingPrefix) {
+		return ""
+	}
+	h := strings.TrimPrefix(name, shippingPrefix)
+	end := strings.LastIndex(h, "_at_")
+	if end < 0 {
+		return ""
+	}
+	h = h[:end]
+	if i := strings.LastIndex(h, "_by_"); i >= 0 {
+		h = h[:i]
+	}
+	if i := strings.LastIndex(h, "_as_"); i >= 0 {
+		h = h[:i]
+	}
+	return h
+}
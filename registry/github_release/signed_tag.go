@@ -0,0 +1,37 @@
+package ghrelease
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// verifySignedTag checks that the tag is an annotated tag whose signature is verified by GitHub,
+// and tagged by one of the allowed signers if any.
+func (g *GithubRelease) verifySignedTag(ctx context.Context, tag string) error {
+	ref, _, err := g.cl.Git.GetRef(ctx, g.owner, g.repo, "tags/"+tag)
+	if err != nil {
+		return err
+	}
+	if ref.GetObject().GetType() != "tag" {
+		return fmt.Errorf("tag %s is not an annotated tag", tag)
+	}
+	t, _, err := g.cl.Git.GetTag(ctx, g.owner, g.repo, ref.GetObject().GetSHA())
+	if err != nil {
+		return err
+	}
+	v := t.GetVerification()
+	if !v.GetVerified() {
+		return fmt.Errorf("signature of tag %s is not verified: %s", tag, v.GetReason())
+	}
+	if len(g.allowedSigners) == 0 {
+		return nil
+	}
+	email := t.GetTagger().GetEmail()
+	for _, s := range g.allowedSigners {
+		if strings.EqualFold(s, email) {
+			return nil
+		}
+	}
+	return fmt.Errorf("tag %s is signed by %s, not an allowed signer", tag, email)
+}
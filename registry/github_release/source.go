@@ -0,0 +1,70 @@
+package ghrelease
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/linyows/dewy/httputil"
+)
+
+const (
+	// versionSourceTimeout is the timeout to fetch the desired version.
+	versionSourceTimeout = 10 * time.Second
+	// maxVersionSourceSize is the maximum size of the response of the version source.
+	maxVersionSourceSize = 1024 * 1024
+)
+
+// versionSource is the JSON response of the version source.
+type versionSource struct {
+	Tag     string `json:"tag"`
+	Version string `json:"version"`
+}
+
+// desiredTag fetches the tag to deploy from the version source, which returns it
+// as plain text or JSON like {"tag":"v1.2.3"} or {"version":"v1.2.3"}.
+func (g *GithubRelease) desiredTag(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, versionSourceTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.versionSourceURL, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range g.versionSourceHeader {
+		req.Header[k] = v
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", httputil.NewStatusError("unexpected status of "+g.versionSourceURL, res)
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, maxVersionSourceSize))
+	if err != nil {
+		return "", err
+	}
+	b = bytes.TrimSpace(b)
+
+	tag := string(b)
+	if bytes.HasPrefix(b, []byte("{")) {
+		s := &versionSource{}
+		if err := json.Unmarshal(b, s); err != nil {
+			return "", fmt.Errorf("invalid response of %s: %w", g.versionSourceURL, err)
+		}
+		tag = s.Tag
+		if tag == "" {
+			tag = s.Version
+		}
+	}
+	if tag == "" {
+		return "", fmt.Errorf("no tag in %s", g.versionSourceURL)
+	}
+
+	return tag, nil
+}
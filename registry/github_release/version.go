@@ -0,0 +1,139 @@
+package ghrelease
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+	"github.com/linyows/dewy/registry"
+	"golang.org/x/mod/semver"
+)
+
+// compileVersionRegex compiles the regex to extract comparable parts from tags.
+// "semver" returns nil, as tags are compared as semantic versions without the regex.
+func compileVersionRegex(s string) (*regexp.Regexp, error) {
+	if s == "semver" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version regex: %w", err)
+	}
+	if re.NumSubexp() == 0 {
+		return nil, fmt.Errorf("version regex requires a capture group: %s", s)
+	}
+	return re, nil
+}
+
+// canonicalSemver returns the tag as a semantic version with the "v" prefix, or false if it is not.
+func canonicalSemver(tag string) (string, bool) {
+	v := tag
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	return v, semver.IsValid(v)
+}
+
+// version is the version of a tag, parsed as a semantic version or into parts captured by the version regex.
+type version struct {
+	semver string
+	parts  []string
+}
+
+// parseVersion parses the tag by the version regex, or as a semantic version if the regex is not configured.
+func (g *GithubRelease) parseVersion(tag string) (version, bool) {
+	if g.versionRegex == nil {
+		v, ok := canonicalSemver(tag)
+		return version{semver: v}, ok
+	}
+	m := g.versionRegex.FindStringSubmatch(tag)
+	if m == nil {
+		return version{}, false
+	}
+	return version{parts: m[1:]}, true
+}
+
+// compare compares semantic versions by the precedence, where a pre-release version is lower than its normal version,
+// or parts captured by the version regex in order, numerically if both parts are numbers.
+func (v version) compare(o version) int {
+	if v.parts == nil {
+		return semver.Compare(v.semver, o.semver)
+	}
+	for i := 0; i < len(v.parts) && i < len(o.parts); i++ {
+		x, xerr := strconv.ParseUint(v.parts[i], 10, 64)
+		y, yerr := strconv.ParseUint(o.parts[i], 10, 64)
+		switch {
+		case xerr == nil && yerr == nil && x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		case (xerr != nil || yerr != nil) && v.parts[i] != o.parts[i]:
+			return strings.Compare(v.parts[i], o.parts[i])
+		}
+	}
+	return len(v.parts) - len(o.parts)
+}
+
+// highest returns the release with the highest version satisfying the semver constraint if configured.
+func (g *GithubRelease) highest(ctx context.Context) (*github.RepositoryRelease, error) {
+	var (
+		found *github.RepositoryRelease
+		ver   version
+	)
+	page := 1
+	for {
+		releases, res, err := g.cl.Repositories.ListReleases(ctx, g.owner, g.repo, &github.ListOptions{
+			Page:    page,
+			PerPage: 100,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range releases {
+			if v.GetDraft() || (v.GetPrerelease() && !g.prerelease) {
+				continue
+			}
+			p, ok := g.parseVersion(v.GetTagName())
+			if !ok {
+				log.Printf("[DEBUG] Skip %s not parsed as the version", v.GetTagName())
+				continue
+			}
+			if g.minReleaseAge > 0 && time.Since(v.GetPublishedAt().Time) < g.minReleaseAge {
+				continue
+			}
+			if g.semverConstraint != nil && !g.semverConstraint.check(p.semver) {
+				continue
+			}
+			if found == nil || p.compare(ver) > 0 {
+				found, ver = v, p
+			}
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+
+	if found != nil {
+		return found, nil
+	}
+	var cond string
+	switch {
+	case g.versionRegex != nil:
+		cond = "matching " + g.versionRegex.String()
+	case g.semverConstraint != nil:
+		cond = "satisfying " + g.semverConstraint.String()
+	default:
+		cond = "of semantic versions"
+	}
+	if g.minReleaseAge > 0 {
+		return nil, fmt.Errorf("%w: no release %s older than %s", registry.ErrNotReady, cond, g.minReleaseAge)
+	}
+	return nil, fmt.Errorf("no release %s", cond)
+}
@@ -0,0 +1,121 @@
+package ghrelease
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		re   string
+		a    string
+		b    string
+		want int
+	}{
+		{"semver", "v1.2.3", "v1.2.3", 0},
+		{"semver", "v1.10.0", "v1.9.9", 1},
+		{"semver", "1.2.3", "v2.0.0", -1},
+		{"semver", "v1.2.0-rc1", "v1.2.0", -1},
+		{`^build-(\d+)-(\d+)$`, "build-20240101-10", "build-20240101-9", 1},
+		{`^build-(\d+)-(\d+)$`, "build-20231231-9", "build-20240101-1", -1},
+		{`^release-([a-z]+)$`, "release-beta", "release-alpha", 1},
+	}
+	for _, tt := range tests {
+		re, err := compileVersionRegex(tt.re)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := &GithubRelease{versionRegex: re}
+		a, ok := g.parseVersion(tt.a)
+		if !ok {
+			t.Fatalf("%s is not parsed", tt.a)
+		}
+		b, ok := g.parseVersion(tt.b)
+		if !ok {
+			t.Fatalf("%s is not parsed", tt.b)
+		}
+		got := a.compare(b)
+		if (got > 0) != (tt.want > 0) || (got < 0) != (tt.want < 0) {
+			t.Errorf("compare %s and %s: got %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCompileVersionRegex(t *testing.T) {
+	if _, err := compileVersionRegex(`^build-\d+$`); err == nil {
+		t.Error("regex without capture group should be error")
+	}
+	if _, err := compileVersionRegex(`(`); err == nil {
+		t.Error("invalid regex should be error")
+	}
+}
+
+func TestLatestVersionOrdering(t *testing.T) {
+	tests := []struct {
+		name         string
+		versioned    bool
+		want         string
+		wantRequests int
+	}{
+		{"latest release by default", false, "v1.2.5", 1},
+		{"highest semantic version", true, "v2.0.0", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/linyows/dewy/releases", func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.URL.Query().Get("page") != "2" {
+					w.Header().Set("Link", fmt.Sprintf(`<%s?page=2>; rel="next"`, r.URL.Path))
+					fmt.Fprint(w, `[{"tag_name":"v1.2.5"}]`)
+					return
+				}
+				fmt.Fprint(w, `[{"tag_name":"v2.0.0"},{"tag_name":"v1.2.4"}]`)
+			})
+			mux.HandleFunc("/repos/linyows/dewy/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				fmt.Fprint(w, `{"tag_name":"v1.2.5"}`)
+			})
+			g := testGithubRelease(t, mux)
+			g.versioned = tt.versioned
+
+			r, err := g.latest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.GetTagName(); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestHighest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/linyows/dewy/releases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"tag_name":"build-20240101-9"},
+			{"tag_name":"build-20240101-10"},
+			{"tag_name":"build-20240201-1","draft":true},
+			{"tag_name":"nightly"},
+			{"tag_name":"build-20231231-20"}
+		]`)
+	})
+	g := testGithubRelease(t, mux)
+	g.versionRegex = regexp.MustCompile(`^build-(\d+)-(\d+)$`)
+	g.versioned = true
+
+	r, err := g.latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.GetTagName(), "build-20240101-10"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
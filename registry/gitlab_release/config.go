@@ -0,0 +1,10 @@
+package glrelease
+
+// Config struct.
+type Config struct {
+	// Project is the path of the project such as group/subgroup/project.
+	Project string
+	// Endpoint is the API endpoint of self-hosted GitLab such as https://gitlab.example.com/api/v4/.
+	// GITLAB_ENDPOINT or gitlab.com is used if empty.
+	Endpoint string
+}
@@ -0,0 +1,199 @@
+package glrelease
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/linyows/dewy/httputil"
+	"github.com/linyows/dewy/registry"
+	glrelease "github.com/linyows/dewy/storage/gitlab_release"
+)
+
+const (
+	Scheme = "gitlab_release"
+	// EndpointEnv is the environment variable of the API endpoint for self-hosted GitLab.
+	EndpointEnv = "GITLAB_ENDPOINT"
+	// defaultEndpoint is the API endpoint of gitlab.com.
+	defaultEndpoint = "https://gitlab.com/api/v4/"
+	// shippingPackage is the name of the generic package where shipping markers are uploaded.
+	shippingPackage = "dewy"
+)
+
+// GitlabRelease is the registry of release links of GitLab.
+type GitlabRelease struct {
+	project  string
+	endpoint *url.URL
+	token    string
+	cl       *http.Client
+}
+
+var _ registry.Registry = (*GitlabRelease)(nil)
+
+// release is the release of the GitLab API.
+type release struct {
+	TagName         string    `json:"tag_name"`
+	ReleasedAt      time.Time `json:"released_at"`
+	UpcomingRelease bool      `json:"upcoming_release"`
+	Assets          struct {
+		Links []link `json:"links"`
+	} `json:"assets"`
+}
+
+// link is the asset link of the release.
+type link struct {
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	DirectAssetURL string `json:"direct_asset_url"`
+}
+
+// New returns GitlabRelease.
+func New(c Config) (*GitlabRelease, error) {
+	if c.Project == "" {
+		return nil, fmt.Errorf("project is required for %s", Scheme)
+	}
+	ep := c.Endpoint
+	if ep == "" {
+		ep = os.Getenv(EndpointEnv)
+	}
+	if ep == "" {
+		ep = defaultEndpoint
+	}
+	if !strings.HasSuffix(ep, "/") {
+		ep += "/"
+	}
+	u, err := url.Parse(ep)
+	if err != nil {
+		return nil, err
+	}
+	return &GitlabRelease{
+		project:  c.Project,
+		endpoint: u,
+		token:    os.Getenv(glrelease.TokenEnv),
+		cl:       http.DefaultClient,
+	}, nil
+}
+
+// Current returns the artifact linked from the latest release.
+func (g *GitlabRelease) Current(req *registry.CurrentRequest) (*registry.CurrentResponse, error) {
+	ctx := context.Background()
+	r, err := g.latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var found *link
+	for i, l := range r.Assets.Links {
+		if l.Name == req.ArtifactName {
+			found = &r.Assets.Links[i]
+			break
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("artifact not found: %s", req.ArtifactName)
+	}
+	au, err := g.artifactURL(found)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DEBUG] Fetched: %s of %s", found.Name, r.TagName)
+
+	return &registry.CurrentResponse{
+		ID:          time.Now().Format(registry.ISO8601),
+		Tag:         r.TagName,
+		ArtifactURL: au,
+		PublishedAt: r.ReleasedAt,
+		// the link is recreated when the asset is replaced
+		Revision: strconv.FormatInt(found.ID, 10),
+	}, nil
+}
+
+// latest returns the latest release except upcoming ones, listed in descending order of release dates.
+func (g *GitlabRelease) latest(ctx context.Context) (*release, error) {
+	page := "1"
+	for page != "" {
+		var releases []release
+		res, err := g.do(ctx, http.MethodGet, fmt.Sprintf("projects/%s/releases?per_page=100&page=%s", url.PathEscape(g.project), page), nil, &releases)
+		if err != nil {
+			return nil, err
+		}
+		for i, r := range releases {
+			if !r.UpcomingRelease {
+				return &releases[i], nil
+			}
+		}
+		page = res.Header.Get("X-Next-Page")
+	}
+	return nil, fmt.Errorf("release not found: %s", g.project)
+}
+
+// artifactURL returns the URL of the link to download, fetched with the access token if hosted by GitLab.
+func (g *GitlabRelease) artifactURL(l *link) (string, error) {
+	s := l.DirectAssetURL
+	if s == "" {
+		s = l.URL
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported link url: %s", s)
+	}
+	if u.Scheme == "https" && u.Host == g.endpoint.Host {
+		return fmt.Sprintf("%s://%s%s", glrelease.Scheme, u.Host, u.RequestURI()), nil
+	}
+	return s, nil
+}
+
+// Report uploads the shipping marker to the generic package of the tag.
+func (g *GitlabRelease) Report(req *registry.ReportRequest) error {
+	if req.Err != nil {
+		return req.Err
+	}
+	name, content := registry.ShippingMarker(req)
+
+	p := fmt.Sprintf("projects/%s/packages/generic/%s/%s/%s", url.PathEscape(g.project), shippingPackage,
+		url.PathEscape(req.Tag), url.PathEscape(name))
+	_, err := g.do(context.Background(), http.MethodPut, p, bytes.NewReader(content), nil)
+	return err
+}
+
+// do requests the API with the access token, and decodes the JSON response into v if not nil.
+func (g *GitlabRelease) do(ctx context.Context, method, p string, body io.Reader, v any) (*http.Response, error) {
+	u, err := g.endpoint.Parse(p)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if g.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", g.token)
+	}
+	res, err := g.cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return res, fmt.Errorf("%w %s", httputil.NewStatusError(fmt.Sprintf("unexpected status of %s %s", method, u.Redacted()), res), strings.TrimSpace(string(b)))
+	}
+	if v != nil {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
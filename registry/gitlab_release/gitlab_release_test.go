@@ -0,0 +1,123 @@
+package glrelease
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/linyows/dewy/registry"
+)
+
+func testGitlabRelease(t *testing.T, mux *http.ServeMux) *GitlabRelease {
+	t.Helper()
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	u, err := url.Parse(ts.URL + "/api/v4/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &GitlabRelease{project: "group/app", endpoint: u, token: "secret", cl: ts.Client()}
+}
+
+func TestCurrent(t *testing.T) {
+	tests := []struct {
+		name    string
+		pages   []string
+		wantTag string
+		wantRev string
+		wantURL string
+		wantErr bool
+	}{
+		{
+			"hosted link",
+			[]string{`[{"tag_name":"v1.1.0","released_at":"2024-01-02T00:00:00Z","assets":{"links":[
+				{"id":11,"name":"app_darwin_arm64.tar.gz","url":"https://{{host}}/a","direct_asset_url":"https://{{host}}/group/app/-/releases/v1.1.0/downloads/app_darwin_arm64.tar.gz"},
+				{"id":12,"name":"app_linux_amd64.tar.gz","url":"https://{{host}}/b","direct_asset_url":"https://{{host}}/group/app/-/releases/v1.1.0/downloads/app_linux_amd64.tar.gz"}]}}]`},
+			"v1.1.0", "12", "gitlab_release://{{host}}/group/app/-/releases/v1.1.0/downloads/app_linux_amd64.tar.gz", false,
+		},
+		{
+			"external link after upcoming releases",
+			[]string{
+				`[{"tag_name":"v2.0.0","upcoming_release":true,"assets":{"links":[{"id":21,"name":"app_linux_amd64.tar.gz","url":"https://dl.example.com/v2.0.0/app_linux_amd64.tar.gz"}]}}]`,
+				`[{"tag_name":"v1.0.0","assets":{"links":[{"id":13,"name":"app_linux_amd64.tar.gz","url":"https://dl.example.com/v1.0.0/app_linux_amd64.tar.gz"}]}}]`,
+			},
+			"v1.0.0", "13", "https://dl.example.com/v1.0.0/app_linux_amd64.tar.gz", false,
+		},
+		{
+			"artifact not found",
+			[]string{`[{"tag_name":"v1.0.0","assets":{"links":[{"id":13,"name":"app_darwin_arm64.tar.gz","url":"https://dl.example.com/a"}]}}]`},
+			"", "", "", true,
+		},
+		{
+			"release not found",
+			[]string{`[]`},
+			"", "", "", true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			var g *GitlabRelease
+			mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.EscapedPath() != "/api/v4/projects/group%2Fapp/releases" {
+					t.Errorf("unexpected path: %s", r.URL.EscapedPath())
+				}
+				if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+					t.Error("token is not sent")
+				}
+				p := 1
+				if s := r.URL.Query().Get("page"); s == "2" {
+					p = 2
+				}
+				if p < len(tt.pages) {
+					w.Header().Set("X-Next-Page", "2")
+				}
+				_, _ = io.WriteString(w, strings.ReplaceAll(tt.pages[p-1], "{{host}}", g.endpoint.Host))
+			})
+			g = testGitlabRelease(t, mux)
+			res, err := g.Current(&registry.CurrentRequest{ArtifactName: "app_linux_amd64.tar.gz"})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Tag != tt.wantTag || res.Revision != tt.wantRev {
+				t.Errorf("got %s (%s), want %s (%s)", res.Tag, res.Revision, tt.wantTag, tt.wantRev)
+			}
+			if want := strings.ReplaceAll(tt.wantURL, "{{host}}", g.endpoint.Host); res.ArtifactURL != want {
+				t.Errorf("got %s, want %s", res.ArtifactURL, want)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	var path, body string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("unexpected method: %s", r.Method)
+		}
+		path = r.URL.EscapedPath()
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusCreated)
+	})
+	g := testGitlabRelease(t, mux)
+	if err := g.Report(&registry.ReportRequest{Tag: "v1.0.0", Role: "web", Tags: []string{"tokyo"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(path, "/api/v4/projects/group%2Fapp/packages/generic/dewy/v1.0.0/shipped_to_") || !strings.Contains(path, "_as_web_at_") {
+		t.Errorf("unexpected path: %s", path)
+	}
+	if !strings.HasSuffix(body, "\ntags: tokyo") {
+		t.Errorf("unexpected content: %s", body)
+	}
+}
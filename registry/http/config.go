@@ -0,0 +1,12 @@
+package httpreg
+
+// Config struct.
+type Config struct {
+	// URL is the URL of the artifact, or the template of it with VersionURL,
+	// such as https://dl.example.com/myapp/{{.Version}}/myapp_{{.OS}}_{{.Arch}}.tar.gz.
+	// It may be empty if the manifest of VersionURL has the artifact URL.
+	URL string
+	// VersionURL is the endpoint returning the latest version as plain text,
+	// or a JSON manifest such as {"version":"1.2.3","url":"https://..."}.
+	VersionURL string
+}
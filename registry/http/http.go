@@ -0,0 +1,157 @@
+package httpreg
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/linyows/dewy/httputil"
+	"github.com/linyows/dewy/registry"
+)
+
+const (
+	Scheme       = "http"
+	SchemeSecure = "https"
+)
+
+// invalidTagChars are characters not allowed in tags used as cache keys.
+var invalidTagChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// HTTP is the registry serving the artifact by a plain URL.
+// It detects changes by HEAD requests so the artifact is downloaded only when changed,
+// or by the version endpoint if the version URL is set.
+type HTTP struct {
+	url        string
+	versionURL string
+	cl         *http.Client
+}
+
+var _ registry.Registry = (*HTTP)(nil)
+
+// New returns HTTP.
+func New(c Config) (*HTTP, error) {
+	if c.URL == "" {
+		// the manifest of the version endpoint has the artifact URL
+		if !isHTTP(c.VersionURL) {
+			return nil, fmt.Errorf("invalid version url: %s", c.VersionURL)
+		}
+	} else if !isHTTP(c.URL) {
+		return nil, fmt.Errorf("invalid url: %s", c.URL)
+	}
+	if isTemplate(c.URL) {
+		if c.VersionURL == "" {
+			return nil, fmt.Errorf("version url is required for url template: %s", c.URL)
+		}
+		if _, err := renderURL(c.URL, URLData{}); err != nil {
+			return nil, err
+		}
+	}
+	return &HTTP{
+		url:        c.URL,
+		versionURL: c.VersionURL,
+		cl:         http.DefaultClient,
+	}, nil
+}
+
+// isHTTP reports whether the URL is of HTTP or HTTPS.
+func isHTTP(u string) bool {
+	return strings.HasPrefix(u, Scheme+"://") || strings.HasPrefix(u, SchemeSecure+"://")
+}
+
+// Current returns current artifact.
+func (h *HTTP) Current(req *registry.CurrentRequest) (*registry.CurrentResponse, error) {
+	if h.versionURL != "" {
+		return h.versioned(req)
+	}
+	return h.head(h.url)
+}
+
+// head returns the artifact of the URL versioned by the response headers of HEAD request.
+func (h *HTTP) head(u string) (*registry.CurrentResponse, error) {
+	res, err := h.cl.Head(u)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, httputil.NewStatusError(fmt.Sprintf("unexpected status of %s", u), res)
+	}
+
+	tag, err := versionOf(res.Header)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", u, err)
+	}
+	log.Printf("[DEBUG] Fetched: %s as %s", u, tag)
+
+	cr := &registry.CurrentResponse{
+		ID:          time.Now().Format(registry.ISO8601),
+		Tag:         tag,
+		ArtifactURL: u,
+	}
+	if lm, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		cr.PublishedAt = lm
+	}
+
+	return cr, nil
+}
+
+// versioned returns the artifact of the latest version from the version endpoint.
+func (h *HTTP) versioned(req *registry.CurrentRequest) (*registry.CurrentResponse, error) {
+	m, err := h.latest()
+	if err != nil {
+		return nil, err
+	}
+	au := m.URL
+	if au == "" {
+		if h.url == "" {
+			return nil, fmt.Errorf("no url in manifest of %s", h.versionURL)
+		}
+		au, err = renderURL(h.url, URLData{Version: m.Version, OS: req.OS, Arch: req.Arch})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if m.Version == "" {
+		// the manifest only pointing the artifact is versioned by the artifact
+		return h.head(au)
+	}
+	log.Printf("[DEBUG] Fetched: %s as %s", au, m.Version)
+
+	return &registry.CurrentResponse{
+		ID:          time.Now().Format(registry.ISO8601),
+		Tag:         invalidTagChars.ReplaceAllString(m.Version, "_"),
+		ArtifactURL: au,
+	}, nil
+}
+
+// versionOf returns the version from ETag, Last-Modified or Content-Length in order.
+func versionOf(header http.Header) (string, error) {
+	if etag := header.Get("ETag"); etag != "" {
+		etag = strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+		return invalidTagChars.ReplaceAllString(etag, "_"), nil
+	}
+	if lm := header.Get("Last-Modified"); lm != "" {
+		t, err := http.ParseTime(lm)
+		if err != nil {
+			return "", err
+		}
+		return t.UTC().Format(registry.ISO8601), nil
+	}
+	if cl := header.Get("Content-Length"); cl != "" {
+		return "size-" + invalidTagChars.ReplaceAllString(cl, "_"), nil
+	}
+
+	return "", fmt.Errorf("none of ETag, Last-Modified and Content-Length is available to detect changes")
+}
+
+// Report reports nothing because the HTTP server has no place to record shipping.
+func (h *HTTP) Report(req *registry.ReportRequest) error {
+	if req.Err != nil {
+		return req.Err
+	}
+	log.Printf("[DEBUG] Shipped %s", req.Tag)
+	return nil
+}
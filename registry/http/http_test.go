@@ -0,0 +1,155 @@
+package httpreg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/linyows/dewy/registry"
+)
+
+func TestCurrent(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   string
+	}{
+		{"etag", map[string]string{"ETag": `"abc123"`}, "abc123"},
+		{"weak etag", map[string]string{"ETag": `W/"abc/123"`}, "abc_123"},
+		{"last modified", map[string]string{"Last-Modified": "Mon, 01 Jan 2024 00:00:00 GMT"}, "20240101T000000Z"},
+		{"content length", map[string]string{"Content-Length": "1024"}, "size-1024"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gets := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodHead {
+					gets++
+				}
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+			}))
+			defer ts.Close()
+
+			h, err := New(Config{URL: ts.URL + "/app.tar.gz"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := h.Current(&registry.CurrentRequest{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Tag != tt.want {
+				t.Errorf("got %s, want %s", res.Tag, tt.want)
+			}
+			if res.ArtifactURL != ts.URL+"/app.tar.gz" {
+				t.Errorf("unexpected artifact url: %s", res.ArtifactURL)
+			}
+			if gets != 0 {
+				t.Errorf("artifact should not be downloaded to detect changes")
+			}
+		})
+	}
+}
+
+func TestCurrentVersioned(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		url     string
+		wantTag string
+		wantURL string
+	}{
+		{"plain text", "1.2.3\n", "/dl/{{.Version}}/app_{{.OS}}_{{.Arch}}.tar.gz", "1.2.3", "/dl/1.2.3/app_linux_amd64.tar.gz"},
+		{"manifest with url", `{"version":"v2.0.0","url":"/files/app-v2.0.0.tar.gz"}`, "/dl/app.tar.gz", "v2.0.0", "/files/app-v2.0.0.tar.gz"},
+		{"manifest without url", `{"version":"v2.0.0"}`, "/dl/{{.Version}}.tar.gz", "v2.0.0", "/dl/v2.0.0.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/latest" {
+					t.Errorf("unexpected request: %s", r.URL.Path)
+				}
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			h, err := New(Config{URL: ts.URL + tt.url, VersionURL: ts.URL + "/latest"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := h.Current(&registry.CurrentRequest{OS: "linux", Arch: "amd64"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Tag != tt.wantTag {
+				t.Errorf("tag: got %s, want %s", res.Tag, tt.wantTag)
+			}
+			if res.ArtifactURL != ts.URL+tt.wantURL {
+				t.Errorf("artifact url: got %s, want %s", res.ArtifactURL, ts.URL+tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestNewTemplate(t *testing.T) {
+	if _, err := New(Config{URL: "https://dl.example.com/{{.Version}}/app.tar.gz"}); err == nil {
+		t.Error("template without version url should be error")
+	}
+	if _, err := New(Config{URL: "https://dl.example.com/{{.Unknown}}/app.tar.gz", VersionURL: "https://dl.example.com/latest"}); err == nil {
+		t.Error("unknown template field should be error")
+	}
+}
+
+func TestCurrentManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantTag string
+		wantErr bool
+	}{
+		{"version", `{"version":"1.2.3","url":"/files/app-1.2.3.tar.gz"}`, "1.2.3", false},
+		{"etag of artifact", `{"url":"/files/app.tar.gz"}`, "abc123", false},
+		{"no url", `{"version":"1.2.3"}`, "", true},
+		{"plain text", "1.2.3", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/manifest.json", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.body))
+			})
+			mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodHead {
+					t.Errorf("artifact should not be downloaded to detect changes")
+				}
+				w.Header().Set("ETag", `"abc123"`)
+			})
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			h, err := New(Config{VersionURL: ts.URL + "/manifest.json"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := h.Current(&registry.CurrentRequest{OS: "linux", Arch: "amd64"})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Tag != tt.wantTag {
+				t.Errorf("tag: got %s, want %s", res.Tag, tt.wantTag)
+			}
+			if !strings.HasPrefix(res.ArtifactURL, ts.URL+"/files/") {
+				t.Errorf("unexpected artifact url: %s", res.ArtifactURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package httpreg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/linyows/dewy/httputil"
+)
+
+// maxManifestSize is the maximum size of the response of the version endpoint.
+const maxManifestSize = 1024 * 1024
+
+// Manifest is the JSON response of the version endpoint.
+type Manifest struct {
+	// Version is the version of the artifact. The artifact is versioned by its ETag, Last-Modified
+	// or Content-Length if empty.
+	Version string `json:"version"`
+	// URL is the artifact URL of the version. The URL template is used if empty.
+	URL string `json:"url"`
+}
+
+// URLData is the data to render the URL template.
+type URLData struct {
+	Version string
+	OS      string
+	Arch    string
+}
+
+// isTemplate reports whether the URL is a template.
+func isTemplate(u string) bool {
+	return strings.Contains(u, "{{")
+}
+
+// latest fetches the latest version and its artifact URL from the version endpoint.
+func (h *HTTP) latest() (*Manifest, error) {
+	res, err := h.cl.Get(h.versionURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, httputil.NewStatusError("unexpected status of "+h.versionURL, res)
+	}
+	b, err := io.ReadAll(io.LimitReader(res.Body, maxManifestSize))
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+
+	m := &Manifest{}
+	if bytes.HasPrefix(b, []byte("{")) {
+		if err := json.Unmarshal(b, m); err != nil {
+			return nil, fmt.Errorf("invalid manifest of %s: %w", h.versionURL, err)
+		}
+	} else {
+		m.Version = string(b)
+	}
+	if m.Version == "" && m.URL == "" {
+		return nil, fmt.Errorf("no version in %s", h.versionURL)
+	}
+	if m.URL != "" {
+		// the URL may be relative to the version endpoint
+		base, err := url.Parse(h.versionURL)
+		if err != nil {
+			return nil, err
+		}
+		ref, err := url.Parse(m.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid url in manifest of %s: %w", h.versionURL, err)
+		}
+		m.URL = base.ResolveReference(ref).String()
+	}
+
+	return m, nil
+}
+
+// renderURL renders the URL template.
+func renderURL(tmpl string, data URLData) (string, error) {
+	t, err := template.New("url").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid url template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid url template: %w", err)
+	}
+	return buf.String(), nil
+}
@@ -1,5 +1,13 @@
 package registry
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotReady is returned when the artifact exists but is not ready to deploy yet.
+var ErrNotReady = errors.New("artifact is not ready to deploy")
+
 type Registry interface {
 	// Current returns the current artifact.
 	Current(*CurrentRequest) (*CurrentResponse, error)
@@ -16,6 +24,8 @@ type CurrentRequest struct {
 	// ArtifactName is the name of the artifact to fetch.
 	// FIXME: If possible, ArtifactName should be optional.
 	ArtifactName string
+	// DryRun does not change any state of the registry.
+	DryRun bool
 }
 
 // CurrentResponse is the response to get the current artifact.
@@ -27,6 +37,10 @@ type CurrentResponse struct {
 	// ArtifactURL is the URL to download the artifact.
 	// The URL is not only "https://"
 	ArtifactURL string
+	// PublishedAt is the time when the artifact was published, if known.
+	PublishedAt time.Time
+	// Revision distinguishes artifacts of the same tag, such as a moving tag repointed to new content.
+	Revision string
 }
 
 // ReportRequest is the request to report the result of deploying the artifact.
@@ -37,4 +51,10 @@ type ReportRequest struct {
 	Tag string
 	// Err is the error that occurred during deployment. If Err is nil, the deployment is considered successful.
 	Err error
+	// Role is the role of the deployed host.
+	Role string
+	// Tags are the labels of the deployed host.
+	Tags []string
+	// DeployerID is the team or pipeline deploying, recorded with the host.
+	DeployerID string
 }
@@ -0,0 +1,11 @@
+package s3reg
+
+// Config struct.
+type Config struct {
+	// Bucket is the bucket of artifacts.
+	Bucket string
+	// Prefix is the key prefix under which artifacts are put as <prefix>/<tag>/<artifact>.
+	Prefix string
+	// Region is the region of the bucket. The region of the AWS configuration is used if empty.
+	Region string
+}
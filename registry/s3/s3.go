@@ -0,0 +1,155 @@
+package s3reg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/linyows/dewy/registry"
+)
+
+const Scheme = "s3"
+
+// S3 is the registry of artifacts put in an Amazon S3 bucket as <prefix>/<tag>/<artifact>.
+// The artifact of the latest modified object is deployed, and its ETag distinguishes the content.
+type S3 struct {
+	bucket string
+	prefix string
+	cl     s3iface.S3API
+}
+
+var _ registry.Registry = (*S3)(nil)
+
+// New returns S3.
+func New(c Config) (*S3, error) {
+	if c.Bucket == "" {
+		return nil, fmt.Errorf("bucket is required")
+	}
+	cfg := aws.NewConfig()
+	if c.Region != "" {
+		cfg = cfg.WithRegion(c.Region)
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &S3{
+		bucket: c.Bucket,
+		prefix: strings.Trim(c.Prefix, "/"),
+		cl:     s3.New(sess),
+	}, nil
+}
+
+// ParseURL returns Config of the registry URL such as s3://bucket/prefix?region=ap-northeast-1.
+func ParseURL(urlstr string) (Config, error) {
+	u, err := url.Parse(urlstr)
+	if err != nil {
+		return Config{}, err
+	}
+	if u.Scheme != Scheme || u.Host == "" {
+		return Config{}, fmt.Errorf("invalid registry: %s", urlstr)
+	}
+	return Config{
+		Bucket: u.Host,
+		Prefix: strings.Trim(u.Path, "/"),
+		Region: u.Query().Get("region"),
+	}, nil
+}
+
+// Current returns the artifact of the latest modified object named the artifact name.
+func (s *S3) Current(req *registry.CurrentRequest) (*registry.CurrentResponse, error) {
+	ctx := context.Background()
+	var found *s3.Object
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+	}
+	if s.prefix != "" {
+		input.Prefix = aws.String(s.prefix + "/")
+	}
+	err := s.cl.ListObjectsV2PagesWithContext(ctx, input, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, o := range page.Contents {
+			tag, name, ok := s.split(aws.StringValue(o.Key))
+			if !ok || tag == "" || name != req.ArtifactName {
+				continue
+			}
+			if found == nil || newer(o, found) {
+				found = o
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, fmt.Errorf("artifact not found: s3://%s/%s", s.bucket, path.Join(s.prefix, "*", req.ArtifactName))
+	}
+
+	key := aws.StringValue(found.Key)
+	tag, _, _ := s.split(key)
+	log.Printf("[DEBUG] Fetched: %s as %s", key, tag)
+
+	return &registry.CurrentResponse{
+		ID:          time.Now().Format(registry.ISO8601),
+		Tag:         tag,
+		ArtifactURL: s.artifactURL(key),
+		PublishedAt: aws.TimeValue(found.LastModified),
+		Revision:    strings.Trim(aws.StringValue(found.ETag), `"`),
+	}, nil
+}
+
+// split returns the tag and the name of the key as <prefix>/<tag>/<name>.
+func (s *S3) split(key string) (string, string, bool) {
+	if s.prefix != "" {
+		if !strings.HasPrefix(key, s.prefix+"/") {
+			return "", "", false
+		}
+		key = strings.TrimPrefix(key, s.prefix+"/")
+	}
+	tag, name, ok := strings.Cut(key, "/")
+	if !ok || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	return tag, name, true
+}
+
+// newer reports whether o is modified after p, or has the greater key if modified at the same time,
+// so that version-encoded keys are ordered when objects are put at once.
+func newer(o, p *s3.Object) bool {
+	ot, pt := aws.TimeValue(o.LastModified), aws.TimeValue(p.LastModified)
+	if !ot.Equal(pt) {
+		return ot.After(pt)
+	}
+	return aws.StringValue(o.Key) > aws.StringValue(p.Key)
+}
+
+// artifactURL returns the URL of the key to download. The region is given to the storage, not in the URL
+// deriving the cache key.
+func (s *S3) artifactURL(key string) string {
+	return fmt.Sprintf("%s://%s/%s", Scheme, s.bucket, key)
+}
+
+// Report puts the shipping marker next to the artifact.
+func (s *S3) Report(req *registry.ReportRequest) error {
+	if req.Err != nil {
+		return req.Err
+	}
+	name, content := registry.ShippingMarker(req)
+
+	_, err := s.cl.PutObjectWithContext(context.Background(), &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(path.Join(s.prefix, req.Tag, name)),
+		Body:        bytes.NewReader(content),
+		ContentType: aws.String("text/plain"),
+	})
+	return err
+}
@@ -0,0 +1,155 @@
+package s3reg
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/linyows/dewy/registry"
+)
+
+type mockS3 struct {
+	s3iface.S3API
+	pages [][]*s3.Object
+	input *s3.ListObjectsV2Input
+	put   map[string]string
+}
+
+func (m *mockS3) ListObjectsV2PagesWithContext(_ aws.Context, in *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool, _ ...request.Option) error {
+	m.input = in
+	for i, p := range m.pages {
+		if !fn(&s3.ListObjectsV2Output{Contents: p}, i == len(m.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockS3) PutObjectWithContext(_ aws.Context, in *s3.PutObjectInput, _ ...request.Option) (*s3.PutObjectOutput, error) {
+	b, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	if m.put == nil {
+		m.put = map[string]string{}
+	}
+	m.put[aws.StringValue(in.Key)] = string(b)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func object(key, etag string, t time.Time) *s3.Object {
+	return &s3.Object{Key: aws.String(key), ETag: aws.String(`"` + etag + `"`), LastModified: aws.Time(t)}
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    Config
+		wantErr bool
+	}{
+		{"s3://bucket/myapp?region=ap-northeast-1", Config{Bucket: "bucket", Prefix: "myapp", Region: "ap-northeast-1"}, false},
+		{"s3://bucket/path/to/myapp/", Config{Bucket: "bucket", Prefix: "path/to/myapp"}, false},
+		{"s3://bucket", Config{Bucket: "bucket"}, false},
+		{"s3:///myapp", Config{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ParseURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		pages   [][]*s3.Object
+		wantTag string
+		wantRev string
+		wantErr bool
+	}{
+		{
+			"latest modified across pages",
+			[][]*s3.Object{
+				{object("myapp/v1.0.0/app_linux_amd64.tar.gz", "aaa", base), object("myapp/v1.2.0/app_linux_amd64.tar.gz", "ccc", base.Add(2*time.Hour))},
+				{object("myapp/v1.1.0/app_linux_amd64.tar.gz", "bbb", base.Add(time.Hour))},
+			},
+			"v1.2.0", "ccc", false,
+		},
+		{
+			"greater key at the same time",
+			[][]*s3.Object{{object("myapp/v1.0.0/app_linux_amd64.tar.gz", "aaa", base), object("myapp/v1.0.1/app_linux_amd64.tar.gz", "bbb", base)}},
+			"v1.0.1", "bbb", false,
+		},
+		{
+			"other artifacts and layouts are skipped",
+			[][]*s3.Object{{
+				object("myapp/v1.0.0/app_linux_amd64.tar.gz", "aaa", base),
+				object("myapp/v2.0.0/app_darwin_arm64.tar.gz", "bbb", base.Add(time.Hour)),
+				object("myapp/app_linux_amd64.tar.gz", "ccc", base.Add(time.Hour)),
+				object("myapp/v3.0.0/nested/app_linux_amd64.tar.gz", "ddd", base.Add(time.Hour)),
+			}},
+			"v1.0.0", "aaa", false,
+		},
+		{
+			"not found",
+			[][]*s3.Object{{object("myapp/v1.0.0/app_darwin_arm64.tar.gz", "aaa", base)}},
+			"", "", true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockS3{pages: tt.pages}
+			s := &S3{bucket: "bucket", prefix: "myapp", cl: m}
+			res, err := s.Current(&registry.CurrentRequest{ArtifactName: "app_linux_amd64.tar.gz"})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if aws.StringValue(m.input.Prefix) != "myapp/" {
+				t.Errorf("unexpected prefix: %s", aws.StringValue(m.input.Prefix))
+			}
+			if res.Tag != tt.wantTag || res.Revision != tt.wantRev {
+				t.Errorf("got %s (%s), want %s (%s)", res.Tag, res.Revision, tt.wantTag, tt.wantRev)
+			}
+			if want := "s3://bucket/myapp/" + tt.wantTag + "/app_linux_amd64.tar.gz"; res.ArtifactURL != want {
+				t.Errorf("got %s, want %s", res.ArtifactURL, want)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	m := &mockS3{}
+	s := &S3{bucket: "bucket", prefix: "myapp", cl: m}
+	if err := s.Report(&registry.ReportRequest{Tag: "v1.0.0", Role: "web", Tags: []string{"tokyo"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.put) != 1 {
+		t.Fatalf("got %d markers, want 1", len(m.put))
+	}
+	for k, v := range m.put {
+		if !strings.HasPrefix(k, "myapp/v1.0.0/shipped_to_") || !strings.Contains(k, "_as_web_at_") {
+			t.Errorf("unexpected marker: %s", k)
+		}
+		if !strings.HasSuffix(v, "\ntags: tokyo") {
+			t.Errorf("unexpected content: %s", v)
+		}
+	}
+}
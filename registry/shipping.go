@@ -0,0 +1,34 @@
+package registry
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ISO8601 for time format.
+const ISO8601 = "20060102T150405Z0700"
+
+// ShippingMarker returns the file name and the content of the shipping marker put next to the artifact,
+// which records the host, the role and the deployer shipped with the tag, such as shipped_to_host_as_web_at_<time>.txt.
+func ShippingMarker(req *ReportRequest) (string, []byte) {
+	now := time.Now().UTC().Format(ISO8601)
+	hostname, _ := os.Hostname()
+	info := fmt.Sprintf("shipped to %s", strings.ToLower(hostname))
+	if req.Role != "" {
+		info = fmt.Sprintf("%s as %s", info, req.Role)
+	}
+	if req.DeployerID != "" {
+		info = fmt.Sprintf("%s by %s", info, req.DeployerID)
+	}
+	info = fmt.Sprintf("%s at %s", info, now)
+	content := info
+	if req.DeployerID != "" {
+		content = fmt.Sprintf("%s\ndeployer: %s", content, req.DeployerID)
+	}
+	if len(req.Tags) > 0 {
+		content = fmt.Sprintf("%s\ntags: %s", content, strings.Join(req.Tags, ", "))
+	}
+	return strings.Replace(info, " ", "_", -1) + ".txt", []byte(content)
+}
@@ -0,0 +1,128 @@
+package dewy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// releaseKeysKey is the cache key of the cache keys of artifacts deployed to release directories,
+// to know the version of each release.
+const releaseKeysKey = "releases.json"
+
+// tagsKey is the cache key of the tags of deployed artifacts by the cache keys,
+// to deploy them again with the tag such as by redeploy.
+const tagsKey = "tags.json"
+
+// releaseKeys returns the cache keys of artifacts by the names of release directories.
+func (d *Dewy) releaseKeys() map[string]string {
+	keys := map[string]string{}
+	b, err := d.state.Read(releaseKeysKey)
+	if err != nil {
+		return keys
+	}
+	_ = json.Unmarshal(b, &keys)
+	return keys
+}
+
+// recordRelease records the cache key of the artifact deployed to the release directory.
+func (d *Dewy) recordRelease(release, key string) error {
+	keys := d.releaseKeys()
+	keys[filepath.Base(release)] = key
+	b, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return d.state.Write(releaseKeysKey, b)
+}
+
+// releaseKeyOf returns the cache key of the artifact deployed to the release directory, or empty if unknown.
+func (d *Dewy) releaseKeyOf(release string) string {
+	return d.releaseKeys()[filepath.Base(release)]
+}
+
+// tags returns the tags of deployed artifacts by the cache keys.
+func (d *Dewy) tags() map[string]string {
+	tags := map[string]string{}
+	b, err := d.state.Read(tagsKey)
+	if err != nil {
+		return tags
+	}
+	_ = json.Unmarshal(b, &tags)
+	return tags
+}
+
+// recordTag records the tag of the deployed artifact, forgetting tags of artifacts no longer in release directories.
+func (d *Dewy) recordTag(key, tag string) error {
+	keep := map[string]bool{key: true}
+	for _, k := range d.releaseKeys() {
+		keep[k] = true
+	}
+	tags := d.tags()
+	for k := range tags {
+		if !keep[k] {
+			delete(tags, k)
+		}
+	}
+	tags[key] = tag
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+	return d.state.Write(tagsKey, b)
+}
+
+// tagOf returns the tag of the deployed artifact of the cache key, or empty if unknown.
+func (d *Dewy) tagOf(key string) string {
+	return d.tags()[key]
+}
+
+// release is the release directory with the modification time.
+type release struct {
+	path    string
+	modTime time.Time
+}
+
+// listReleases returns release directories ordered from the newest by the modification time,
+// as content addressed releases are not named by time. Directories being extracted are excluded.
+func (d *Dewy) listReleases() ([]release, error) {
+	dir := d.releasesPath()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var releases []release
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasSuffix(e.Name(), ".tmp") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, release{path: filepath.Join(dir, e.Name()), modTime: info.ModTime()})
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		if !releases[i].modTime.Equal(releases[j].modTime) {
+			return releases[i].modTime.After(releases[j].modTime)
+		}
+		return releases[i].path > releases[j].path
+	})
+	return releases, nil
+}
+
+// forgetReleases removes the cache keys of the removed release directories.
+func (d *Dewy) forgetReleases(removed []string) error {
+	keys := d.releaseKeys()
+	for _, p := range removed {
+		delete(keys, filepath.Base(p))
+	}
+	b, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return d.state.Write(releaseKeysKey, b)
+}
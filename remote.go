@@ -0,0 +1,269 @@
+package dewy
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// RemoteHost is the host to deploy the release over SSH.
+type RemoteHost struct {
+	// Addr is the address of the host as host or host:port.
+	Addr string
+	// User is the SSH user.
+	User string
+	// KeyFile is the path of the SSH private key.
+	KeyFile string
+	// KnownHostsFile is the path of known_hosts to verify the host key.
+	KnownHostsFile string
+	// Root is the directory to deploy on the host. The local root is used if empty.
+	Root string
+	// HealthCheck is the command to check the health on the host after the symlink swap.
+	HealthCheck string
+}
+
+// ParseRemoteHost parses the remote host as [user@]host[:port].
+func ParseRemoteHost(s string) RemoteHost {
+	h := RemoteHost{Addr: s}
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		h.User = s[:i]
+		h.Addr = s[i+1:]
+	}
+	return h
+}
+
+func (h RemoteHost) addr() string {
+	if _, _, err := net.SplitHostPort(h.Addr); err == nil {
+		return h.Addr
+	}
+	return net.JoinHostPort(h.Addr, "22")
+}
+
+func (h RemoteHost) clientConfig() (*ssh.ClientConfig, error) {
+	home, _ := os.UserHomeDir()
+	keyFile := h.KeyFile
+	if keyFile == "" {
+		keyFile = filepath.Join(home, ".ssh", "id_rsa")
+	}
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	knownHostsFile := h.KnownHostsFile
+	if knownHostsFile == "" {
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, err
+	}
+	user := h.User
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	}, nil
+}
+
+// deployRemotes uploads the release to the remote hosts and swaps their symlinks.
+// The hosts that failed are recorded to retry them with the release.
+func (d *Dewy) deployRemotes(release string, hosts []RemoteHost) error {
+	deploy := d.remoteDeploy
+	if deploy == nil {
+		deploy = d.deployRemote
+	}
+	var failed []RemoteHost
+	var errs []string
+	for _, h := range hosts {
+		if err := deploy(h, release); err != nil {
+			log.Printf("[ERROR] Remote deploy failure on %s: %s", h.Addr, err)
+			failed = append(failed, h)
+			errs = append(errs, fmt.Sprintf("%s: %s", h.Addr, err))
+		}
+	}
+	d.Lock()
+	d.remoteRelease = release
+	d.failedRemotes = failed
+	d.Unlock()
+	if len(errs) > 0 {
+		return fmt.Errorf("remote deploy failed: %s", strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// retryRemotes deploys the current release again to the remote hosts that failed,
+// as the local deploy is skipped for the same version.
+func (d *Dewy) retryRemotes() error {
+	d.RLock()
+	release, hosts := d.remoteRelease, d.failedRemotes
+	d.RUnlock()
+	if len(hosts) == 0 {
+		return nil
+	}
+	log.Printf("[INFO] Retry remote deploy of %s to %d hosts", release, len(hosts))
+	return d.deployRemotes(release, hosts)
+}
+
+func (d *Dewy) deployRemote(h RemoteHost, release string) error {
+	cc, err := h.clientConfig()
+	if err != nil {
+		return err
+	}
+	client, err := ssh.Dial("tcp", h.addr(), cc)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	root := h.Root
+	if root == "" {
+		root = d.root
+	}
+	dst := path.Join(root, releasesDir, filepath.Base(release))
+	link := path.Join(root, d.symlinkName())
+
+	log.Printf("[INFO] Upload release to %s:%s", h.Addr, dst)
+	if err := uploadDir(client, release, dst); err != nil {
+		return err
+	}
+
+	prev, _ := runRemote(client, fmt.Sprintf("readlink %s", shellQuote(link)))
+	prev = strings.TrimSpace(prev)
+
+	if err := swapRemoteSymlink(client, dst, link); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Create symlink to %s from %s on %s", link, dst, h.Addr)
+
+	if h.HealthCheck == "" {
+		return nil
+	}
+	out, err := runRemote(client, h.HealthCheck)
+	if err == nil {
+		return nil
+	}
+	log.Printf("[ERROR] Health check failure on %s: %s", h.Addr, strings.TrimSpace(out))
+
+	if prev == "" {
+		return fmt.Errorf("health check failed and no release to roll back: %w", err)
+	}
+	if rerr := swapRemoteSymlink(client, prev, link); rerr != nil {
+		return fmt.Errorf("health check failed and rollback failed: %w", rerr)
+	}
+	log.Printf("[INFO] Rollback symlink to %s on %s", prev, h.Addr)
+
+	return fmt.Errorf("health check failed: %w", err)
+}
+
+func runRemote(client *ssh.Client, cmd string) (string, error) {
+	s, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer s.Close()
+	out, err := s.CombinedOutput(cmd)
+
+	return string(out), err
+}
+
+func swapRemoteSymlink(client *ssh.Client, target, link string) error {
+	tmp := link + ".tmp"
+	cmd := fmt.Sprintf("ln -sfn %s %s && mv -Tf %s %s", shellQuote(target), shellQuote(tmp), shellQuote(tmp), shellQuote(link))
+	if out, err := runRemote(client, cmd); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(out))
+	}
+	return nil
+}
+
+// uploadDir streams the directory as tar to the remote host and extracts it.
+func uploadDir(client *ssh.Client, src, dst string) error {
+	s, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	pr, pw := io.Pipe()
+	s.Stdin = pr
+	var stderr bytes.Buffer
+	s.Stderr = &stderr
+
+	go func() {
+		pw.CloseWithError(writeTar(pw, src))
+	}()
+
+	cmd := fmt.Sprintf("mkdir -p %s && tar -xf - -C %s", shellQuote(dst), shellQuote(dst))
+	if err := s.Run(cmd); err != nil {
+		pr.Close()
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+func writeTar(w io.Writer, src string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		link := ""
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,102 @@
+package dewy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/linyows/dewy/kvs"
+)
+
+func TestParseRemoteHost(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RemoteHost
+		addr string
+	}{
+		{"app1.example.com", RemoteHost{Addr: "app1.example.com"}, "app1.example.com:22"},
+		{"deploy@app1.example.com:2222", RemoteHost{Addr: "app1.example.com:2222", User: "deploy"}, "app1.example.com:2222"},
+	}
+	for _, tt := range tests {
+		got := ParseRemoteHost(tt.in)
+		if got != tt.want {
+			t.Errorf("got %+v, want %+v", got, tt.want)
+		}
+		if got.addr() != tt.addr {
+			t.Errorf("got %s, want %s", got.addr(), tt.addr)
+		}
+	}
+}
+
+func TestRunRetriesRemotes(t *testing.T) {
+	data := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	c := DefaultConfig()
+	c.Command = ASSETS
+	c.Registry = ts.URL + "/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	c.Notifiers = []string{"none"}
+	c.RemoteHosts = []RemoteHost{{Addr: "app1"}, {Addr: "app2"}}
+	d, err := New(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.root = t.TempDir()
+
+	var mu sync.Mutex
+	deployed := map[string]int{}
+	down := "app2"
+	d.remoteDeploy = func(h RemoteHost, release string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deployed[h.Addr]++
+		if h.Addr == down {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	if err := d.Run(); err != nil {
+		t.Fatalf("failure of a remote host should not fail the local deploy: %s", err)
+	}
+	if !kvs.IsFileExist(filepath.Join(d.currentPath(), "app")) {
+		t.Fatal("artifact is not deployed locally")
+	}
+	if key, _ := d.state.Read(currentKey); string(key) != "v1-app.tar.gz" {
+		t.Errorf("current version should be recorded, got %q", key)
+	}
+	release, _ := d.readCurrent()
+
+	// the failed host is retried without deploying locally again
+	if err := d.Run(); err == nil {
+		t.Error("failure of the retry should be returned")
+	}
+	down = ""
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if deployed["app1"] != 1 || deployed["app2"] != 3 {
+		t.Errorf("unexpected remote deploys: %v", deployed)
+	}
+	if got, _ := d.readCurrent(); got != release {
+		t.Errorf("local release should not be deployed again, got %s", got)
+	}
+	files, _ := os.ReadDir(d.releasesPath())
+	if len(files) != 1 {
+		t.Errorf("got %d releases, want 1", len(files))
+	}
+}
@@ -0,0 +1,39 @@
+package dewy
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/linyows/dewy/httputil"
+)
+
+// defaultDownloadRetryInterval is the first interval to retry if not configured, doubled for each retry.
+const defaultDownloadRetryInterval = time.Second
+
+// retry runs f, retrying up to DownloadRetries times with exponential backoff and jitter while it fails
+// with a retryable error, such as fetching the release and downloading the artifact.
+// Rate limits are retried after they reset.
+func (d *Dewy) retry(ctx context.Context, name string, f func() error) error {
+	interval := d.config.DownloadRetryInterval
+	if interval <= 0 {
+		interval = defaultDownloadRetryInterval
+	}
+	for i := 0; ; i++ {
+		err := f()
+		if err == nil || i >= d.config.DownloadRetries {
+			return err
+		}
+		wait, ok := httputil.RetryWait(err, interval, i)
+		if !ok {
+			return err
+		}
+		log.Printf("[WARN] %s failure, retry %d/%d in %s: %s", name, i+1, d.config.DownloadRetries, wait, err)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package dewy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunRetriesDownload(t *testing.T) {
+	data := artifact(t, "app.tar.gz", map[string]string{"app": "v1"})
+	tests := []struct {
+		name      string
+		status    int
+		failures  int32
+		wantErr   bool
+		wantCalls int32
+	}{
+		{"server errors", http.StatusServiceUnavailable, 2, false, 3},
+		{"too many server errors", http.StatusBadGateway, 5, true, 4},
+		{"client error", http.StatusNotFound, 2, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var heads, gets int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				if r.Method == http.MethodHead {
+					// the release fetch also fails once
+					if atomic.AddInt32(&heads, 1) == 1 {
+						w.WriteHeader(http.StatusInternalServerError)
+					}
+					return
+				}
+				if atomic.AddInt32(&gets, 1) <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				if _, err := w.Write(data); err != nil {
+					t.Error(err)
+				}
+			}))
+			defer ts.Close()
+
+			c := DefaultConfig()
+			c.Command = ASSETS
+			c.Registry = ts.URL + "/app.tar.gz"
+			c.Cache.Dir = t.TempDir()
+			c.DownloadRetries = 3
+			c.DownloadRetryInterval = 10 * time.Millisecond
+			c.Notifiers = []string{"none"}
+			d, err := New(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d.root = t.TempDir()
+
+			err = d.Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := atomic.LoadInt32(&gets); got != tt.wantCalls {
+				t.Errorf("got %d downloads, want %d", got, tt.wantCalls)
+			}
+			list, err := d.cache.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cached := false
+			for _, k := range list {
+				if k != currentKey && k != releaseKey {
+					cached = true
+				}
+			}
+			if cached == tt.wantErr {
+				t.Errorf("got cached %t, want %t: %v", cached, !tt.wantErr, list)
+			}
+		})
+	}
+}
+
+func TestRetryCancel(t *testing.T) {
+	d := &Dewy{config: Config{DownloadRetries: 3, DownloadRetryInterval: time.Hour}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	calls := 0
+	err := d.retry(ctx, "Download", func() error {
+		calls++
+		return syscall.ECONNRESET
+	})
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("got %v, want canceled and the last error", err)
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+}
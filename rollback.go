@@ -0,0 +1,83 @@
+package dewy
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+)
+
+// Rollback links the current symlink to the release before the current one in the releases directory,
+// and records its version as the current version. The version of the newest release is quarantined not to be
+// deployed again until a different version is released, and the server of Dewy running on the host is restarted by SIGHUP.
+func (d *Dewy) Rollback() error {
+	unlock, err := d.lockDeploy()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := d.readCurrent()
+	if err != nil {
+		return fmt.Errorf("no current release to roll back: %w", err)
+	}
+	releases, err := d.listReleases()
+	if err != nil {
+		return err
+	}
+	d.previousRelease = previousReleaseOf(releases, current)
+	if d.previousRelease == "" {
+		return fmt.Errorf("no release older than %s in %s to roll back to", current, d.releasesPath())
+	}
+	release := d.previousRelease
+	if err := d.rollback(); err != nil {
+		return err
+	}
+	d.previousRelease = current
+
+	// the newest release is of the version the registry serves, even if rolling back again
+	key := d.releaseKeyOf(releases[0].path)
+	if key == "" {
+		if b, err := d.state.Read(currentKey); err == nil {
+			key = string(b)
+		}
+	}
+	if key != "" {
+		if err := d.state.Write(quarantineKey, []byte(key)); err != nil {
+			return err
+		}
+		log.Printf("[INFO] %s is quarantined by the rollback", key)
+	}
+	if key := d.releaseKeyOf(release); key != "" {
+		if err := d.state.Write(currentKey, []byte(key)); err != nil {
+			return err
+		}
+	} else if _, err := d.state.Read(currentKey); err == nil {
+		// the version of the release is unknown
+		if err := d.state.Delete(currentKey); err != nil {
+			return err
+		}
+	}
+
+	if d.config.Command == SERVER && d.isServerRunning {
+		if err := d.restartServer(); err != nil {
+			return err
+		}
+		return d.restartSidecars()
+	}
+	return d.restartRunningServer()
+}
+
+// previousReleaseOf returns the newest release older than the current one in releases listed by listReleases,
+// or empty if none.
+func previousReleaseOf(releases []release, current string) string {
+	for i, r := range releases {
+		if r.path != filepath.Clean(current) {
+			continue
+		}
+		if i+1 < len(releases) {
+			return releases[i+1].path
+		}
+		break
+	}
+	return ""
+}
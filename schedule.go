@@ -0,0 +1,67 @@
+package dewy
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carlescere/scheduler"
+	"github.com/robfig/cron/v3"
+)
+
+// parseCron parses the cron expression of 5 fields in the local time like "*/5 9-17 * * 1-5",
+// or a descriptor like "@hourly".
+func parseCron(expr string) (cron.Schedule, error) {
+	s, err := cron.ParseStandard(strings.TrimSpace(expr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule: %q: %w", expr, err)
+	}
+	return s, nil
+}
+
+// runCron runs f immediately, such as to start the server, and then at times of the schedule until the job quits,
+// skipping a time while f is still running. The job is compatible with jobs of the scheduler to quit by Quit.
+func runCron(c cron.Schedule, f func()) *scheduler.Job {
+	j := &scheduler.Job{Quit: make(chan bool, 1), SkipWait: make(chan bool, 1)}
+	j.SkipWait <- true
+	var running sync.Mutex
+	run := func() {
+		if !running.TryLock() {
+			return
+		}
+		defer running.Unlock()
+		f()
+	}
+	go func() {
+		for {
+			// never fires if no time matches
+			var timer <-chan time.Time
+			if next := c.Next(time.Now()); !next.IsZero() {
+				timer = time.After(time.Until(next))
+			}
+			select {
+			case <-j.Quit:
+				return
+			case <-j.SkipWait:
+				go run()
+			case <-timer:
+				go run()
+			}
+		}
+	}()
+	return j
+}
+
+// jitterDelay returns a random delay up to the jitter, bounded by the bound such as the interval,
+// so that a delayed cycle never overlaps the next one. The random source is seeded per process.
+func jitterDelay(jitter, bound time.Duration) time.Duration {
+	if jitter > bound {
+		jitter = bound
+	}
+	if jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
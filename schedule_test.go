@@ -0,0 +1,114 @@
+package dewy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"* * * * *", false},
+		{"*/5 9-17 * * 1-5", false},
+		{"0,30 0 1,15 */3 0", false},
+		{"5/15 * * * 6", false},
+		{"@hourly", false},
+		{"* * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"* * * * 7", true},
+		{"*/0 * * * *", true},
+		{"5-1 * * * *", true},
+		{"a * * * *", true},
+		{"@reboot", true},
+	}
+	for _, tt := range tests {
+		if _, err := parseCron(tt.expr); (err != nil) != tt.wantErr {
+			t.Errorf("%q: unexpected error: %v", tt.expr, err)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// Monday
+	base := time.Date(2024, 1, 1, 10, 2, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 1, 10, 3, 0, 0, time.UTC)},
+		{"*/5 * * * *", time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2024, 1, 2, 9, 30, 0, 0, time.UTC)},
+		{"0 9-17 * * 1-5", time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"0 0 * * 6", time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// either of restricted days matches like cron
+		{"0 0 15 * 3", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Next(base); !got.Equal(tt.want) {
+			t.Errorf("%q: got %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestRunCron(t *testing.T) {
+	c, err := parseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran := make(chan struct{}, 2)
+	j := runCron(c, func() { ran <- struct{}{} })
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job should run when started")
+	}
+	j.Quit <- true
+	j.SkipWait <- true
+	select {
+	case <-ran:
+		t.Error("job should not run after quit")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestJitterDelay(t *testing.T) {
+	tests := []struct {
+		jitter time.Duration
+		bound  time.Duration
+		max    time.Duration
+	}{
+		{10 * time.Second, time.Minute, 10 * time.Second},
+		{time.Minute, 10 * time.Second, 10 * time.Second},
+		{time.Second, 0, 0},
+		{0, time.Minute, 0},
+	}
+	for _, tt := range tests {
+		var maxGot time.Duration
+		for i := 0; i < 10000; i++ {
+			got := jitterDelay(tt.jitter, tt.bound)
+			if got < 0 || (tt.max > 0 && got >= tt.max) || (tt.max == 0 && got != 0) {
+				t.Fatalf("jitter %s bound %s: got %s out of bounds", tt.jitter, tt.bound, got)
+			}
+			if got > maxGot {
+				maxGot = got
+			}
+		}
+		if tt.max > 0 && maxGot < tt.max/2 {
+			t.Errorf("jitter %s bound %s: delays should spread, got at most %s", tt.jitter, tt.bound, maxGot)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package dewy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/carlescere/scheduler"
+	"github.com/linyows/dewy/registry"
+	"github.com/linyows/dewy/storage"
+	"github.com/linyows/dewy/verify"
+)
+
+const (
+	// defaultSelfUpdateInterval is the interval to check updates of Dewy if not configured.
+	defaultSelfUpdateInterval = time.Hour
+	// selfVersionTimeout is the timeout to run the new binary for the version check.
+	selfVersionTimeout = 10 * time.Second
+)
+
+// SelfUpdateConfig is the configuration to update the Dewy binary itself, separately from the app.
+type SelfUpdateConfig struct {
+	// Registry is the registry of Dewy releases such as github_release://linyows/dewy. Disabled if empty.
+	Registry string
+	// Artifact is the name of the archive containing the dewy binary. It is found by the OS and the architecture if empty.
+	Artifact string
+	// Interval is the interval to check updates. An hour if zero.
+	Interval time.Duration
+	// Version is the version of the running Dewy, which is not updated to the same tag.
+	Version string
+	// ChecksumArtifact is the name of the checksums file published with Dewy releases, such as checksums.txt.
+	ChecksumArtifact string
+	// Verifiers verify the downloaded artifact of Dewy in order, instead of verifiers of the app.
+	Verifiers []verify.Verifier
+}
+
+// verifier returns the chain verifying the artifact of Dewy. The running binary is never replaced
+// with an unverified download, so either the checksums file or verifiers are required.
+func (c SelfUpdateConfig) verifier() (verify.Chain, error) {
+	var chain verify.Chain
+	if c.ChecksumArtifact != "" {
+		chain = append(chain, &verify.Checksums{Name: c.ChecksumArtifact, Fetch: storage.Fetch})
+	}
+	chain = append(chain, c.Verifiers...)
+	if len(chain) == 0 {
+		return nil, errors.New("self update requires the checksums file or verifiers to verify Dewy")
+	}
+	return chain, nil
+}
+
+// startSelfUpdate schedules checking updates of Dewy.
+func (d *Dewy) startSelfUpdate() error {
+	i := d.config.SelfUpdate.Interval
+	if i <= 0 {
+		i = defaultSelfUpdateInterval
+	}
+	d.selfUpdated = make(chan string, 1)
+	var err error
+	d.selfJob, err = scheduler.Every(int(i / time.Second)).Seconds().NotImmediately().Run(func() {
+		if err := d.selfUpdate(); err != nil {
+			log.Printf("[ERROR] Self update failure: %#v", err)
+		}
+	})
+	return err
+}
+
+// selfUpdate replaces the Dewy binary with the verified new release after checking its version,
+// then Dewy is executed again.
+func (d *Dewy) selfUpdate() error {
+	c := d.config.SelfUpdate
+	chain, err := c.verifier()
+	if err != nil {
+		return err
+	}
+	artifact, err := expandArtifact(c.Artifact)
+	if err != nil {
+		return err
+	}
+	res, err := d.selfRegistry.Current(&registry.CurrentRequest{
+		Arch:         runtime.GOARCH,
+		OS:           runtime.GOOS,
+		ArtifactName: artifact,
+	})
+	if err != nil {
+		return err
+	}
+	if sameVersion(res.Tag, c.Version) || res.Tag == d.selfUpdatedTag {
+		log.Printf("[DEBUG] Dewy is up to date with %s", res.Tag)
+		return nil
+	}
+	log.Printf("[INFO] Update Dewy to %s from %s", res.Tag, res.ArtifactURL)
+
+	buf := new(bytes.Buffer)
+	err = d.downloads.do(func() error {
+		if err := storage.Fetch(res.ArtifactURL, buf); err != nil {
+			return err
+		}
+		a := &verify.Artifact{Tag: res.Tag, URL: res.ArtifactURL}
+		return chain.Verify(context.Background(), a, bytes.NewReader(buf.Bytes()))
+	})
+	if err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "dewy-update-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, artifactName(res.ArtifactURL))
+	if err := os.WriteFile(archive, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	if err := d.extract(archive, filepath.Join(dir, "x")); err != nil {
+		return err
+	}
+	bin, err := findBinary(filepath.Join(dir, "x"), filepath.Base(exe))
+	if err != nil {
+		return err
+	}
+	if err := d.replaceBinary(exe, bin, res.Tag); err != nil {
+		return err
+	}
+	d.selfUpdatedTag = res.Tag
+
+	log.Printf("[INFO] Dewy is updated to %s, execute it again", res.Tag)
+	d.selfUpdated <- res.Tag
+	return nil
+}
+
+// quitSelfUpdate stops checking updates of Dewy.
+func (d *Dewy) quitSelfUpdate() {
+	if d.selfJob != nil {
+		d.selfJob.Quit <- true
+	}
+}
+
+// reexec executes the updated Dewy in place of this process, and rolls back the binary if it fails.
+func (d *Dewy) reexec() {
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err == nil {
+		err = execSelf(exe)
+	}
+	log.Printf("[ERROR] Execute updated Dewy failure: %#v", err)
+	if exe != "" {
+		if err := os.Rename(exe+".old", exe); err != nil {
+			log.Printf("[ERROR] Roll back Dewy failure: %#v", err)
+		}
+	}
+}
+
+// sameVersion reports whether the tag is the version, ignoring the "v" prefix.
+func sameVersion(tag, version string) bool {
+	return strings.TrimPrefix(tag, "v") == strings.TrimPrefix(version, "v")
+}
+
+// findBinary returns the path of the file named name in the extracted directory.
+func findBinary(dir, name string) (string, error) {
+	var found string
+	err := filepath.WalkDir(dir, func(p string, e os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !e.IsDir() && e.Name() == name {
+			found = p
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if found == "" {
+		return "", fmt.Errorf("%s is not found in the artifact", name)
+	}
+	return found, nil
+}
+
+// replaceBinary replaces exe with bin if bin reports the version of the tag.
+// The old binary is kept as exe.old to roll back.
+func (d *Dewy) replaceBinary(exe, bin, tag string) error {
+	tmp := exe + ".new"
+	if err := copyExecutable(bin, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := d.checkVersion(tmp, tag); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(exe, exe+".old"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		if rerr := os.Rename(exe+".old", exe); rerr != nil {
+			log.Printf("[ERROR] Roll back Dewy failure: %#v", rerr)
+		}
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func copyExecutable(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// checkVersion runs the binary with --version and checks it reports the version of the tag.
+// The exit status is ignored if the version is reported, as the version may be printed with a non-zero status.
+func (d *Dewy) checkVersion(bin, tag string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), selfVersionTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, bin, "--version")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := d.runCommand(cmd)
+	if v := strings.TrimPrefix(tag, "v"); !strings.Contains(out.String(), v) {
+		if err != nil {
+			return fmt.Errorf("version check of %s failed: %w", tag, err)
+		}
+		return fmt.Errorf("version check of %s failed: %s", tag, strings.TrimSpace(out.String()))
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package dewy
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/linyows/dewy/verify"
+)
+
+func TestReplaceBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not executable on windows")
+	}
+	tests := []struct {
+		name    string
+		tag     string
+		wantErr bool
+	}{
+		{"same version", "v1.2.3", false},
+		{"different version", "v1.3.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			exe := filepath.Join(dir, "dewy")
+			if err := os.WriteFile(exe, []byte("old"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			bin := filepath.Join(t.TempDir(), "dewy")
+			script := "#!/bin/sh\necho 'dewy version 1.2.3 [abc, 2024-01-01]' >&2\n"
+			if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			d := &Dewy{}
+			err := d.replaceBinary(exe, bin, tt.tag)
+			got, _ := os.ReadFile(exe)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("want error")
+				}
+				if string(got) != "old" {
+					t.Error("binary failing the version check should not be installed")
+				}
+				if _, err := os.Stat(exe + ".new"); !os.IsNotExist(err) {
+					t.Error("new binary should be removed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != script {
+				t.Error("binary is not replaced")
+			}
+			if old, _ := os.ReadFile(exe + ".old"); string(old) != "old" {
+				t.Error("old binary should be kept to roll back")
+			}
+		})
+	}
+}
+
+func TestSameVersion(t *testing.T) {
+	tests := []struct {
+		tag     string
+		version string
+		want    bool
+	}{
+		{"v1.2.3", "1.2.3", true},
+		{"v1.2.3", "v1.2.3", true},
+		{"v1.2.4", "1.2.3", false},
+		{"v1.2.3", "dev", false},
+	}
+	for _, tt := range tests {
+		if got := sameVersion(tt.tag, tt.version); got != tt.want {
+			t.Errorf("sameVersion(%s, %s) = %v, want %v", tt.tag, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestSelfUpdateVerifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SelfUpdateConfig
+		want    int
+		wantErr bool
+	}{
+		{"checksums", SelfUpdateConfig{ChecksumArtifact: "checksums.txt"}, 1, false},
+		{"verifiers", SelfUpdateConfig{Verifiers: []verify.Verifier{&verify.Size{Max: 1}}}, 1, false},
+		{"unverified", SelfUpdateConfig{}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := tt.config.verifier()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(chain) != tt.want {
+				t.Errorf("got %d verifiers, want %d", len(chain), tt.want)
+			}
+		})
+	}
+
+	c := DefaultConfig()
+	c.Registry = "https://example.com/app.tar.gz"
+	c.Cache.Dir = t.TempDir()
+	c.SelfUpdate.Registry = "github_release://linyows/dewy"
+	if _, err := New(c); err == nil {
+		t.Error("self update without verification should be refused")
+	}
+}
@@ -0,0 +1,73 @@
+package dewy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/linyows/dewy/kvs"
+)
+
+// sharedDir is the directory of data kept across deploys, next to the releases directory.
+const sharedDir = "shared"
+
+// validateSharedPath validates the path relative to the release directory.
+func validateSharedPath(p string) error {
+	c := filepath.Clean(p)
+	if p == "" || filepath.IsAbs(p) || c == "." || c == ".." || strings.HasPrefix(c, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid shared path: %s", p)
+	}
+	return nil
+}
+
+// sharedPath returns the directory of data kept across deploys.
+func (d *Dewy) sharedPath() string {
+	return filepath.Join(filepath.Dir(d.releasesPath()), sharedDir)
+}
+
+// linkShared replaces the shared paths in the release with symlinks into the shared directory.
+// A path missing in the shared directory is moved from the release if it exists, or created as a directory.
+func (d *Dewy) linkShared(release string) error {
+	mode := d.config.DirMode
+	if mode == 0 {
+		mode = kvs.DefaultDirMode
+	}
+	for _, p := range d.config.SharedPaths {
+		target := filepath.Join(d.sharedPath(), p)
+		link := filepath.Join(release, p)
+		if err := os.MkdirAll(filepath.Dir(target), mode); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(link), mode); err != nil {
+			return err
+		}
+
+		fi, err := os.Lstat(link)
+		shipped := err == nil && fi.Mode()&os.ModeSymlink == 0
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			if shipped {
+				if err := os.Rename(link, target); err != nil {
+					return err
+				}
+			} else if err := os.Mkdir(target, mode); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(link); err != nil {
+			return err
+		}
+
+		// relative, so that it is resolved in the chroot and after renaming the release
+		rel, err := filepath.Rel(filepath.Dir(link), target)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(rel, link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package dewy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/linyows/dewy/kvs"
+)
+
+func TestLinkShared(t *testing.T) {
+	root := t.TempDir()
+	kv := &kvs.File{}
+	kv.Default()
+	if err := kv.SetDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Write("v1.0.0-app.tar.gz", artifact(t, "app.tar.gz", map[string]string{"app": "v1", "app.db": "seed"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Write("v1.0.1-app.tar.gz", artifact(t, "app.tar.gz", map[string]string{"app": "v2", "app.db": "seed"})); err != nil {
+		t.Fatal(err)
+	}
+	d := &Dewy{root: root, cache: kv, state: kv, config: Config{Command: ASSETS, SharedPaths: []string{"app.db", "public/uploads"}}}
+
+	// first deploy creates the shared paths
+	if err := d.deploy("v1.0.0-app.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(root, sharedDir, "app.db")); err != nil || string(got) != "seed" {
+		t.Errorf("shipped path should be moved to the shared directory: %q, %v", got, err)
+	}
+	if fi, err := os.Stat(filepath.Join(root, sharedDir, "public", "uploads")); err != nil || !fi.IsDir() {
+		t.Errorf("missing path should be created as a directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.currentPath(), "app.db"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(d.currentPath(), "public", "uploads", "a.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// subsequent deploy links the release to the kept data
+	time.Sleep(time.Second)
+	if err := d.deploy("v1.0.1-app.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(d.currentPath(), "app")); err != nil || string(got) != "v2" {
+		t.Fatalf("new release is not deployed: %q, %v", got, err)
+	}
+	if got, err := os.ReadFile(filepath.Join(d.currentPath(), "app.db")); err != nil || string(got) != "data" {
+		t.Errorf("shared file should be kept: %q, %v", got, err)
+	}
+	if !kvs.IsFileExist(filepath.Join(d.currentPath(), "public", "uploads", "a.png")) {
+		t.Error("shared directory should be kept")
+	}
+
+	for _, p := range []string{"/var/data", "../data", ".", ""} {
+		if err := validateSharedPath(p); err == nil {
+			t.Errorf("shared path %q should be invalid", p)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package dewy
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// sidecarHealthTimeout is the time to wait for the health check of a sidecar to succeed.
+	sidecarHealthTimeout = 30 * time.Second
+	// sidecarHealthInterval is the interval to retry the health check of a sidecar.
+	sidecarHealthInterval = time.Second
+)
+
+// Sidecar is a command started and restarted together with the server on deploy.
+type Sidecar struct {
+	// Name is the name of the sidecar.
+	Name string
+	// Command is the shell command to run the sidecar.
+	Command string
+	// HealthCheck is the shell command succeeding when the sidecar is healthy. No check if empty.
+	HealthCheck string
+}
+
+// ParseSidecars parses sidecars like "exporter:./current/exporter --port 9100" in order,
+// with health checks by name.
+func ParseSidecars(commands []string, healthChecks map[string]string) ([]Sidecar, error) {
+	var sidecars []Sidecar
+	names := map[string]bool{}
+	for _, c := range commands {
+		name, command, ok := strings.Cut(c, ":")
+		if !ok || name == "" || strings.TrimSpace(command) == "" {
+			return nil, fmt.Errorf("invalid sidecar: %s", c)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate sidecar: %s", name)
+		}
+		names[name] = true
+		sidecars = append(sidecars, Sidecar{Name: name, Command: command, HealthCheck: healthChecks[name]})
+	}
+	for name := range healthChecks {
+		if !names[name] {
+			return nil, fmt.Errorf("health check for unknown sidecar: %s", name)
+		}
+	}
+	return sidecars, nil
+}
+
+// restartSidecars restarts sidecars in order in the working directory of the current release,
+// and waits for each to be healthy before the next.
+func (d *Dewy) restartSidecars() error {
+	if len(d.config.Commands) == 0 {
+		return nil
+	}
+	release, _ := d.readCurrent()
+	dir, err := d.workDir(release)
+	if err != nil {
+		return err
+	}
+
+	d.Lock()
+	if d.sidecars == nil {
+		d.sidecars = map[string]*foreground{}
+	}
+	d.Unlock()
+
+	for _, s := range d.config.Commands {
+		cmd := shellCommand(s.Command)
+		sc := &StarterConfig{command: cmd.Args[0], args: cmd.Args[1:], dir: dir}
+		d.Lock()
+		f, ok := d.sidecars[s.Name]
+		if !ok {
+			f = newForeground(sc, d.reaper)
+			d.sidecars[s.Name] = f
+		}
+		d.Unlock()
+		// the working directory may change by the release
+		f.config = sc
+
+		log.Printf("[INFO] Restart sidecar %s", s.Name)
+		if err := f.restart(serverStartWait); err != nil {
+			return fmt.Errorf("sidecar %s failed to start: %w", s.Name, err)
+		}
+		if err := d.checkSidecar(s, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSidecar retries the health check of the sidecar until it succeeds or times out.
+func (d *Dewy) checkSidecar(s Sidecar, dir string) error {
+	if s.HealthCheck == "" {
+		return nil
+	}
+	deadline := time.Now().Add(sidecarHealthTimeout)
+	for {
+		cmd := shellCommand(s.HealthCheck)
+		cmd.Dir = dir
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		err := d.runCommand(cmd)
+		if err == nil {
+			log.Printf("[INFO] Sidecar %s is healthy", s.Name)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("sidecar %s is unhealthy: %w: %s", s.Name, err, strings.TrimSpace(out.String()))
+		}
+		log.Printf("[DEBUG] Sidecar %s is not healthy yet: %s", s.Name, err)
+		time.Sleep(sidecarHealthInterval)
+	}
+}
+
+// stopSidecars stops sidecars in reverse order.
+func (d *Dewy) stopSidecars() {
+	for i := len(d.config.Commands) - 1; i >= 0; i-- {
+		s := d.config.Commands[i]
+		d.Lock()
+		f, ok := d.sidecars[s.Name]
+		d.Unlock()
+		if ok {
+			log.Printf("[INFO] Stop sidecar %s", s.Name)
+			f.stop(nil, foregroundStopTimeout)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package dewy
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSidecars(t *testing.T) {
+	tests := []struct {
+		name         string
+		commands     []string
+		healthChecks map[string]string
+		want         []Sidecar
+		wantErr      bool
+	}{
+		{"empty", nil, nil, nil, false},
+		{"in order", []string{"logs:fluent-bit -c a:b.conf", "metrics:exporter"}, map[string]string{"metrics": "curl -sf localhost:9100"},
+			[]Sidecar{{Name: "logs", Command: "fluent-bit -c a:b.conf"}, {Name: "metrics", Command: "exporter", HealthCheck: "curl -sf localhost:9100"}}, false},
+		{"no name", []string{":exporter"}, nil, nil, true},
+		{"no command", []string{"metrics:"}, nil, nil, true},
+		{"duplicate", []string{"metrics:a", "metrics:b"}, nil, nil, true},
+		{"unknown health check", []string{"metrics:a"}, map[string]string{"logs": "true"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSidecars(tt.commands, tt.healthChecks)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %+v, want %+v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRestartSidecars(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not found")
+	}
+	root := t.TempDir()
+	release := filepath.Join(root, releasesDir, "20240101T000000Z")
+	if err := os.MkdirAll(release, 0755); err != nil {
+		t.Fatal(err)
+	}
+	d := &Dewy{root: root, config: Config{
+		WorkDir: "{{.ReleaseDir}}",
+		Commands: []Sidecar{
+			{Name: "first", Command: "echo $$ > first.pid; exec sleep 60", HealthCheck: "test -s first.pid"},
+			{Name: "second", Command: "test -s first.pid && exec sleep 60"},
+		},
+	}}
+	if err := d.linkCurrent(release); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(d.stopSidecars)
+
+	if err := d.restartSidecars(); err != nil {
+		t.Fatal(err)
+	}
+	old := d.sidecars["first"].cmd.Process.Pid
+	if err := d.restartSidecars(); err != nil {
+		t.Fatal(err)
+	}
+	if d.sidecars["first"].cmd.Process.Pid == old {
+		t.Error("sidecar is not restarted")
+	}
+}
@@ -1,6 +1,7 @@
 package dewy
 
 import (
+	"encoding/json"
 	"os"
 	"time"
 
@@ -21,6 +22,17 @@ type StarterConfig struct {
 	statusfile string
 }
 
+// newStarterConfig returns StarterConfig running the server command listening on the port,
+// which receives the restart signal of the configuration on restart.
+func newStarterConfig(c Config, port string, command []string) *StarterConfig {
+	return &StarterConfig{
+		ports:    []string{port},
+		command:  command[0],
+		args:     command[1:],
+		sigonhup: c.RestartSignal,
+	}
+}
+
 // Args for StarterConfig.
 func (c StarterConfig) Args() []string { return c.args }
 
@@ -50,3 +62,13 @@ func (c StarterConfig) SignalOnTERM() os.Signal { return starter.SigFromName(c.s
 
 // StatusFile for StarterConfig.
 func (c StarterConfig) StatusFile() string { return c.statusfile }
+
+// MarshalJSON encodes StarterConfig to print the configuration.
+func (c StarterConfig) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Command string
+		Args    []string
+		Dir     string   `json:",omitempty"`
+		Ports   []string `json:",omitempty"`
+	}{c.command, c.args, c.dir, c.ports})
+}
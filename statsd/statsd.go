@@ -0,0 +1,65 @@
+package statsd
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// Prefix is the prefix of metric names.
+const Prefix = "dewy."
+
+// Client sends metrics and events to a StatsD or DogStatsD endpoint over UDP.
+// All methods of nil Client do nothing.
+type Client struct {
+	conn net.Conn
+	tags []string
+}
+
+// New returns Client sending to addr with tags added to all metrics.
+func New(addr string, tags []string) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, tags: tags}, nil
+}
+
+// Count sends the counter.
+func (c *Client) Count(name string, v int64, tags ...string) {
+	c.send(fmt.Sprintf("%s%s:%d|c", Prefix, name, v), tags)
+}
+
+// Timing sends the timer in milliseconds.
+func (c *Client) Timing(name string, d time.Duration, tags ...string) {
+	c.send(fmt.Sprintf("%s%s:%d|ms", Prefix, name, d.Milliseconds()), tags)
+}
+
+// Event sends the DogStatsD event.
+func (c *Client) Event(title, text string, tags ...string) {
+	text = strings.ReplaceAll(text, "\n", "\\n")
+	c.send(fmt.Sprintf("_e{%d,%d}:%s|%s", len(title), len(text), title, text), tags)
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *Client) send(s string, tags []string) {
+	if c == nil {
+		return
+	}
+	all := append(append([]string{}, c.tags...), tags...)
+	if len(all) > 0 {
+		s = fmt.Sprintf("%s|#%s", s, strings.Join(all, ","))
+	}
+	if _, err := c.conn.Write([]byte(s)); err != nil {
+		log.Printf("[DEBUG] StatsD failure: %s", err)
+	}
+}
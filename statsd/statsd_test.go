@@ -0,0 +1,57 @@
+package statsd
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	c, err := New(pc.LocalAddr().String(), []string{"host:web1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tests := []struct {
+		name string
+		send func()
+		want string
+	}{
+		{"count", func() { c.Count("deploy.success", 1, "tag:v1.0.0") }, "dewy.deploy.success:1|c|#host:web1,tag:v1.0.0"},
+		{"timing", func() { c.Timing("deploy.duration", 1500*time.Millisecond) }, "dewy.deploy.duration:1500|ms|#host:web1"},
+		{"event", func() { c.Event("Deployed", "v1.0.0") }, "_e{8,6}:Deployed|v1.0.0|#host:web1"},
+	}
+	buf := make([]byte, 1024)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.send()
+			if err := pc.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+				t.Fatal(err)
+			}
+			n, _, err := pc.ReadFrom(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(buf[:n]); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilClient(t *testing.T) {
+	var c *Client
+	c.Count("deploy.success", 1)
+	c.Timing("deploy.duration", time.Second)
+	c.Event("Deployed", "v1.0.0")
+	if err := c.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,78 @@
+package dewy
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/linyows/dewy/registry"
+)
+
+// releaseKey is the cache key of the last fetched release.
+const releaseKey = "release.json"
+
+// Release is the metadata of the last fetched release.
+type Release struct {
+	Tag         string    `json:"tag"`
+	ArtifactURL string    `json:"artifact_url"`
+	PublishedAt time.Time `json:"published_at,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Status is the state of Dewy read from the cache.
+type Status struct {
+	// Release is the last fetched release, or nil if no release has been fetched.
+	Release *Release `json:"release"`
+	// Deployed is the cache key of the deployed artifact.
+	Deployed string `json:"deployed"`
+	// Current is the release directory linked from the current symlink.
+	Current string `json:"current"`
+	// Quarantined is the cache key of the artifact quarantined by consecutive failures, a rollback or a deploy.
+	Quarantined string `json:"quarantined,omitempty"`
+}
+
+func (d *Dewy) saveRelease(res *registry.CurrentResponse) error {
+	b, err := json.Marshal(&Release{
+		Tag:         res.Tag,
+		ArtifactURL: res.ArtifactURL,
+		PublishedAt: res.PublishedAt,
+		FetchedAt:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return d.state.Write(releaseKey, b)
+}
+
+// CurrentVersion returns the last fetched release from the cache without accessing the registry.
+func (d *Dewy) CurrentVersion() (*Release, error) {
+	b, err := d.state.Read(releaseKey)
+	if err != nil {
+		return nil, err
+	}
+	r := &Release{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Status returns the state of Dewy from the cache and the deploy root.
+func (d *Dewy) Status() *Status {
+	s := &Status{}
+	if r, err := d.CurrentVersion(); err == nil {
+		s.Release = r
+	} else {
+		log.Printf("[DEBUG] Release is not cached: %s", err)
+	}
+	if key, err := d.state.Read(currentKey); err == nil {
+		s.Deployed = string(key)
+	}
+	if dst, err := d.readCurrent(); err == nil {
+		s.Current = dst
+	}
+	if key, err := d.state.Read(quarantineKey); err == nil {
+		s.Quarantined = string(key)
+	}
+	return s
+}
@@ -1,10 +1,13 @@
 package gcs
 
 import (
+	"context"
 	"io"
 	"log"
 
+	"cloud.google.com/go/storage"
 	"github.com/k1LoW/remote"
+	"google.golang.org/api/option"
 )
 
 const (
@@ -12,14 +15,27 @@ const (
 	SchemeShort = "gs"
 )
 
-type GCS struct{}
+type GCS struct {
+	// CredentialsFile is the path of the service account JSON. Application Default Credentials are used if empty.
+	CredentialsFile string
+}
 
 func New() (*GCS, error) {
 	return &GCS{}, nil
 }
 
+// Fetch downloads the object of the URL such as gs://bucket/name.
 func (s *GCS) Fetch(urlstr string, w io.Writer) error {
-	f, err := remote.Open(urlstr)
+	var opts []remote.Option
+	if s.CredentialsFile != "" {
+		cl, err := storage.NewClient(context.Background(), option.WithCredentialsFile(s.CredentialsFile))
+		if err != nil {
+			return err
+		}
+		defer cl.Close()
+		opts = append(opts, remote.GCSClient(cl))
+	}
+	f, err := remote.Open(urlstr, opts...)
 	if err != nil {
 		return err
 	}
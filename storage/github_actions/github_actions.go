@@ -0,0 +1,70 @@
+package ghactions
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+	"github.com/k1LoW/go-github-client/v55/factory"
+	"github.com/linyows/dewy/httputil"
+	httpstore "github.com/linyows/dewy/storage/http"
+)
+
+const (
+	Scheme = "github_actions"
+	// Artifacts is the path segment of url for workflow artifacts.
+	Artifacts = "artifacts"
+)
+
+// GithubActions fetches workflow artifacts.
+type GithubActions struct {
+	cl *github.Client
+}
+
+func New() (*GithubActions, error) {
+	cl, err := factory.NewGithubClient()
+	if err != nil {
+		return nil, err
+	}
+	return &GithubActions{
+		cl: cl,
+	}, nil
+}
+
+// Fetch fetch artifact.
+func (g *GithubActions) Fetch(urlstr string, w io.Writer) error {
+	ctx := context.Background()
+	// github_actions://owner/repo/artifacts/123456/artifact.zip
+	splitted := strings.Split(strings.TrimPrefix(urlstr, fmt.Sprintf("%s://", Scheme)), "/")
+	if len(splitted) != 5 || splitted[2] != Artifacts {
+		return fmt.Errorf("invalid url: %s", urlstr)
+	}
+	id, err := strconv.ParseInt(splitted[3], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid url: %s", urlstr)
+	}
+
+	u, _, err := g.cl.Actions.DownloadArtifact(ctx, splitted[0], splitted[1], id, true)
+	if err != nil {
+		return err
+	}
+	res, err := httpstore.Get(g.cl.Client(), u.String())
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return httputil.NewStatusError("artifact download failure", res)
+	}
+
+	if err := httpstore.ReadBody(res, splitted[4], w); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Downloaded from %s", urlstr)
+	return nil
+}
@@ -5,13 +5,21 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/http"
+	"path"
 	"strings"
 
 	"github.com/google/go-github/v55/github"
 	"github.com/k1LoW/go-github-client/v55/factory"
+	"github.com/linyows/dewy/httputil"
+	httpstore "github.com/linyows/dewy/storage/http"
 )
 
-const Scheme = "github_release"
+const (
+	Scheme = "github_release"
+	// SourceArchive is the path segment of url for the source tarball of the release.
+	SourceArchive = "tarball"
+)
 
 type GithubRelease struct {
 	cl *github.Client
@@ -32,6 +40,7 @@ func (r *GithubRelease) Fetch(urlstr string, w io.Writer) error {
 	ctx := context.Background()
 	// github_release://owner/repo/tag/v1.0.0/artifact.zip
 	// github_release://owner/repo/latest/artifact.zip
+	// github_release://owner/repo/tarball/v1.0.0/repo-1.0.0.tar.gz
 	splitted := strings.Split(strings.TrimPrefix(urlstr, fmt.Sprintf("%s://", Scheme)), "/")
 	if len(splitted) != 4 && len(splitted) != 5 {
 		return fmt.Errorf("invalid url: %s", urlstr)
@@ -45,6 +54,9 @@ func (r *GithubRelease) Fetch(urlstr string, w io.Writer) error {
 	}
 	tag := splitted[3]
 	artifactName := splitted[4]
+	if splitted[2] == SourceArchive {
+		return r.fetchSourceArchive(ctx, owner, repo, tag, urlstr, w)
+	}
 	page := 1
 	var assetID int64
 L:
@@ -56,15 +68,16 @@ L:
 		if err != nil {
 			return err
 		}
-		for _, r := range releases {
-			if r.GetTagName() != tag {
+		for _, rel := range releases {
+			if rel.GetTagName() != tag {
 				continue
 			}
-			for _, a := range r.Assets {
-				if a.GetName() != artifactName {
-					continue
-				}
-				assetID = a.GetID()
+			id, err := r.findAsset(ctx, owner, repo, rel.GetID(), artifactName)
+			if err != nil {
+				return err
+			}
+			if id != 0 {
+				assetID = id
 				break L
 			}
 		}
@@ -73,24 +86,79 @@ L:
 		}
 		page = res.NextPage
 	}
+	if assetID == 0 {
+		return fmt.Errorf("artifact not found: %s", urlstr)
+	}
 
-	reader, url, err := r.cl.Repositories.DownloadReleaseAsset(ctx, owner, repo, assetID, r.cl.Client())
+	// follow the redirect by ourselves to decode the content consistently
+	reader, url, err := r.cl.Repositories.DownloadReleaseAsset(ctx, owner, repo, assetID, nil)
 	if err != nil {
 		return err
 	}
 	if url != "" {
-		res, err := r.cl.Client().Get(url)
+		res, err := httpstore.Get(r.cl.Client(), url)
 		if err != nil {
 			return err
 		}
 		defer res.Body.Close()
-		reader = res.Body
+		if res.StatusCode != http.StatusOK {
+			return httputil.NewStatusError("artifact download failure", res)
+		}
+		if err := httpstore.ReadBody(res, artifactName, w); err != nil {
+			return err
+		}
+	} else {
+		defer reader.Close()
+		if _, err := io.Copy(w, reader); err != nil {
+			return err
+		}
 	}
 
 	log.Printf("[INFO] Downloaded from %s", urlstr)
-	if _, err := io.Copy(w, reader); err != nil {
+	return nil
+}
+
+// findAsset returns the ID of the asset by name, searching all pages of assets of the release.
+func (r *GithubRelease) findAsset(ctx context.Context, owner, repo string, releaseID int64, name string) (int64, error) {
+	page := 1
+	for {
+		assets, res, err := r.cl.Repositories.ListReleaseAssets(ctx, owner, repo, releaseID, &github.ListOptions{
+			Page:    page,
+			PerPage: 100,
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, a := range assets {
+			if a.GetName() == name {
+				return a.GetID(), nil
+			}
+		}
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+	return 0, nil
+}
+
+func (r *GithubRelease) fetchSourceArchive(ctx context.Context, owner, repo, tag, urlstr string, w io.Writer) error {
+	u, _, err := r.cl.Repositories.GetArchiveLink(ctx, owner, repo, github.Tarball, &github.RepositoryContentGetOptions{Ref: tag}, true)
+	if err != nil {
+		return err
+	}
+	res, err := httpstore.Get(r.cl.Client(), u.String())
+	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return httputil.NewStatusError("source archive download failure", res)
+	}
 
+	if err := httpstore.ReadBody(res, path.Base(urlstr), w); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Downloaded from %s", urlstr)
 	return nil
 }
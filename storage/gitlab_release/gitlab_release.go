@@ -0,0 +1,72 @@
+package glrelease
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/linyows/dewy/httputil"
+	httpstore "github.com/linyows/dewy/storage/http"
+)
+
+const (
+	Scheme = "gitlab_release"
+	// TokenEnv is the environment variable of the access token for GitLab.
+	TokenEnv = "GITLAB_TOKEN"
+)
+
+// GitlabRelease fetches release assets hosted by GitLab, which require the access token for private projects.
+type GitlabRelease struct {
+	cl    *http.Client
+	token string
+}
+
+func New() (*GitlabRelease, error) {
+	return &GitlabRelease{
+		cl:    &http.Client{CheckRedirect: dropToken},
+		token: os.Getenv(TokenEnv),
+	}, nil
+}
+
+// dropToken drops the access token when redirected to another host, such as the external URL of the link.
+func dropToken(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after 10 redirects")
+	}
+	if req.URL.Host != via[0].URL.Host {
+		req.Header.Del("PRIVATE-TOKEN")
+	}
+	return nil
+}
+
+// Fetch fetches the artifact by HTTPS with the access token.
+func (r *GitlabRelease) Fetch(urlstr string, w io.Writer) error {
+	// gitlab_release://gitlab.com/group/project/-/releases/v1.0.0/downloads/artifact.tar.gz
+	u := "https://" + strings.TrimPrefix(urlstr, Scheme+"://")
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	// setting Accept-Encoding disables transparent decompression of net/http
+	req.Header.Set("Accept-Encoding", "identity")
+	if r.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", r.token)
+	}
+	res, err := r.cl.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return httputil.NewStatusError("unexpected status of "+u, res)
+	}
+	if err := httpstore.ReadBody(res, path.Base(res.Request.URL.Path), w); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Downloaded from %s", urlstr)
+	return nil
+}
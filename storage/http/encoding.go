@@ -0,0 +1,76 @@
+package httpstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// gzipMagic is the header of gzip streams.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// gzipExts are extensions of artifacts which are gzip streams by themselves.
+var gzipExts = []string{".gz", ".tgz"}
+
+// Get requests the url without transport compression, so that responses are decoded consistently by ReadBody.
+func Get(cl *http.Client, urlstr string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, urlstr, nil)
+	if err != nil {
+		return nil, err
+	}
+	// setting Accept-Encoding disables transparent decompression of net/http
+	req.Header.Set("Accept-Encoding", "identity")
+	return cl.Do(req)
+}
+
+// ReadBody writes the artifact of the response to w, decoding gzip Content-Encoding.
+// Servers often label gzip artifacts such as .tar.gz with Content-Encoding: gzip without encoding them again,
+// so the artifact named as gzip is decoded only if it is still a gzip stream after decoding.
+func ReadBody(res *http.Response, name string, w io.Writer) error {
+	enc := strings.ToLower(strings.TrimSpace(res.Header.Get("Content-Encoding")))
+	if enc == "" || enc == "identity" {
+		_, err := io.Copy(w, res.Body)
+		return err
+	}
+	if enc != "gzip" && enc != "x-gzip" {
+		return fmt.Errorf("unsupported content encoding: %s", enc)
+	}
+
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	decoded, err := gunzip(raw)
+	if err != nil {
+		return fmt.Errorf("content encoding: %w", err)
+	}
+	if isGzipName(name) && !bytes.HasPrefix(decoded, gzipMagic) {
+		// labeled by the server, not encoded for the transport
+		decoded = raw
+	}
+	_, err = w.Write(decoded)
+	return err
+}
+
+func gunzip(b []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+func isGzipName(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, e := range gzipExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
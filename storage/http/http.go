@@ -0,0 +1,42 @@
+package httpstore
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"path"
+
+	"github.com/linyows/dewy/httputil"
+)
+
+const (
+	Scheme       = "http"
+	SchemeSecure = "https"
+)
+
+// HTTP struct.
+type HTTP struct {
+	cl *http.Client
+}
+
+// New returns HTTP.
+func New() (*HTTP, error) {
+	return &HTTP{cl: http.DefaultClient}, nil
+}
+
+// Fetch fetches the artifact by GET request.
+func (h *HTTP) Fetch(urlstr string, w io.Writer) error {
+	res, err := Get(h.cl, urlstr)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return httputil.NewStatusError("unexpected status of "+urlstr, res)
+	}
+	if err := ReadBody(res, path.Base(res.Request.URL.Path), w); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Downloaded from %s", urlstr)
+	return nil
+}
@@ -0,0 +1,67 @@
+package httpstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func gzipped(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFetchContentEncoding(t *testing.T) {
+	tar := []byte("tar archive content")
+	tgz := gzipped(t, tar)
+
+	tests := []struct {
+		name     string
+		path     string
+		encoding string
+		body     []byte
+		want     []byte
+	}{
+		{"plain", "/app.tar.gz", "", tgz, tgz},
+		{"labeled gzip artifact", "/app.tar.gz", "gzip", tgz, tgz},
+		{"transport encoded gzip artifact", "/app.tar.gz", "gzip", gzipped(t, tgz), tgz},
+		{"transport encoded tar", "/app.tar", "gzip", tgz, tar},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); got != "identity" {
+					t.Errorf("unexpected accept encoding: %s", got)
+				}
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				_, _ = w.Write(tt.body)
+			}))
+			defer ts.Close()
+
+			h, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := h.Fetch(ts.URL+tt.path, &buf); err != nil {
+				t.Fatal(err)
+			}
+			if sha256.Sum256(buf.Bytes()) != sha256.Sum256(tt.want) {
+				t.Errorf("checksum mismatch: got %q, want %q", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
@@ -4,19 +4,34 @@ import (
 	"io"
 	"log"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/k1LoW/remote"
 )
 
 const Scheme = "s3"
 
-type S3 struct{}
+type S3 struct {
+	// Region is the region of the bucket. The default region of the environment is used if empty.
+	Region string
+}
 
 func New() (*S3, error) {
 	return &S3{}, nil
 }
 
+// Fetch downloads the object of the URL such as s3://bucket/key.
 func (s *S3) Fetch(urlstr string, w io.Writer) error {
-	f, err := remote.Open(urlstr)
+	var opts []remote.Option
+	if s.Region != "" {
+		sess, err := session.NewSession(aws.NewConfig().WithRegion(s.Region))
+		if err != nil {
+			return err
+		}
+		opts = append(opts, remote.S3Client(s3.New(sess)))
+	}
+	f, err := remote.Open(urlstr, opts...)
 	if err != nil {
 		return err
 	}
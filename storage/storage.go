@@ -6,7 +6,10 @@ import (
 	"strings"
 
 	"github.com/linyows/dewy/storage/gcs"
+	ghactions "github.com/linyows/dewy/storage/github_actions"
 	ghrelease "github.com/linyows/dewy/storage/github_release"
+	glrelease "github.com/linyows/dewy/storage/gitlab_release"
+	httpstore "github.com/linyows/dewy/storage/http"
 	"github.com/linyows/dewy/storage/s3"
 )
 
@@ -18,7 +21,22 @@ type Fetcher interface {
 
 var _ Fetcher = (*ghrelease.GithubRelease)(nil)
 
+// Fetch fetches the artifact of the URL from the storage of its scheme with the default options.
 func Fetch(urlstr string, w io.Writer) error {
+	return Options{}.Fetch(urlstr, w)
+}
+
+// Options are the options of storages, such as the region of S3, kept out of artifact URLs
+// since the URLs derive cache keys and are notified.
+type Options struct {
+	// S3Region is the region of S3 buckets.
+	S3Region string
+	// GCSCredentialsFile is the path of the service account JSON for GCS.
+	GCSCredentialsFile string
+}
+
+// Fetch fetches the artifact of the URL from the storage of its scheme with the options.
+func (o Options) Fetch(urlstr string, w io.Writer) error {
 	pair := strings.SplitN(urlstr, "://", 2)
 	scheme := pair[0]
 	switch scheme {
@@ -28,17 +46,37 @@ func Fetch(urlstr string, w io.Writer) error {
 			return err
 		}
 		return r.Fetch(urlstr, w)
+	case ghactions.Scheme:
+		r, err := ghactions.New()
+		if err != nil {
+			return err
+		}
+		return r.Fetch(urlstr, w)
+	case glrelease.Scheme:
+		r, err := glrelease.New()
+		if err != nil {
+			return err
+		}
+		return r.Fetch(urlstr, w)
 	case s3.Scheme:
 		r, err := s3.New()
 		if err != nil {
 			return err
 		}
+		r.Region = o.S3Region
 		return r.Fetch(urlstr, w)
 	case gcs.Scheme, gcs.SchemeShort:
 		r, err := gcs.New()
 		if err != nil {
 			return err
 		}
+		r.CredentialsFile = o.GCSCredentialsFile
+		return r.Fetch(urlstr, w)
+	case httpstore.Scheme, httpstore.SchemeSecure:
+		r, err := httpstore.New()
+		if err != nil {
+			return err
+		}
 		return r.Fetch(urlstr, w)
 	}
 	return fmt.Errorf("unsupported scheme: %s", urlstr)
@@ -0,0 +1,26 @@
+package dewy
+
+import (
+	"net"
+	"os"
+)
+
+// sdNotify sends the state to systemd through NOTIFY_SOCKET, such as READY=1 and WATCHDOG=1.
+// It does nothing if Dewy is not started by systemd with Type=notify.
+func sdNotify(state string) error {
+	name := os.Getenv("NOTIFY_SOCKET")
+	if name == "" {
+		return nil
+	}
+	if name[0] == '@' {
+		// abstract namespace socket
+		name = "\x00" + name[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
@@ -0,0 +1,31 @@
+package dewy
+
+// throttle bounds the number of heavy operations running at the same time,
+// such as downloading, verifying and extracting artifacts.
+type throttle chan struct{}
+
+// newThrottle returns throttle allowing n operations at the same time. No limit if n is zero.
+func newThrottle(n int) throttle {
+	if n <= 0 {
+		return nil
+	}
+	return make(throttle, n)
+}
+
+// stageLimit returns the limit of the stage, or the limit of all stages if not configured.
+func stageLimit(n, all int) int {
+	if n > 0 {
+		return n
+	}
+	return all
+}
+
+// do runs f when a slot is available.
+func (t throttle) do(f func() error) error {
+	if t == nil {
+		return f()
+	}
+	t <- struct{}{}
+	defer func() { <-t }()
+	return f()
+}
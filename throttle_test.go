@@ -0,0 +1,67 @@
+package dewy
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want int
+	}{
+		{"limited", 2, 2},
+		{"single", 1, 1},
+		{"unlimited", 0, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th := newThrottle(tt.n)
+			var mu sync.Mutex
+			running, max := 0, 0
+			var wg sync.WaitGroup
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_ = th.do(func() error {
+						mu.Lock()
+						running++
+						if running > max {
+							max = running
+						}
+						mu.Unlock()
+						time.Sleep(20 * time.Millisecond)
+						mu.Lock()
+						running--
+						mu.Unlock()
+						return nil
+					})
+				}()
+			}
+			wg.Wait()
+			if max != tt.want {
+				t.Errorf("got %d at the same time, want %d", max, tt.want)
+			}
+		})
+	}
+}
+
+func TestStageLimit(t *testing.T) {
+	tests := []struct {
+		n, all int
+		want   int
+	}{
+		{0, 0, 0},
+		{0, 2, 2},
+		{1, 2, 1},
+		{3, 0, 3},
+	}
+	for _, tt := range tests {
+		if got := stageLimit(tt.n, tt.all); got != tt.want {
+			t.Errorf("stageLimit(%d, %d) = %d, want %d", tt.n, tt.all, got, tt.want)
+		}
+	}
+}
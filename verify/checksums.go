@@ -0,0 +1,64 @@
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Checksums verifies the artifact against the checksums file published with it,
+// in the format of sha256sum and GoReleaser such as "<hex>  <filename>".
+type Checksums struct {
+	// Name is the name of the checksums file next to the artifact, such as checksums.txt.
+	Name string
+	// Fetch fetches the checksums file of the URL.
+	Fetch func(urlstr string, w io.Writer) error
+}
+
+// Verify verifies the digest of the artifact with the entry of the checksums file.
+func (c *Checksums) Verify(ctx context.Context, a *Artifact, r io.Reader) error {
+	// the URL is not always parsed by net/url, such as github_release://
+	u, query, _ := strings.Cut(a.URL, "?")
+	i := strings.LastIndex(u, "/")
+	if i < 0 {
+		return fmt.Errorf("invalid artifact url: %s", a.URL)
+	}
+	name := u[i+1:]
+	cu := u[:i+1] + c.Name
+	if query != "" {
+		cu += "?" + query
+	}
+
+	buf := new(bytes.Buffer)
+	if err := c.Fetch(cu, buf); err != nil {
+		return fmt.Errorf("%w: checksums of %s: %s", ErrVerification, a.URL, err)
+	}
+	sum, ok := lookupChecksum(buf, name)
+	if !ok {
+		return fmt.Errorf("%w: %s is not in %s", ErrVerification, name, c.Name)
+	}
+	algo := "sha256"
+	if len(sum) == 128 {
+		algo = "sha512"
+	}
+	return (&Digest{Algorithm: algo, Sum: sum}).Verify(ctx, a, r)
+}
+
+// lookupChecksum returns the checksum of the file name in the checksums file.
+func lookupChecksum(r io.Reader, name string) (string, bool) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// "*" marks the file read in binary mode
+		if strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,212 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	// oidIssuerV1 is the extension of Fulcio certificates for the OIDC issuer as a raw string.
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	// oidIssuerV2 is the extension of Fulcio certificates for the OIDC issuer as a DER-encoded string.
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// CosignConfig is the key material to verify signatures of cosign sign-blob.
+type CosignConfig struct {
+	// Key is the PEM-encoded public key of cosign generate-key-pair. Keyless signing is verified if empty.
+	Key string
+	// Identity is the email or URI of the signer in the certificate of keyless signing.
+	Identity string
+	// Issuer is the OIDC issuer in the certificate of keyless signing, such as https://token.actions.githubusercontent.com.
+	Issuer string
+	// Roots are the PEM-encoded certificates of Fulcio which issued the certificate of keyless signing.
+	Roots string
+}
+
+// Cosign verifies the signature of cosign sign-blob published with the artifact as <artifact>.sig,
+// by the public key, or by the certificate <artifact>.pem or <artifact>.crt of keyless signing.
+// The transparency log is not checked for keyless signing, so the certificate is verified at the time it was issued.
+type Cosign struct {
+	key      crypto.PublicKey
+	identity string
+	issuer   string
+	roots    *x509.CertPool
+	fetch    func(urlstr string, w io.Writer) error
+}
+
+// NewCosign returns Cosign of the key material, fetching signatures and certificates by fetch.
+func NewCosign(c CosignConfig, fetch func(urlstr string, w io.Writer) error) (*Cosign, error) {
+	s := &Cosign{fetch: fetch}
+	if c.Key != "" {
+		b, _ := pem.Decode([]byte(c.Key))
+		if b == nil {
+			return nil, fmt.Errorf("invalid cosign key: no PEM block")
+		}
+		k, err := x509.ParsePKIXPublicKey(b.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cosign key: %w", err)
+		}
+		s.key = k
+		return s, nil
+	}
+	if c.Identity == "" || c.Issuer == "" || c.Roots == "" {
+		return nil, fmt.Errorf("identity, issuer and roots are required for keyless cosign verification")
+	}
+	s.identity = c.Identity
+	s.issuer = c.Issuer
+	s.roots = x509.NewCertPool()
+	if !s.roots.AppendCertsFromPEM([]byte(c.Roots)) {
+		return nil, fmt.Errorf("invalid cosign roots: no certificate")
+	}
+	return s, nil
+}
+
+// Verify verifies the signature of the artifact.
+func (s *Cosign) Verify(ctx context.Context, a *Artifact, r io.Reader) error {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	sig, err := s.fetchDecoded(a.URL, ".sig")
+	if err != nil {
+		return fmt.Errorf("%w: signature of %s: %s", ErrVerification, a.URL, err)
+	}
+	key := s.key
+	if key == nil {
+		cert, err := s.certificate(a.URL)
+		if err != nil {
+			return fmt.Errorf("%w: certificate of %s: %s", ErrVerification, a.URL, err)
+		}
+		key = cert.PublicKey
+	}
+	if err := verifySignature(key, content, sig); err != nil {
+		return fmt.Errorf("%w: signature of %s: %s", ErrVerification, a.URL, err)
+	}
+	return nil
+}
+
+// certificate returns the certificate of keyless signing published as <artifact>.pem or <artifact>.crt,
+// verified with the roots, the identity and the issuer.
+func (s *Cosign) certificate(urlstr string) (*x509.Certificate, error) {
+	b, err := s.fetchDecoded(urlstr, ".pem")
+	if err != nil {
+		var crtErr error
+		if b, crtErr = s.fetchDecoded(urlstr, ".crt"); crtErr != nil {
+			return nil, errors.Join(err, crtErr)
+		}
+	}
+	cert, err := x509.ParseCertificate(b)
+	if err != nil {
+		return nil, err
+	}
+	// the certificate is short-lived, and valid when the artifact was signed
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:       s.roots,
+		CurrentTime: cert.NotBefore,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, err
+	}
+	if !hasIdentity(cert, s.identity) {
+		return nil, fmt.Errorf("identity is not %s", s.identity)
+	}
+	if issuer := issuerOf(cert); issuer != s.issuer {
+		return nil, fmt.Errorf("issuer %s is not %s", issuer, s.issuer)
+	}
+	return cert, nil
+}
+
+// fetchDecoded fetches the file of the suffix next to the artifact, decoding base64 as cosign outputs
+// and the PEM block, such as of certificates, into DER bytes.
+func (s *Cosign) fetchDecoded(urlstr, suffix string) ([]byte, error) {
+	u, query, _ := strings.Cut(urlstr, "?")
+	u += suffix
+	if query != "" {
+		u += "?" + query
+	}
+	buf := new(bytes.Buffer)
+	if err := s.fetch(u, buf); err != nil {
+		return nil, err
+	}
+	b := bytes.TrimSpace(buf.Bytes())
+	if !bytes.HasPrefix(b, []byte("-----BEGIN")) {
+		d, err := base64.StdEncoding.DecodeString(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64: %w", err)
+		}
+		// certificates are PEM encoded in base64
+		if b = bytes.TrimSpace(d); !bytes.HasPrefix(b, []byte("-----BEGIN")) {
+			return d, nil
+		}
+	}
+	p, _ := pem.Decode(b)
+	if p == nil {
+		return nil, errors.New("invalid PEM block")
+	}
+	return p.Bytes, nil
+}
+
+// verifySignature verifies the signature of the content by the public key as cosign signs blobs.
+func verifySignature(key crypto.PublicKey, content, sig []byte) error {
+	digest := sha256.Sum256(content)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, content, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key type: %T", key)
+}
+
+// hasIdentity reports whether the certificate is issued to the email or the URI.
+func hasIdentity(cert *x509.Certificate, identity string) bool {
+	for _, e := range cert.EmailAddresses {
+		if e == identity {
+			return true
+		}
+	}
+	for _, u := range cert.URIs {
+		if u.String() == identity {
+			return true
+		}
+	}
+	return false
+}
+
+// issuerOf returns the OIDC issuer in the extension of the certificate.
+func issuerOf(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var s string
+			if _, err := asn1.Unmarshal(ext.Value, &s); err == nil {
+				return s
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package verify
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCosign(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	// a root of Fulcio and a short-lived certificate for keyless signing
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ = x509.ParseCertificate(caDER)
+	issuer, _ := asn1.Marshal("https://token.actions.githubusercontent.com")
+	leaf := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       time.Now().Add(-2 * time.Minute),
+		NotAfter:        time.Now().Add(-time.Minute),
+		EmailAddresses:  []string{"release@example.com"},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+	cert := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}))
+
+	digest := sha256.Sum256([]byte("hello"))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"app.tar.gz.sig": base64.StdEncoding.EncodeToString(sig),
+		"app.tar.gz.pem": cert,
+		// a raw PEM certificate and signature
+		"raw.tar.gz.sig":    string(pem.EncodeToMemory(&pem.Block{Type: "SIGNATURE", Bytes: sig})),
+		"raw.tar.gz.crt":    string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})),
+		"broken.tar.gz.sig": "-----BEGIN SIGNATURE-----",
+	}
+	fetch := func(urlstr string, w io.Writer) error {
+		for name, content := range files {
+			if urlstr == "github_release://owner/repo/tag/v1.0.0/"+name {
+				_, err := io.WriteString(w, content)
+				return err
+			}
+		}
+		return errors.New("not found")
+	}
+
+	keyless := CosignConfig{Identity: "release@example.com", Issuer: "https://token.actions.githubusercontent.com", Roots: roots}
+	tests := []struct {
+		name     string
+		config   CosignConfig
+		artifact string
+		content  string
+		wantErr  bool
+	}{
+		{"key", CosignConfig{Key: pub}, "app.tar.gz", "hello", false},
+		{"key with tampered artifact", CosignConfig{Key: pub}, "app.tar.gz", "hello!", true},
+		{"key with PEM signature", CosignConfig{Key: pub}, "raw.tar.gz", "hello", false},
+		{"key with broken PEM signature", CosignConfig{Key: pub}, "broken.tar.gz", "hello", true},
+		{"keyless", keyless, "app.tar.gz", "hello", false},
+		{"keyless with tampered artifact", keyless, "app.tar.gz", "hello!", true},
+		{"keyless with PEM certificate as crt", keyless, "raw.tar.gz", "hello", false},
+		{"keyless without certificate", keyless, "broken.tar.gz", "hello", true},
+		{"keyless of other identity", CosignConfig{Identity: "other@example.com", Issuer: keyless.Issuer, Roots: roots}, "app.tar.gz", "hello", true},
+		{"keyless of other issuer", CosignConfig{Identity: keyless.Identity, Issuer: "https://accounts.google.com", Roots: roots}, "app.tar.gz", "hello", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCosign(tt.config, fetch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := &Artifact{Tag: "v1.0.0", URL: "github_release://owner/repo/tag/v1.0.0/" + tt.artifact}
+			err = c.Verify(context.Background(), a, strings.NewReader(tt.content))
+			if tt.wantErr {
+				if !errors.Is(err, ErrVerification) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	if _, err := NewCosign(CosignConfig{Identity: "release@example.com"}, fetch); err == nil {
+		t.Error("keyless without issuer and roots should be error")
+	}
+}
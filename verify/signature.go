@@ -0,0 +1,63 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// signatureSuffixes are suffixes of detached signatures tried in order.
+var signatureSuffixes = []string{".sig", ".asc"}
+
+// Signature verifies the detached OpenPGP signature published with the artifact, such as <artifact>.sig or <artifact>.asc.
+type Signature struct {
+	keyring openpgp.EntityList
+	fetch   func(urlstr string, w io.Writer) error
+}
+
+// NewSignature returns Signature verifying with the armored public key, fetching signatures by fetch.
+func NewSignature(armoredKey string, fetch func(urlstr string, w io.Writer) error) (*Signature, error) {
+	keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armoredKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature key: %w", err)
+	}
+	return &Signature{keyring: keyring, fetch: fetch}, nil
+}
+
+// Verify verifies the signature of the artifact, armored or binary.
+func (s *Signature) Verify(ctx context.Context, a *Artifact, r io.Reader) error {
+	u, query, _ := strings.Cut(a.URL, "?")
+	var (
+		sig  *bytes.Buffer
+		errs []string
+	)
+	for _, suffix := range signatureSuffixes {
+		su := u + suffix
+		if query != "" {
+			su += "?" + query
+		}
+		buf := new(bytes.Buffer)
+		if err := s.fetch(su, buf); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		sig = buf
+		break
+	}
+	if sig == nil {
+		return fmt.Errorf("%w: signature of %s: %s", ErrVerification, a.URL, strings.Join(errs, ", "))
+	}
+
+	check := openpgp.CheckDetachedSignature
+	if bytes.HasPrefix(bytes.TrimSpace(sig.Bytes()), []byte("-----BEGIN")) {
+		check = openpgp.CheckArmoredDetachedSignature
+	}
+	if _, err := check(s.keyring, r, sig, nil); err != nil {
+		return fmt.Errorf("%w: signature of %s: %s", ErrVerification, a.URL, err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// ErrVerification is returned when the artifact fails verification.
+var ErrVerification = errors.New("artifact verification failed")
+
+// Artifact is the metadata of the downloaded artifact.
+type Artifact struct {
+	// Tag is the tag of the artifact.
+	Tag string
+	// URL is the URL where the artifact is downloaded from.
+	URL string
+}
+
+// Verifier is the interface that wraps the Verify method.
+type Verifier interface {
+	// Verify verifies the downloaded artifact read from r.
+	Verify(ctx context.Context, a *Artifact, r io.Reader) error
+}
+
+// Chain runs verifiers in order and stops at the first failure.
+type Chain []Verifier
+
+var _ Verifier = Chain(nil)
+
+// Verify runs each verifier on the content.
+func (c Chain) Verify(ctx context.Context, a *Artifact, r io.Reader) error {
+	if len(c) == 0 {
+		return nil
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	for _, v := range c {
+		if err := v.Verify(ctx, a, bytes.NewReader(b)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Size verifies the size of the artifact.
+type Size struct {
+	// Max is the maximum size in bytes. No limit if zero.
+	Max int64
+}
+
+// Verify verifies the size.
+func (s *Size) Verify(ctx context.Context, a *Artifact, r io.Reader) error {
+	if s.Max <= 0 {
+		return nil
+	}
+	n, err := io.Copy(io.Discard, io.LimitReader(r, s.Max+1))
+	if err != nil {
+		return err
+	}
+	if n > s.Max {
+		return fmt.Errorf("%w: %s is larger than %d bytes", ErrVerification, a.URL, s.Max)
+	}
+	return nil
+}
+
+// Digest verifies the digest of the artifact.
+type Digest struct {
+	// Algorithm is sha256 or sha512.
+	Algorithm string
+	// Sum is the expected digest in hex.
+	Sum string
+}
+
+// Verify verifies the digest.
+func (d *Digest) Verify(ctx context.Context, a *Artifact, r io.Reader) error {
+	var h hash.Hash
+	switch d.Algorithm {
+	case "", "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported digest algorithm: %s", d.Algorithm)
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	got := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(got, d.Sum) {
+		return fmt.Errorf("%w: digest of %s is %s, expected %s", ErrVerification, a.URL, got, d.Sum)
+	}
+	return nil
+}
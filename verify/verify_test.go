@@ -0,0 +1,190 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+type called struct {
+	n   int
+	err error
+}
+
+func (c *called) Verify(ctx context.Context, a *Artifact, r io.Reader) error {
+	c.n++
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if string(b) != "hello" {
+		return errors.New("content is not passed")
+	}
+	return c.err
+}
+
+func TestChain(t *testing.T) {
+	first := &called{}
+	second := &called{err: ErrVerification}
+	third := &called{}
+	c := Chain{first, second, third}
+	err := c.Verify(context.Background(), &Artifact{}, strings.NewReader("hello"))
+	if !errors.Is(err, ErrVerification) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if first.n != 1 || second.n != 1 || third.n != 0 {
+		t.Errorf("unexpected calls: %d, %d, %d", first.n, second.n, third.n)
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	// sha256 of "hello"
+	sum := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	tests := []struct {
+		name    string
+		v       Verifier
+		wantErr bool
+	}{
+		{"size within", &Size{Max: 5}, false},
+		{"size exceeded", &Size{Max: 4}, true},
+		{"no size limit", &Size{}, false},
+		{"digest", &Digest{Sum: sum}, false},
+		{"digest uppercase", &Digest{Algorithm: "sha256", Sum: strings.ToUpper(sum)}, false},
+		{"digest mismatch", &Digest{Sum: "00"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.v.Verify(context.Background(), &Artifact{}, strings.NewReader("hello"))
+			if tt.wantErr {
+				if !errors.Is(err, ErrVerification) {
+					t.Errorf("expected ErrVerification, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	// sha256 of "hello"
+	sum := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"match", "0000  other.tar.gz\n" + sum + "  app.tar.gz\n", false},
+		{"binary mode", sum + " *app.tar.gz\n", false},
+		{"mismatch", strings.Repeat("0", 64) + "  app.tar.gz\n", true},
+		{"missing entry", sum + "  other.tar.gz\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetched string
+			c := &Checksums{Name: "checksums.txt", Fetch: func(urlstr string, w io.Writer) error {
+				fetched = urlstr
+				_, err := io.WriteString(w, tt.content)
+				return err
+			}}
+			a := &Artifact{Tag: "v1.0.0", URL: "github_release://owner/repo/tag/v1.0.0/app.tar.gz"}
+			err := c.Verify(context.Background(), a, strings.NewReader("hello"))
+			if fetched != "github_release://owner/repo/tag/v1.0.0/checksums.txt" {
+				t.Errorf("unexpected checksums url: %s", fetched)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, ErrVerification) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSignature(t *testing.T) {
+	entity, err := openpgp.NewEntity("release", "", "release@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := openpgp.NewEntity("other", "", "other@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := new(bytes.Buffer)
+	w, err := armor.Encode(pub, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	sign := func(e *openpgp.Entity, content string, armored bool) string {
+		buf := new(bytes.Buffer)
+		f := openpgp.DetachSign
+		if armored {
+			f = openpgp.ArmoredDetachSign
+		}
+		if err := f(buf, e, strings.NewReader(content), nil); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	tests := []struct {
+		name    string
+		sigs    map[string]string
+		wantErr bool
+	}{
+		{"binary sig", map[string]string{".sig": sign(entity, "hello", false)}, false},
+		{"armored asc", map[string]string{".asc": sign(entity, "hello", true)}, false},
+		{"other signer", map[string]string{".sig": sign(other, "hello", false)}, true},
+		{"other content", map[string]string{".sig": sign(entity, "bye", false)}, true},
+		{"missing signature", map[string]string{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSignature(pub.String(), func(urlstr string, w io.Writer) error {
+				for suffix, sig := range tt.sigs {
+					if urlstr == "s3://bucket/app/v1.0.0/app.tar.gz"+suffix+"?region=ap-northeast-1" {
+						_, err := io.WriteString(w, sig)
+						return err
+					}
+				}
+				return errors.New("not found")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := &Artifact{Tag: "v1.0.0", URL: "s3://bucket/app/v1.0.0/app.tar.gz?region=ap-northeast-1"}
+			err = s.Verify(context.Background(), a, strings.NewReader("hello"))
+			if tt.wantErr {
+				if !errors.Is(err, ErrVerification) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	if _, err := NewSignature("not a key", nil); err == nil {
+		t.Error("invalid key should be error")
+	}
+}
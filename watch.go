@@ -0,0 +1,196 @@
+package dewy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/linyows/dewy/notice"
+)
+
+const (
+	// defaultHealthCheckTimeout is the timeout of each request of the health check URL if not configured.
+	defaultHealthCheckTimeout = 5 * time.Second
+	// defaultHealthCheckRetries is the number of retries of the health check URL if not configured.
+	defaultHealthCheckRetries = 5
+	// healthCheckRetryInterval is the interval between requests of the health check URL.
+	healthCheckRetryInterval = time.Second
+	// postDeployWatchChecks is the number of health checks within the post deploy watch.
+	postDeployWatchChecks = 12
+	// postDeployWatchFailures is the number of consecutive failures regarded as sustained.
+	postDeployWatchFailures = 3
+)
+
+// startPostDeployWatch runs the post deploy watch in the background, so that polling and other instances
+// are not blocked for the watch. The watch of the previous deploy is stopped.
+func (d *Dewy) startPostDeployWatch(ctx context.Context, m notice.Message) {
+	if d.config.Command != SERVER || d.config.PostDeployWatch <= 0 || d.config.HealthCheck == "" {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	d.Lock()
+	if d.stopWatch != nil {
+		d.stopWatch()
+	}
+	d.stopWatch = cancel
+	key := d.deployedKey
+	d.Unlock()
+
+	go func() {
+		defer cancel()
+		if err := d.watchPostDeploy(ctx, m); err != nil {
+			d.statsd.Count("deploy.failure", 1, "tag:"+m.Tag)
+			d.recordFailure(ctx, key, m, err)
+		}
+	}()
+}
+
+// watchPostDeploy keeps checking the health of the server for the post deploy watch,
+// to catch the release degrading shortly after passing the initial checks.
+// On sustained failures, the server is rolled back if configured, otherwise the rollback is recommended.
+// The watch ends without error when the context is canceled, such as by the next deploy.
+func (d *Dewy) watchPostDeploy(ctx context.Context, m notice.Message) error {
+	if d.config.Command != SERVER || d.config.PostDeployWatch <= 0 || d.config.HealthCheck == "" {
+		return nil
+	}
+	interval := d.config.PostDeployWatch / postDeployWatchChecks
+	deadline := time.Now().Add(d.config.PostDeployWatch)
+	failures := 0
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+		err := d.checkHealth()
+		if err == nil {
+			failures = 0
+			continue
+		}
+		failures++
+		log.Printf("[WARN] Health check failure %d/%d after deploy: %s", failures, postDeployWatchFailures, err)
+		if failures < postDeployWatchFailures {
+			continue
+		}
+
+		unlock, lerr := d.lockDeploy()
+		if lerr != nil {
+			return lerr
+		}
+		if ctx.Err() != nil {
+			// deployed again while waiting for the lock
+			unlock()
+			return nil
+		}
+		if !d.config.PostDeployRollback || d.previousRelease == "" {
+			m.Error = fmt.Sprintf("%s, rolling back is recommended", err)
+		} else if rerr := d.rollbackServer(); rerr != nil {
+			log.Printf("[ERROR] Rollback failure: %#v", rerr)
+			m.Error = fmt.Sprintf("%s, and rollback failed: %s", err, rerr)
+		} else {
+			m.Error = fmt.Sprintf("%s, rolled back", err)
+		}
+		unlock()
+		log.Printf("[ERROR] Server is unhealthy after deploy: %s", m.Error)
+		d.notify(ctx, notice.EventUnhealthy, m)
+		return fmt.Errorf("%w: %s", ErrServerStart, m.Error)
+	}
+	log.Printf("[INFO] Server is healthy for %s after deploy", d.config.PostDeployWatch)
+	return nil
+}
+
+// checkHealth runs the health check command in the working directory like after deploy hooks.
+func (d *Dewy) checkHealth() error {
+	release, _ := d.readCurrent()
+	dir, err := d.workDir(release)
+	if err != nil {
+		return err
+	}
+	cmd := shellCommand(d.config.HealthCheck)
+	cmd.Dir = dir
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = d.runCommand(cmd)
+	if s := strings.TrimSpace(out.String()); err != nil && s != "" {
+		return fmt.Errorf("%w: %s", err, s)
+	}
+	return err
+}
+
+// rollbackServer rolls back the symlink, then restarts the server and sidecars with the previous release.
+func (d *Dewy) rollbackServer() error {
+	if err := d.rollback(); err != nil {
+		return err
+	}
+	if err := d.restartServer(); err != nil {
+		return err
+	}
+	return d.restartSidecars()
+}
+
+// checkHealthURL requests the health check URL after starting or restarting the server, until it responds 2xx.
+// If it keeps failing, the server is rolled back to the previous release if any, and the failure is notified.
+func (d *Dewy) checkHealthURL(ctx context.Context, m notice.Message) error {
+	err := d.waitHealthy()
+	if err == nil {
+		return nil
+	}
+	log.Printf("[ERROR] Server health check failure: %s", err)
+	event := notice.EventServerStartFailure
+	m.Error = err.Error()
+	if d.previousRelease != "" {
+		event = notice.EventServerRestartFailure
+		if rerr := d.rollbackServer(); rerr != nil {
+			log.Printf("[ERROR] Rollback failure: %#v", rerr)
+			m.Error = fmt.Sprintf("%s, and rollback failed: %s", err, rerr)
+		} else {
+			m.Error = fmt.Sprintf("%s, rolled back", err)
+		}
+	}
+	d.notify(ctx, event, m)
+	return fmt.Errorf("%w: %s", ErrServerStart, m.Error)
+}
+
+// waitHealthy requests the health check URL with retries, and returns the last error.
+func (d *Dewy) waitHealthy() error {
+	timeout := d.config.HealthCheckTimeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	retries := d.config.HealthCheckRetries
+	if retries <= 0 {
+		retries = defaultHealthCheckRetries
+	}
+	client := &http.Client{Timeout: timeout}
+	var err error
+	for i := 0; i <= retries; i++ {
+		if i > 0 {
+			log.Printf("[WARN] Health check failure, retry %d/%d: %s", i, retries, err)
+			time.Sleep(healthCheckRetryInterval)
+		}
+		if err = requestHealth(client, d.config.HealthCheckURL); err == nil {
+			log.Printf("[INFO] Server is healthy with %s", d.config.HealthCheckURL)
+			return nil
+		}
+	}
+	return err
+}
+
+func requestHealth(client *http.Client, url string) error {
+	res, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("health check of %s responded %s", url, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package dewy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/linyows/dewy/notice"
+)
+
+func TestWatchPostDeploy(t *testing.T) {
+	tests := []struct {
+		name    string
+		healthy bool
+		wantErr bool
+	}{
+		{"healthy", true, false},
+		{"flapping", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			release := filepath.Join(root, releasesDir, "20240101T000000Z")
+			if err := os.MkdirAll(release, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if tt.healthy {
+				if err := os.WriteFile(filepath.Join(release, "healthy"), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.Symlink(release, filepath.Join(root, symlinkDir)); err != nil {
+				t.Fatal(err)
+			}
+			n := &unreachableNotice{}
+			d := &Dewy{root: root, notice: n, config: Config{
+				Command:         SERVER,
+				WorkDir:         "{{.ReleaseDir}}",
+				HealthCheck:     "test -f healthy",
+				PostDeployWatch: 600 * time.Millisecond,
+			}}
+
+			err := d.watchPostDeploy(context.Background(), notice.Message{Tag: "v1.0.0"})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n.calls != 0 {
+					t.Errorf("healthy server should not be notified, got %d", n.calls)
+				}
+				return
+			}
+			if !errors.Is(err, ErrServerStart) {
+				t.Errorf("got %v, want %v", err, ErrServerStart)
+			}
+			if n.calls != 1 {
+				t.Errorf("unhealthy server should be notified once, got %d", n.calls)
+			}
+		})
+	}
+}
+
+func TestWatchPostDeployRollback(t *testing.T) {
+	root := t.TempDir()
+	for _, v := range []string{"v1", "v2"} {
+		if err := os.MkdirAll(filepath.Join(root, releasesDir, v), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, releasesDir, "v1", "healthy"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	n := &chanNotice{ch: make(chan string, 1)}
+	d := &Dewy{
+		root:   root,
+		notice: n,
+		config: Config{
+			Command:            SERVER,
+			WorkDir:            "{{.ReleaseDir}}",
+			HealthCheck:        "test -f healthy",
+			PostDeployWatch:    600 * time.Millisecond,
+			PostDeployRollback: true,
+		},
+		fg:              newForeground(&StarterConfig{command: "sleep", args: []string{"30"}}, nil),
+		isServerRunning: true,
+		previousRelease: filepath.Join(root, releasesDir, "v1"),
+	}
+	if err := d.fg.start(0); err != nil {
+		t.Fatal(err)
+	}
+	defer d.fg.stop(syscall.SIGTERM, time.Second)
+	if err := d.linkCurrent(filepath.Join(root, releasesDir, "v2")); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	d.startPostDeployWatch(context.Background(), notice.Message{Tag: "v2"})
+	if elapsed := time.Since(started); elapsed >= d.config.PostDeployWatch {
+		t.Fatalf("the watch should run in the background, blocked for %s", elapsed)
+	}
+
+	// the deploy lock is free during the watch
+	unlock, err := d.lockDeploy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+
+	select {
+	case m := <-n.ch:
+		if !strings.Contains(m, "rolled back") {
+			t.Errorf("got %q, want the rollback notified", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("unhealthy server should be notified")
+	}
+	current, err := d.readCurrent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := filepath.Base(current); got != "v1" {
+		t.Errorf("got %s linked, want v1", got)
+	}
+}
+
+type chanNotice struct {
+	ch chan string
+}
+
+func (n *chanNotice) String() string {
+	return "chan"
+}
+
+func (n *chanNotice) Notify(ctx context.Context, message string) error {
+	n.ch <- message
+	return nil
+}
+
+type recordNotice struct {
+	messages []string
+}
+
+func (n *recordNotice) String() string {
+	return "record"
+}
+
+func (n *recordNotice) Notify(ctx context.Context, message string) error {
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+func TestCheckHealthURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantErr  bool
+		wantLink string
+	}{
+		{"healthy", http.StatusOK, false, "v2"},
+		{"unhealthy", http.StatusServiceUnavailable, true, "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+
+			root := t.TempDir()
+			for _, v := range []string{"v1", "v2"} {
+				if err := os.MkdirAll(filepath.Join(root, releasesDir, v), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			n := &recordNotice{}
+			d := &Dewy{
+				root:   root,
+				notice: n,
+				config: Config{
+					Command:            SERVER,
+					HealthCheckURL:     ts.URL,
+					HealthCheckRetries: 1,
+				},
+				fg:              newForeground(&StarterConfig{command: "sleep", args: []string{"30"}}, nil),
+				isServerRunning: true,
+				previousRelease: filepath.Join(root, releasesDir, "v1"),
+			}
+			if err := d.fg.start(0); err != nil {
+				t.Fatal(err)
+			}
+			defer d.fg.stop(syscall.SIGTERM, time.Second)
+			if err := d.linkCurrent(filepath.Join(root, releasesDir, "v2")); err != nil {
+				t.Fatal(err)
+			}
+
+			err := d.checkHealthURL(context.Background(), notice.Message{Tag: "v2"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr && !errors.Is(err, ErrServerStart) {
+				t.Errorf("got %v, want ErrServerStart", err)
+			}
+			current, err := d.readCurrent()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filepath.Base(current); got != tt.wantLink {
+				t.Errorf("got %s linked, want %s", got, tt.wantLink)
+			}
+			if tt.wantErr && (requests != 2 || len(n.messages) != 1) {
+				t.Errorf("got %d requests and %d notices, want 2 and 1", requests, len(n.messages))
+			}
+		})
+	}
+}
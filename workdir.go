@@ -0,0 +1,50 @@
+package dewy
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"text/template"
+)
+
+// WorkDirData is the data to render the working directory.
+type WorkDirData struct {
+	// ReleaseDir is the directory of the deployed release.
+	ReleaseDir string
+}
+
+// validateWorkDir validates the template of the working directory.
+func validateWorkDir(s string) error {
+	if _, err := renderWorkDir(s, WorkDirData{}); err != nil {
+		return fmt.Errorf("invalid work dir: %w", err)
+	}
+	return nil
+}
+
+func renderWorkDir(s string, data WorkDirData) (string, error) {
+	t, err := template.New("workdir").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// workDir returns the working directory of hooks and the server for the release directory,
+// relative to the root. The release directory is used if not configured.
+func (d *Dewy) workDir(releaseDir string) (string, error) {
+	if d.config.WorkDir == "" {
+		return releaseDir, nil
+	}
+	dir, err := renderWorkDir(d.config.WorkDir, WorkDirData{ReleaseDir: releaseDir})
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(d.root, dir)
+	}
+	return dir, nil
+}
@@ -0,0 +1,36 @@
+package dewy
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		workDir string
+		want    string
+	}{
+		{"release dir by default", "", "/opt/app/releases/20240101T000000Z"},
+		{"release dir", "{{.ReleaseDir}}", "/opt/app/releases/20240101T000000Z"},
+		{"sub dir of release", "{{.ReleaseDir}}/public", "/opt/app/releases/20240101T000000Z/public"},
+		{"relative to root", "current", filepath.Join("/opt/app", "current")},
+		{"absolute", "/srv/app", "/srv/app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Dewy{root: "/opt/app", config: Config{WorkDir: tt.workDir}}
+			got, err := d.workDir("/opt/app/releases/20240101T000000Z")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if err := validateWorkDir("{{.Unknown}}"); err == nil {
+		t.Error("unknown field should be error")
+	}
+}